@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// runAPI implements the api subcommand, which serves the analysis over HTTP
+func runAPI(args []string) {
+	flags := flag.NewFlagSet("api", flag.ExitOnError)
+	listen := flags.String("listen", "localhost:8080", "Address to listen on")
+	workers := flags.Int("workers", runtime.NumCPU(), "Requests analyzed concurrently, others wait for a free worker")
+	maxRequestSize := flags.Int64("max-request-size", 32, "Largest accepted request in MB")
+	timeout := flags.Duration("timeout", 5*time.Minute, "Maximum time per request, waiting for a worker included")
+	circomPath := flags.String("circom-path", os.Getenv("CIRCOM_PATH"), "Path to the circom binary (default: $CIRCOM_PATH, then PATH)")
+	circomDocker := flags.String("circom-docker", "", "Run circom inside the given Docker image instead of the local binary")
+	minCircomVersion := flags.String("min-circom-version", internal.DefaultMinCircomVersion, "Oldest circom version to accept")
+	arityCap := flags.Int("arity-cap", 0, "Connect constraints over more than N signals through a synthetic node instead of a clique (default: no cap)")
+	projection := flags.String("projection", "clique", "Turn constraints into edges between all their signals (clique) or through a constraint node (star)")
+	hotSpots := flags.Int("hot-spots", 5, "Report the N edges with the highest betweenness and the constraints behind them, 0 to skip")
+	hubThreshold := flags.Float64("hub-threshold", 40, "Report signals sharing constraints with more than this percentage of all signals, 0 to skip")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: circuit-analyzer api [-listen localhost:8080] [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *projection != string(circuitgraph.ProjectionClique) && *projection != string(circuitgraph.ProjectionStar) {
+		fmt.Println("The -projection flag accepts clique or star")
+		os.Exit(1)
+	}
+
+	circom := internal.Circom{Path: *circomPath, DockerImage: *circomDocker, MinVersion: *minCircomVersion}
+	if err := internal.CheckCircomInstallation(circom); err != nil {
+		// Precompiled constraints can still be analyzed, /healthz reports the problem
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	server := &http.Server{
+		Addr: *listen,
+		Handler: internal.NewAPIHandler(internal.APIOptions{
+			Circom: circom,
+			Analysis: internal.Options{
+				ArityCap:     *arityCap,
+				Projection:   circuitgraph.Projection(*projection),
+				HotSpots:     *hotSpots,
+				HubThreshold: *hubThreshold,
+			},
+			Workers:         *workers,
+			MaxRequestBytes: *maxRequestSize << 20,
+			Timeout:         *timeout,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Ctrl-C stops accepting requests and lets the running ones finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+		server.Shutdown(context.Background())
+	}()
+
+	fmt.Printf("Serving the analysis on http://%s\n", *listen)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+)
+
+// slowestTemplates is the number of templates listed in the roll-up of a batch
+const slowestTemplates = 10
+
+// batchSettings are the flags a batch applies to every project
+type batchSettings struct {
+	dir             string // Parent directory of the project directories
+	walk            internal.WalkOptions
+	scope           internal.ScopeOptions
+	parallelism     int
+	format          string
+	out             string
+	report          string
+	reportTemplate  string
+	precommit       bool
+	budget          time.Duration
+	similar         bool
+	strict          bool
+	postProcessFail bool
+	hashOnly        bool
+}
+
+// readProjectRoots reads a -batch file, one project root per line. Blank
+// lines and lines starting with # are skipped, relative roots are relative to
+// the file.
+func readProjectRoots(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var roots []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(filepath.Dir(path), line)
+		}
+		roots = append(roots, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("%s lists no project", path)
+	}
+	return roots, nil
+}
+
+// runBatch analyzes every root as a project of its own, with its outputs in a
+// subdirectory of settings.dir, all projects sharing the workers of a single
+// pool. A project that cannot be analyzed is reported without stopping the
+// others. It prints the summary of every project and a roll-up comparing
+// them, and returns the exit code of the batch.
+func runBatch(ctx, analysisCtx context.Context, roots []string, options internal.Options, settings batchSettings) int {
+	workers := settings.parallelism
+	if options.LowMemory {
+		workers = 1
+	}
+	options.Workers = make(chan struct{}, workers)
+
+	names := internal.ProjectNames(roots)
+	projects := make([]internal.Project, len(roots))
+	analyzers := make([]*internal.Analyzer, len(roots))
+	for i, root := range roots {
+		project := &projects[i]
+		project.Name, project.Root = names[i], root
+		files, _, err := internal.GetCircomFiles(root, settings.walk)
+		if err != nil {
+			project.Err = err
+			continue
+		}
+		scopeOptions := settings.scope
+		scopeOptions.Root = inputRoot(root)
+		project.Scope = internal.ScopeFiles(files, scopeOptions)
+		files = project.Scope.Files
+		projectOptions := options
+		projectOptions.OutputDir = filepath.Join(settings.dir, project.Name)
+		projectOptions.Root = inputRoot(root)
+		if err := os.MkdirAll(projectOptions.OutputDir, 0755); err != nil {
+			project.Err = err
+			continue
+		}
+		if settings.precommit {
+			if staged, err := internal.StagedFiles(files); err != nil {
+				fmt.Printf("Warning: cannot list the staged files of project %s, analyzing all %d: %v\n", project.Name, len(files), err)
+			} else {
+				files = staged
+			}
+		}
+		project.Files = len(files)
+		project.Duplicates = internal.DuplicateTemplates(files)
+		projectOptions.Duplicates = project.Duplicates
+
+		analyzers[i] = internal.NewAnalyzer(projectOptions)
+		for _, file := range files {
+			if err := analyzers[i].AnalyzeFileContext(analysisCtx, file); err != nil {
+				fmt.Printf("Error analyzing %s: %v\n", file, err)
+			}
+		}
+	}
+	var all internal.Results
+	for i, analyzer := range analyzers {
+		if analyzer != nil {
+			projects[i].Results = analyzer.Wait()
+			projects[i].Results.Directories = internal.DirectorySummaries(projects[i].Results)
+			all.Templates = append(all.Templates, projects[i].Results.Templates...)
+		}
+	}
+
+	if ctx.Err() != nil {
+		fmt.Println("Analysis interrupted")
+	} else if analysisCtx.Err() != nil {
+		printSkipped(all, settings.budget)
+	} else {
+		fmt.Println("Analysis complete")
+	}
+
+	failed := false
+	for _, project := range projects {
+		fmt.Printf("\n== Project %s (%s) ==\n", project.Name, project.Root)
+		if project.Err != nil {
+			fmt.Printf("Error: %v\n", project.Err)
+			failed = true
+			continue
+		}
+		if err := writeProject(project, settings); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			failed = true
+		}
+	}
+
+	fmt.Printf("\nAnalyzed %d project(s):\n", len(projects))
+	if err := internal.WriteRollup(os.Stdout, projects, slowestTemplates); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case settings.strict && all.Failures() > 0:
+		fmt.Printf("%d file(s) or template(s) failed in strict mode\n", all.Failures())
+		return failureExitCode(all)
+	case settings.postProcessFail && all.HookFailures() > 0:
+		fmt.Printf("The -post-process command failed for %d template(s)\n", all.HookFailures())
+		return exitHookFailed
+	case settings.hashOnly && all.Failures() > 0:
+		fmt.Printf("%d file(s) or template(s) could not be hashed\n", all.Failures())
+		return failureExitCode(all)
+	case failed:
+		return 1
+	}
+	return 0
+}
+
+// writeProject prints the summary of a project and writes its results and
+// report to the project directory
+func writeProject(project internal.Project, settings batchSettings) error {
+	results := project.Results
+	dir := filepath.Join(settings.dir, project.Name)
+	if settings.hashOnly {
+		return internal.WriteHashes(os.Stdout, results)
+	}
+	fmt.Printf("Analyzed %d template(s) with %d finding(s), %d failure(s)\n", len(results.Templates), results.Findings(), results.Failures())
+	internal.WriteDuplicateTemplates(os.Stdout, project.Duplicates)
+	internal.WriteScope(os.Stdout, project.Scope)
+	if err := printDirectories(results); err != nil {
+		return err
+	}
+
+	if settings.similar {
+		internal.WriteSimilarityGroups(os.Stdout, results)
+	}
+	switch settings.format {
+	case "table", "text":
+		if err := internal.WriteTable(os.Stdout, results); err != nil {
+			return err
+		}
+	case "diagnostics":
+		if err := internal.WriteDiagnostics(os.Stdout, results); err != nil {
+			return err
+		}
+	}
+	if settings.report != "" || settings.reportTemplate != "" {
+		path := ""
+		if settings.report != "" {
+			path = filepath.Join(dir, filepath.Base(settings.report))
+		}
+		if err := writeReport(results, path, settings.reportTemplate); err != nil {
+			return err
+		}
+	}
+	if settings.format == "json" || settings.format == "jsonl" || settings.format == "codeclimate" {
+		name := filepath.Base(settings.out)
+		if settings.out == "" {
+			name = "results." + settings.format
+			if settings.format == "codeclimate" {
+				name = "gl-code-quality-report.json"
+			}
+		}
+		path := filepath.Join(dir, name)
+		if err := internal.WriteResults(results, settings.format, path); err != nil {
+			return err
+		}
+		fmt.Printf("Results written to %s\n", path)
+	}
+	if settings.format == "json-per-template" {
+		name := filepath.Base(settings.out)
+		if settings.out == "" {
+			name = "results"
+		}
+		path := filepath.Join(dir, name)
+		paths, err := internal.WriteTemplateResults(results, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Results of %d template(s) written to %s\n", len(paths), path)
+	}
+	return nil
+}
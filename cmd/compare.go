@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// circomPathsFlag collects repeated -circom-path flags
+type circomPathsFlag []string
+
+func (f *circomPathsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *circomPathsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// runCompare implements the compare subcommand, which compiles the same
+// templates with two circom binaries and reports structural differences
+func runCompare(args []string) {
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	inputPath := flags.String("input", "", "Input directory or file path, or @file listing the files to analyze")
+	var circomPaths circomPathsFlag
+	flags.Var(&circomPaths, "circom-path", "Path to a circom binary, given twice: the current compiler first, then the one to compare with")
+	parallelism := flags.Int("parallel", runtime.NumCPU(), "Number of parallel workers")
+	mainComponents := mainComponentFlag{}
+	flags.Var(mainComponents, "main-component", "Use a verbatim main component for a template as Name='component main = Name(...);' (repeatable)")
+	minCircomVersion := flags.String("min-circom-version", internal.DefaultMinCircomVersion, "Oldest circom version to accept")
+	timeout := flags.Duration("timeout", 0, "Maximum compilation time per template, e.g. 2m (default: no limit)")
+	arityCap := flags.Int("arity-cap", 0, "Connect constraints over more than N signals through a synthetic node instead of a clique (default: no cap)")
+	projection := flags.String("projection", "clique", "Turn constraints into edges between all their signals (clique) or through a constraint node (star)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: circuit-analyzer compare -input <file_path> -circom-path OLD -circom-path NEW [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *inputPath == "" || len(circomPaths) != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *projection != string(circuitgraph.ProjectionClique) && *projection != string(circuitgraph.ProjectionStar) {
+		fmt.Println("The -projection flag accepts clique or star")
+		os.Exit(1)
+	}
+
+	var compilers [2]internal.Circom
+	for i, path := range circomPaths {
+		compilers[i] = internal.Circom{Path: path, MinVersion: *minCircomVersion}
+		if err := internal.CheckCircomInstallation(compilers[i]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			if errors.Is(err, internal.ErrCircomNotFound) {
+				os.Exit(exitCircomNotFound)
+			}
+			os.Exit(1)
+		}
+		version, _ := internal.CircomVersion(compilers[i])
+		fmt.Printf("Using %s (%s)\n", compilers[i], version)
+	}
+
+	files, _, err := internal.GetCircomFiles(*inputPath, internal.WalkOptions{})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// The second run reuses the main components of the first, so both
+	// compilers see the same randomly generated arguments
+	var runs [2]internal.Results
+	for i, compiler := range compilers {
+		analyzer := internal.NewAnalyzer(internal.Options{
+			Parallelism:    *parallelism,
+			MainComponents: mainComponents,
+			Compiler:       internal.LocalCircom{Circom: compiler},
+			Timeout:        *timeout,
+			ArityCap:       *arityCap,
+			Projection:     circuitgraph.Projection(*projection),
+			Quiet:          true,
+		})
+		for _, file := range files {
+			if err := analyzer.AnalyzeFileContext(ctx, file); err != nil {
+				fmt.Printf("Error analyzing %s: %v\n", file, err)
+			}
+		}
+		runs[i] = analyzer.Wait()
+		if ctx.Err() != nil {
+			fmt.Println("Comparison interrupted")
+			os.Exit(exitInterrupted)
+		}
+		if i == 0 {
+			mainComponents = internal.FixedMainComponents(runs[0])
+		}
+	}
+
+	changed, err := internal.WriteComparison(os.Stdout, internal.CompareResults(runs[0], runs[1]))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d of %d template(s) changed structure\n", changed, len(runs[0].Templates))
+	if changed > 0 {
+		os.Exit(1)
+	}
+}
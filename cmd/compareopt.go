@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// runCompareOpt implements the compare-opt subcommand, which compiles the same
+// templates at two optimization levels and reports what simplification removed
+func runCompareOpt(args []string) {
+	flags := flag.NewFlagSet("compare-opt", flag.ExitOnError)
+	inputPath := flags.String("input", "", "Input directory or file path, or @file listing the files to analyze")
+	from := flags.String("from", "O0", "Optimization level of the first compilation")
+	to := flags.String("to", "O2", "Optimization level of the second compilation")
+	circomPath := flags.String("circom-path", os.Getenv("CIRCOM_PATH"), "Path to the circom binary (default: $CIRCOM_PATH, then PATH)")
+	circomDocker := flags.String("circom-docker", "", "Run circom inside the given Docker image instead of the local binary")
+	minCircomVersion := flags.String("min-circom-version", internal.DefaultMinCircomVersion, "Oldest circom version to accept")
+	parallelism := flags.Int("parallel", runtime.NumCPU(), "Number of parallel workers")
+	mainComponents := mainComponentFlag{}
+	flags.Var(mainComponents, "main-component", "Use a verbatim main component for a template as Name='component main = Name(...);' (repeatable)")
+	timeout := flags.Duration("timeout", 0, "Maximum compilation time per template, e.g. 2m (default: no limit)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: circuit-analyzer compare-opt -input <file_path> [-from O0] [-to O2] [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *inputPath == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+	for _, level := range []string{*from, *to} {
+		if level != "O0" && level != "O1" && level != "O2" {
+			fmt.Println("The -from and -to flags accept O0, O1 or O2")
+			os.Exit(1)
+		}
+	}
+
+	circom := internal.Circom{Path: *circomPath, DockerImage: *circomDocker, MinVersion: *minCircomVersion}
+	if err := internal.CheckCircomInstallation(circom); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		if errors.Is(err, internal.ErrCircomNotFound) {
+			os.Exit(exitCircomNotFound)
+		}
+		os.Exit(1)
+	}
+
+	files, _, err := internal.GetCircomFiles(*inputPath, internal.WalkOptions{})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// The second run reuses the main components of the first, so both
+	// compilations see the same randomly generated arguments
+	var runs [2]internal.Results
+	for i, level := range []string{*from, *to} {
+		analyzer := internal.NewAnalyzer(internal.Options{
+			Parallelism:    *parallelism,
+			MainComponents: mainComponents,
+			Compiler:       internal.LocalCircom{Circom: circom},
+			Timeout:        *timeout,
+			Projection:     circuitgraph.ProjectionClique,
+			Optimization:   level,
+			KeepSignals:    true,
+			Quiet:          true,
+		})
+		for _, file := range files {
+			if err := analyzer.AnalyzeFileContext(ctx, file); err != nil {
+				fmt.Printf("Error analyzing %s: %v\n", file, err)
+			}
+		}
+		runs[i] = analyzer.Wait()
+		if ctx.Err() != nil {
+			fmt.Println("Comparison interrupted")
+			os.Exit(exitInterrupted)
+		}
+		if i == 0 {
+			mainComponents = internal.FixedMainComponents(runs[0])
+		}
+	}
+
+	// Templates by file and name in either run
+	var byName [2]map[[2]string]internal.TemplateResult
+	for i, run := range runs {
+		byName[i] = make(map[[2]string]internal.TemplateResult)
+		for _, t := range run.Templates {
+			byName[i][[2]string{t.File, t.Template}] = t
+		}
+	}
+	fmt.Printf("Comparing --%s with --%s\n", *from, *to)
+	for _, d := range internal.CompareResults(runs[0], runs[1]) {
+		if d.OldError != "" || d.NewError != "" {
+			fmt.Printf("\n%s in %s: failed at --%s or --%s\n", d.Template, d.File, *from, *to)
+			continue
+		}
+		key := [2]string{d.File, d.Template}
+		internal.WriteSignalDiff(os.Stdout, d, internal.CompareSignals(byName[0][key], byName[1][key]))
+	}
+}
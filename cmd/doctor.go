@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+)
+
+// runDoctor implements the doctor subcommand, which diagnoses the environment
+func runDoctor(args []string) {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	inputPath := flags.String("input", "", "Optional input directory or file path to check for .circom files")
+	circomPath := flags.String("circom-path", os.Getenv("CIRCOM_PATH"), "Path to the circom binary (default: $CIRCOM_PATH, then PATH)")
+	circomDocker := flags.String("circom-docker", "", "Run circom inside the given Docker image instead of the local binary")
+	minCircomVersion := flags.String("min-circom-version", internal.DefaultMinCircomVersion, "Oldest circom version to accept")
+	var libraries listFlag
+	flags.Var(&libraries, "l", "Library directory passed to circom with -l, checked for access (repeatable)")
+	flags.Parse(args)
+
+	outputDir, err := os.Getwd()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	circom := internal.Circom{Path: *circomPath, DockerImage: *circomDocker, MinVersion: *minCircomVersion}
+	if !internal.Doctor(circom, outputDir, *inputPath, libraries) {
+		os.Exit(1)
+	}
+}
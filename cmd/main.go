@@ -1,51 +1,805 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
 
 	"github.com/Artifex1/circuit-graph-analysis/internal"
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// Exit codes
+const (
+	exitCircomNotFound = 2   // No usable circom compiler
+	exitCompileError   = 3   // A template failed to compile (strict mode)
+	exitParseError     = 4   // Compiler output could not be read (strict mode)
+	exitHookFailed     = 5   // A -post-process hook exited non-zero (-post-process-fail)
+	exitInterrupted    = 130 // Stopped by Ctrl-C, as shells report SIGINT
 )
 
+// profiles bundle settings for a use case, applied with -profile to the flags
+// not set on the command line
+var profiles = map[string]map[string]string{
+	// Fast enough for a pre-commit hook: no visualization, a short compile
+	// timeout, the cheap checks only and one line per finding
+	"precommit": {
+		"format":           "diagnostics",
+		"timeout":          "10s",
+		"budget":           "30s",
+		"visualize":        "false",
+		"report":           "",
+		"hot-spots":        "0",
+		"hub-threshold":    "0",
+		"pinned-threshold": "0",
+		"patterns":         "false",
+		"coloring":         "false",
+		"analyze-includes": "true",
+	},
+}
+
+// applyProfile sets the flags of the named profile that were not set explicitly
+func applyProfile(name string) error {
+	settings, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q, expected precommit", name)
+	}
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	for flagName, value := range settings {
+		if !explicit[flagName] {
+			if err := flag.Set(flagName, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printSkipped lists the templates and files the budget stopped
+func printSkipped(results internal.Results, budget time.Duration) {
+	var skipped []string
+	for _, t := range results.Templates {
+		var interrupted *internal.InterruptedError
+		if errors.As(t.Err(), &interrupted) {
+			if t.Template == "" {
+				skipped = append(skipped, t.File)
+			} else {
+				skipped = append(skipped, t.File+":"+t.Template)
+			}
+		}
+	}
+	fmt.Printf("Budget of %s exceeded, skipped %d template(s) or file(s): %s\n", budget, len(skipped), strings.Join(skipped, ", "))
+}
+
+// inputRoot returns the input path if it is a directory, which the paths of
+// the results are then relative to, empty otherwise
+func inputRoot(path string) string {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return path
+	}
+	return ""
+}
+
+// printDirectories breaks the results down by top-level directory, if they
+// span more than one
+func printDirectories(results internal.Results) error {
+	if len(results.Directories) < 2 {
+		return nil
+	}
+	fmt.Println("By directory:")
+	return internal.WriteDirectorySummaries(os.Stdout, results.Directories)
+}
+
+// writeHashes prints the topology hashes of a -hash-only run and returns its
+// exit code, non-zero if a file or template could not be hashed
+func writeHashes(results internal.Results) int {
+	if err := internal.WriteHashes(os.Stdout, results); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	if results.Failures() > 0 {
+		fmt.Printf("%d file(s) or template(s) could not be hashed\n", results.Failures())
+		return failureExitCode(results)
+	}
+	return 0
+}
+
+// writeReport writes the HTML report to path, or renders the results through
+// reportTemplate if set, to path or to the output if path is empty
+func writeReport(results internal.Results, path, reportTemplate string) error {
+	if reportTemplate == "" {
+		if err := internal.WriteReport(results, path); err != nil {
+			return err
+		}
+	} else if path == "" {
+		return internal.WriteTemplateReport(os.Stdout, results, reportTemplate)
+	} else {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := internal.WriteTemplateReport(f, results, reportTemplate); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Report written to %s\n", path)
+	return nil
+}
+
+// streamTarget names where the results are streamed to, stdout with -format ndjson
+func streamTarget(out string) string {
+	if out == "" {
+		return "stdout"
+	}
+	return out
+}
+
+// observer returns the stream and the TUI that are set as a single Observer,
+// nil rather than a typed nil if there are none
+func observer(stream *internal.ResultStream, tui *internal.TUI) internal.Observer {
+	var list []internal.Observer
+	if stream != nil {
+		list = append(list, stream)
+	}
+	if tui != nil {
+		list = append(list, tui)
+	}
+	return internal.MultiObserver(list...)
+}
+
+// failureExitCode picks the exit code for a run with failed templates
+func failureExitCode(results internal.Results) int {
+	var parseErr *circuitgraph.ParseError
+	var compileErr *internal.CompileError
+	switch {
+	case results.As(&parseErr):
+		return exitParseError
+	case results.As(&compileErr):
+		return exitCompileError
+	default:
+		return 1
+	}
+}
+
+// isTerminal reports whether f is an interactive terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// argCountFlag collects repeated -argcount Name=N flags
+type argCountFlag map[string]int
+
+func (f argCountFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for name, count := range f {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, count))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f argCountFlag) Set(value string) error {
+	name, count, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected Name=N, got %q", value)
+	}
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid argument count %q for template %s", count, name)
+	}
+	f[name] = n
+	return nil
+}
+
+// listFlag collects the values of a repeated flag in order
+type listFlag []string
+
+func (f *listFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// cleanWorkFiles lists the temporary files of crashed runs below every input
+// path, removes them if force is set, and returns the exit code of -clean
+func cleanWorkFiles(inputs []string, force bool) int {
+	code := 0
+	var stale []string
+	for _, input := range inputs {
+		if strings.HasPrefix(input, "@") {
+			fmt.Printf("Skipping %s, -clean needs a directory or file path\n", input)
+			continue
+		}
+		files, err := internal.StaleWorkFiles(input)
+		if err != nil {
+			fmt.Printf("Error searching %s: %v\n", input, err)
+			code = 1
+		}
+		stale = append(stale, files...)
+	}
+	if !force {
+		for _, file := range stale {
+			fmt.Printf("Stale %s\n", file)
+		}
+		if len(stale) > 0 {
+			fmt.Printf("Found %d stale temporary file(s), run again with -force to remove them\n", len(stale))
+		} else {
+			fmt.Println("Found no stale temporary files")
+		}
+		return code
+	}
+
+	removed := 0
+	for _, file := range stale {
+		if err := os.Remove(file); err != nil {
+			fmt.Printf("Error removing %s: %v\n", file, err)
+			code = 1
+			continue
+		}
+		fmt.Printf("Removed %s\n", file)
+		removed++
+	}
+	fmt.Printf("Removed %d stale temporary file(s)\n", removed)
+	return code
+}
+
+// parseHideHubs reads a -hide-hubs value of the form degree>N
+func parseHideHubs(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	bound, ok := strings.CutPrefix(strings.ReplaceAll(value, " ", ""), "degree>")
+	n, err := strconv.Atoi(bound)
+	if !ok || err != nil || n < 1 {
+		return 0, fmt.Errorf("expected degree>N with N at least 1, got %q", value)
+	}
+	return n, nil
+}
+
+// mainComponentFlag collects repeated -main-component Name='component main = Name(...);' flags
+type mainComponentFlag map[string]string
+
+func (f mainComponentFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for name, mainComponent := range f {
+		pairs = append(pairs, name+"="+mainComponent)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (f mainComponentFlag) Set(value string) error {
+	name, mainComponent, ok := strings.Cut(value, "=")
+	if !ok || name == "" || strings.TrimSpace(mainComponent) == "" {
+		return fmt.Errorf("expected Name='component main = Name(...);', got %q", value)
+	}
+	f[name] = strings.TrimSpace(mainComponent)
+	return nil
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "doctor":
+			runDoctor(os.Args[2:])
+			return
+		case "query":
+			runQuery(os.Args[2:])
+			return
+		case "merge":
+			runMerge(os.Args[2:])
+			return
+		case "compare":
+			runCompare(os.Args[2:])
+			return
+		case "compare-opt":
+			runCompareOpt(os.Args[2:])
+			return
+		case "compare-params":
+			runCompareParams(os.Args[2:])
+			return
+		case "api":
+			runAPI(os.Args[2:])
+			return
+		}
+	}
+
 	// Parse command-line flags
-	inputPath := flag.String("input", "", "Input directory or file path")
+	var inputs listFlag
+	flag.Var(&inputs, "input", "Input directory or file path, or @file listing the files to analyze, repeat it to analyze several projects in a batch")
+	mainFile := flag.String("main-file", "", "Compile this file with the main component it declares, as it is, and analyze the instantiated template, like -input with -respect-main")
+	respectMain := flag.Bool("respect-main", false, "Compile the files declaring a main component as they are and analyze the template it instantiates, instead of every template with generated arguments")
+	batchFile := flag.String("batch", "", "File listing the root of every project to analyze in a batch, one per line")
+	batchDir := flag.String("batch-dir", "batch-results", "Directory receiving the outputs of every project in a batch, one subdirectory each")
 	parallelism := flag.Int("parallel", runtime.NumCPU(), "Number of parallel workers")
 	visualize := flag.Bool("visualize", false, "Whether the Graph should be visualized in HTML")
+	hideHubsFlag := flag.String("hide-hubs", "", "Leave signals matching degree>N out of the visualization, keeping them in the analysis")
+	argCounts := argCountFlag{}
+	flag.Var(argCounts, "argcount", "Override the detected argument count of a template as Name=N (repeatable)")
+	mainComponents := mainComponentFlag{}
+	flag.Var(mainComponents, "main-component", "Use a verbatim main component for a template as Name='component main = Name(...);' (repeatable)")
+	circomPath := flag.String("circom-path", os.Getenv("CIRCOM_PATH"), "Path to the circom binary (default: $CIRCOM_PATH, then PATH)")
+	circomDocker := flag.String("circom-docker", "", "Run circom inside the given Docker image instead of the local binary")
+	minCircomVersion := flag.String("min-circom-version", internal.DefaultMinCircomVersion, "Oldest circom version to accept")
+	workDir := flag.String("work-dir", "", "Directory for the temporary sources and compiler outputs, instead of next to the analyzed files, which are passed to circom with -l for their includes")
+	var libraries listFlag
+	flag.Var(&libraries, "l", "Library directory passed to circom with -l (repeatable)")
+	circomArgs := flag.String("circom-args", "", "Flags passed to circom verbatim, separated by spaces, e.g. \"--inspect --O2\", whose effect is up to you; an optimization flag replaces the default --O0")
+	timeout := flag.Duration("timeout", 0, "Maximum compilation time per template, e.g. 2m (default: no limit)")
+	degreeHistogram := flag.String("degree-histogram", "", "Export the degree distribution of each template as json or csv")
+	signalDegrees := flag.String("signal-degrees", "", "Export the degree, percentile and z-score of every signal as json or csv")
+	cooccurrence := flag.String("cooccurrence", "", "Export how many constraints mention each pair of the signals matching this glob, e.g. 'main.state[*]'")
+	analyzeIncludes := flag.Bool("analyze-includes", false, "Also analyze the templates of the files only ever included by other files, which are otherwise analyzed as part of the circuits including them")
+	analyzeVendored := flag.Bool("analyze-vendored", false, "Also analyze the files below node_modules, vendor and third_party directories")
+	followSymlinks := flag.Bool("follow-symlinks", false, "Descend into symlinked directories when searching for .circom files")
+	maxDepth := flag.Int("max-depth", 0, "Maximum directory depth to search below the input path (default: no limit)")
+	maxFileSize := flag.Int64("max-file-size", 10, "Skip .circom files larger than this many MB, 0 for no limit")
+	format := flag.String("format", "", "Output format: table (one row per template), text (detailed report), json/jsonl to also store the results in -out, json-per-template to store the result of every template as <file>_<template>.json in the -out directory, ndjson to stream every result as a JSON line to stdout and print everything else to stderr, diagnostics to print the findings as path:line:col: severity: message [rule] for editors, codeclimate to store them in -out as CodeClimate issues for GitLab, or signals-csv to also write the metrics of every signal to <template>_signals.csv (default: table on a terminal, text otherwise)")
+	verbose := flag.Bool("verbose", false, "Print the detailed report of every template along with the table, with more detail such as per-index prefix statistics")
+	report := flag.String("report", "", "Write a single HTML report of all templates, with their stats, findings and graphs, to this file")
+	reportTemplate := flag.String("report-template", "", "Render the report through this Go template file, with html/template if it ends in .html, or the built-in summary or markdown, to -report or the output")
+	out := flag.String("out", "", "File the json/jsonl results or codeclimate issues are written to, jsonl is streamed as templates complete to fd:N or a named pipe, directory for json-per-template (default: results.<format>, gl-code-quality-report.json for codeclimate, results for json-per-template)")
+	arityCap := flag.Int("arity-cap", 0, "Connect constraints over more than N signals through a synthetic node instead of a clique (default: no cap)")
+	projection := flag.String("projection", "clique", "Turn constraints into edges between all their signals (clique) or through a constraint node (star)")
+	checks := flag.String("checks", "", "Comma-separated analyses to run, e.g. underconstrained,subgraphs,stats, among "+strings.Join(circuitgraph.CheckNames, ", ")+", or all (default: all but blocks, connectivity and hot-spots)")
+	hotSpots := flag.Int("hot-spots", 5, "Report the N edges with the highest betweenness and the constraints behind them, 0 to skip")
+	groupFindings := flag.Bool("group-findings", false, "Print the findings of every template grouped by top-level component, e.g. main.hasher")
+	hubThreshold := flag.Float64("hub-threshold", 40, "Report signals sharing constraints with more than this percentage of all signals, 0 to skip")
+	listComponents := flag.Int("list-components", 10, "List the signals of independent subgraphs up to N signals, summarize larger ones by size and prefixes")
+	similar := flag.Bool("similar", false, "Group structurally near-identical templates across all files after the analysis")
+	componentTotals := flag.Bool("component-totals", false, "Attribute every constraint to the deepest component shared by its signals and report the totals per component")
+	groupArrays := flag.Bool("group-arrays", false, "Merge the instances of component arrays, e.g. main.hashers[*], in the component totals")
+	dropConstant := flag.Bool("drop-constant", false, "Leave the constant \"1\" signal out of the graph, the visualization, the stats and all exports")
+	patterns := flag.Bool("patterns", false, "Count the repeated local structures of array signal families, e.g. those written by loops")
+	coloring := flag.Bool("coloring", false, "Color the signals greedily and report the number of colors and the largest color class, groups of signals sharing no constraint (slow on dense graphs)")
+	argSamples := flag.Int("arg-samples", 1, "Analyze every template with this many sets of random arguments and combine their findings")
+	argAggregate := flag.String("arg-aggregate", "intersect", "Keep the findings of every sample (intersect), of any sample (union) or of more than half of them (majority)")
+	deterministic := flag.Bool("deterministic", false, "Generate the same arguments for a template on every run and leave out timings, so that outputs can be committed and diffed")
+	lowMemory := flag.Bool("low-memory", false, "Analyze one template at a time and keep only the stats and finding counts of finished ones, trading speed and detail for a bounded memory use")
+	postProcess := flag.String("post-process", "", "Pipe the result of every template as JSON to this command, whose JSON output, if any, replaces it")
+	postProcessFail := flag.Bool("post-process-fail", false, "Exit non-zero if the -post-process command exits non-zero for any template")
+	prefixStats := flag.Int("prefix-stats", 0, "Print the signal count, average degree, flagged signals and share of constraints per signal name prefix, N levels deep")
+	pinnedThreshold := flag.Float64("pinned-threshold", 0.9, "Report signals with at least this share of their constraints also mentioning the \"1\" signal, 0 to skip")
+	maxVisualizeNodes := flag.Int("visualize-max-nodes", 5000, "Write graphs with more nodes as <template>_circuit_graph.dot instead of rendering them, 0 for no limit")
+	maxVisualizeEdges := flag.Int("visualize-max-edges", 50000, "Write graphs with more edges as <template>_circuit_graph.dot instead of rendering them, 0 for no limit")
+	renderTimeout := flag.Duration("render-timeout", 30*time.Second, "Skip a visualization whose rendering takes longer than this, 0 for no limit")
+	removeSignals := flag.String("remove-signals", "", "Comma-separated globs of signals, e.g. 'main.nonce,main.salt[*]', to also remove for a what-if component analysis")
+	showCommands := flag.Bool("show-commands", false, "Print the circom command line and main component of every template")
+	strict := flag.Bool("strict", false, "Abort a template on malformed compiler output and exit non-zero")
+	profile := flag.String("profile", "", "Bundle of settings: precommit analyzes only the files staged in git, with the cheap checks, a 10s compile timeout and a 30s budget, printing one line per finding")
+	budget := flag.Duration("budget", 0, "Stop the whole analysis after this long and list the templates skipped (default: no limit)")
+	tuiMode := flag.Bool("tui", false, "Show the templates live as workers complete them, then browse their findings and metrics with the keyboard, plain output if the output is not a terminal")
+	hashOnly := flag.Bool("hash-only", false, "Only print a hash of the graph topology of every template as template: hash, with deterministic arguments, for a check that the structure did not change")
+	clean := flag.Bool("clean", false, "List the temporary circuit-analyzer_*.circom files and their outputs left below the -input paths by crashed runs, then exit")
+	force := flag.Bool("force", false, "With -clean, remove the files it lists, not while another analysis of them is running")
+	configFile := flag.String("config", "", "JSON file of settings per template, its args or the range and rules of generated ones, public signals, checks and expected metrics, over the flags")
 	flag.Parse()
 
-	if *inputPath == "" {
+	if *profile != "" {
+		if err := applyProfile(*profile); err != nil {
+			fmt.Printf("The -profile flag: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *batchFile != "" {
+		roots, err := readProjectRoots(*batchFile)
+		if err != nil {
+			fmt.Printf("The -batch flag: %v\n", err)
+			os.Exit(1)
+		}
+		inputs = append(inputs, roots...)
+	}
+	if *mainFile != "" {
+		if len(inputs) > 0 {
+			fmt.Println("The -main-file flag replaces -input and -batch, use -respect-main to honor the main components of their files")
+			os.Exit(1)
+		}
+		inputs = append(inputs, *mainFile)
+		*respectMain = true
+	}
+	if len(inputs) == 0 {
 		fmt.Println("Please provide an input path using the -input flag")
 		os.Exit(1)
 	}
+	if *clean {
+		if *workDir != "" {
+			inputs = append(inputs, *workDir)
+		}
+		os.Exit(cleanWorkFiles(inputs, *force))
+	}
+	batch := len(inputs) > 1 || *batchFile != ""
+	if batch && (*format == "ndjson" || *format == "jsonl" && *out != "" && internal.IsStreamTarget(*out)) {
+		fmt.Println("Streaming the results to -out is not supported with several projects")
+		os.Exit(1)
+	}
+	if *format == "" {
+		*format = "text"
+		if isTerminal(os.Stdout) {
+			*format = "table"
+		}
+	}
+	if *format != "table" && *format != "text" && *format != "json" && *format != "jsonl" && *format != "ndjson" && *format != "diagnostics" && *format != "codeclimate" && *format != "signals-csv" && *format != "json-per-template" {
+		fmt.Println("The -format flag accepts table, text, json, jsonl, json-per-template, ndjson, diagnostics, codeclimate or signals-csv")
+		os.Exit(1)
+	}
+	// ndjson keeps stdout for the results, so that it can be piped into line
+	// tools, and everything else printed goes to stderr
+	stdout := os.Stdout
+	if *format == "ndjson" {
+		if *out != "" {
+			fmt.Println("The -out flag does not apply to -format ndjson, which writes to stdout")
+			os.Exit(1)
+		}
+		os.Stdout = os.Stderr
+	}
+	if *projection != string(circuitgraph.ProjectionClique) && *projection != string(circuitgraph.ProjectionStar) {
+		fmt.Println("The -projection flag accepts clique or star")
+		os.Exit(1)
+	}
+	if *degreeHistogram != "" && *degreeHistogram != "json" && *degreeHistogram != "csv" {
+		fmt.Println("The -degree-histogram flag accepts json or csv")
+		os.Exit(1)
+	}
+	if *argSamples < 1 {
+		fmt.Println("The -arg-samples flag needs at least 1 sample")
+		os.Exit(1)
+	}
+	switch internal.ArgAggregate(*argAggregate) {
+	case internal.AggregateIntersect, internal.AggregateUnion, internal.AggregateMajority:
+	default:
+		fmt.Println("The -arg-aggregate flag accepts intersect, union or majority")
+		os.Exit(1)
+	}
+	if *postProcess != "" && strings.TrimSpace(*postProcess) == "" {
+		fmt.Println("The -post-process flag needs a command")
+		os.Exit(1)
+	}
+	var removedSignals []string
+	for _, pattern := range strings.Split(*removeSignals, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			removedSignals = append(removedSignals, pattern)
+		}
+	}
+	checkSet, err := circuitgraph.ParseChecks(*checks)
+	if err != nil {
+		fmt.Printf("The -checks flag: %v\n", err)
+		os.Exit(1)
+	}
+	hideHubs, err := parseHideHubs(*hideHubsFlag)
+	if err != nil {
+		fmt.Printf("The -hide-hubs flag: %v\n", err)
+		os.Exit(1)
+	}
+	if *signalDegrees != "" && *signalDegrees != "json" && *signalDegrees != "csv" {
+		fmt.Println("The -signal-degrees flag accepts json or csv")
+		os.Exit(1)
+	}
+
+	if *reportTemplate != "" && !internal.IsBuiltinReportTemplate(*reportTemplate) {
+		if _, err := os.Stat(*reportTemplate); err != nil {
+			fmt.Printf("The -report-template flag: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// circom runs in the work directory, so relative paths would no longer
+	// point where they were meant to
+	if *workDir != "" {
+		if *workDir, err = filepath.Abs(*workDir); err == nil {
+			err = os.MkdirAll(*workDir, 0755)
+		}
+		if err != nil {
+			fmt.Printf("The -work-dir flag: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	for i, library := range libraries {
+		if libraries[i], err = filepath.Abs(library); err != nil {
+			fmt.Printf("The -l flag: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	passthrough := strings.Fields(*circomArgs)
+	if err := internal.CheckCircomArgs(passthrough); err != nil {
+		fmt.Printf("The -circom-args flag: %v\n", err)
+		os.Exit(1)
+	}
+	var config internal.Config
+	if *configFile != "" {
+		if config, err = internal.LoadConfig(*configFile); err != nil {
+			fmt.Printf("The -config flag: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	circom := internal.Circom{Path: *circomPath, DockerImage: *circomDocker, MinVersion: *minCircomVersion}
 
 	// Check if circom is installed
-	if err := internal.CheckCircomInstallation(); err != nil {
+	if err := internal.CheckCircomInstallation(circom); err != nil {
 		fmt.Printf("Error: %v\n", err)
+		if errors.Is(err, internal.ErrCircomNotFound) {
+			fmt.Println("Install circom from https://docs.circom.io/getting-started/installation/ or point -circom-path at it")
+			os.Exit(exitCircomNotFound)
+		}
 		os.Exit(1)
 	}
+	version, _ := internal.CircomVersion(circom)
+	if !*hashOnly {
+		fmt.Printf("Using %s (%s)\n", circom, version)
+	}
+
+	options := internal.Options{
+		Parallelism:    *parallelism,
+		Visualize:      *visualize,
+		ArgCounts:      argCounts,
+		MainComponents: mainComponents,
+		Compiler:       internal.LocalCircom{Circom: circom},
+		Timeout:        *timeout,
+		WorkDir:        *workDir,
+		RespectMain:    *respectMain,
+		Libraries:      libraries,
+		CircomArgs:     passthrough,
+		Templates:      config.Templates,
+
+		DegreeHistogram: *degreeHistogram,
+		SignalDegrees:   *signalDegrees,
+		Cooccurrence:    *cooccurrence,
+		Strict:          *strict,
+		MaxFileSize:     *maxFileSize << 20,
+		ArityCap:        *arityCap,
+		Projection:      circuitgraph.Projection(*projection),
+		ShowCommands:    *showCommands,
+		HotSpots:        *hotSpots,
+		GroupFindings:   *groupFindings,
+		HubThreshold:    *hubThreshold,
+		ListComponents:  *listComponents,
+		Report:          *report != "",
+		HideHubs:        hideHubs,
+		SignalsCSV:      *format == "signals-csv",
+		ComponentTotals: *componentTotals,
+		GroupArrays:     *groupArrays,
+		DropConstant:    *dropConstant,
+		Patterns:        *patterns,
+		Coloring:        *coloring,
+		ArgSamples:      *argSamples,
+		ArgAggregate:    internal.ArgAggregate(*argAggregate),
+		LowMemory:       *lowMemory,
+		Deterministic:   *deterministic,
+		PostProcess:     *postProcess,
+		PrefixStats:     *prefixStats,
+		Verbose:         *verbose,
+		PinnedThreshold: *pinnedThreshold,
+		RemoveSignals:   removedSignals,
+
+		MaxVisualizeNodes: *maxVisualizeNodes,
+		MaxVisualizeEdges: *maxVisualizeEdges,
+		RenderTimeout:     *renderTimeout,
+		Quiet:             (*format == "table" || *format == "ndjson" || *format == "diagnostics" || batch || *hashOnly) && !*verbose,
+		Quick:             *profile == "precommit",
+		Checks:            checkSet,
+		HashOnly:          *hashOnly,
+	}
+
+	// The first Ctrl-C or SIGTERM stops the analysis and kills running
+	// compilations, the second one removes the temporary files and exits
+	// right away
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		stop()
+		<-signals
+		internal.RemoveWorkFiles()
+		os.Exit(exitInterrupted)
+	}()
+
+	// The budget stops the analysis like Ctrl-C, but the run still reports
+	analysisCtx := ctx
+	if *budget > 0 {
+		var cancel context.CancelFunc
+		analysisCtx, cancel = context.WithTimeout(ctx, *budget)
+		defer cancel()
+	}
+
+	walkOptions := internal.WalkOptions{
+		FollowSymlinks: *followSymlinks,
+		MaxDepth:       *maxDepth,
+	}
+	scopeOptions := internal.ScopeOptions{Includes: *analyzeIncludes, Vendored: *analyzeVendored, Libraries: libraries}
+	if batch {
+		if *tuiMode {
+			fmt.Println("The -tui flag does not apply to several projects, printing plain output")
+		}
+		os.Exit(runBatch(ctx, analysisCtx, inputs, options, batchSettings{
+			dir:             *batchDir,
+			walk:            walkOptions,
+			scope:           scopeOptions,
+			parallelism:     *parallelism,
+			format:          *format,
+			out:             *out,
+			report:          *report,
+			reportTemplate:  *reportTemplate,
+			precommit:       *profile == "precommit",
+			budget:          *budget,
+			similar:         *similar,
+			strict:          *strict,
+			postProcessFail: *postProcessFail,
+			hashOnly:        *hashOnly,
+		}))
+	}
 
 	// Get all .circom files
-	files, err := internal.GetCircomFiles(*inputPath)
+	files, walkStats, err := internal.GetCircomFiles(inputs[0], walkOptions)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	if walkStats.DirsVisited > 0 && !*hashOnly {
+		fmt.Printf("Found %d .circom files: %s\n", len(files), walkStats)
+	}
+	scopeOptions.Root = inputRoot(inputs[0])
+	scope := internal.ScopeFiles(files, scopeOptions)
+	files = scope.Files
+	if *profile == "precommit" {
+		staged, err := internal.StagedFiles(files)
+		if err != nil {
+			fmt.Printf("Warning: cannot list the staged files, analyzing all %d: %v\n", len(files), err)
+		} else {
+			fmt.Printf("%d of the %d .circom files are staged\n", len(staged), len(files))
+			files = staged
+		}
+	}
+
+	// JSON Lines to a file descriptor or named pipe are streamed as templates complete
+	var stream *internal.ResultStream
+	if *format == "jsonl" && *out != "" && internal.IsStreamTarget(*out) {
+		f, err := internal.OpenStream(*out)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		stream = internal.NewResultStream(f)
+	} else if *format == "ndjson" {
+		stream = internal.NewResultStream(stdout)
+	}
+
+	// The TUI needs a terminal to draw on and read keys from
+	var tui *internal.TUI
+	if *tuiMode && *format == "ndjson" {
+		fmt.Println("-tui is ignored with -format ndjson, whose output is the results")
+	} else if *tuiMode && !*hashOnly {
+		if isTerminal(os.Stdout) && isTerminal(os.Stdin) {
+			tui = internal.NewTUI(os.Stdout, options.OutputDir)
+			options.Quiet = true
+		} else {
+			fmt.Println("The output is not a terminal, -tui falls back to plain output")
+		}
+	}
 
 	// Create an analyzer
-	analyzer := internal.NewAnalyzer(*parallelism, *visualize)
+	options.Observer = observer(stream, tui)
+	options.Root = inputRoot(inputs[0])
+	options.Duplicates = internal.DuplicateTemplates(files)
+	analyzer := internal.NewAnalyzer(options)
 
 	// Process each file
 	for _, file := range files {
-		if err := analyzer.AnalyzeFile(file); err != nil {
+		if err := analyzer.AnalyzeFileContext(analysisCtx, file); err != nil {
 			fmt.Printf("Error analyzing %s: %v\n", file, err)
 		}
 	}
 
 	// Wait for all analysis to complete
-	analyzer.Wait()
+	results := analyzer.Wait()
+	results.Directories = internal.DirectorySummaries(results)
+	if tui != nil && ctx.Err() == nil {
+		if err := tui.Browse(os.Stdin); err != nil {
+			// The TUI kept the report quiet, print it instead
+			fmt.Printf("Warning: cannot browse the results: %v\n", err)
+			if err := internal.WriteTemplateReport(os.Stdout, results, "summary"); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+		}
+	}
+	if *hashOnly {
+		if ctx.Err() != nil {
+			os.Exit(exitInterrupted)
+		}
+		os.Exit(writeHashes(results))
+	}
 
-	fmt.Println("Analysis complete")
+	if ctx.Err() != nil {
+		fmt.Println("Analysis interrupted")
+	} else if analysisCtx.Err() != nil {
+		printSkipped(results, *budget)
+	} else {
+		fmt.Println("Analysis complete")
+	}
+	fmt.Printf("Analyzed %d template(s) with %d finding(s), %d failure(s)\n", len(results.Templates), results.Findings(), results.Failures())
+	internal.WriteDuplicateTemplates(os.Stdout, options.Duplicates)
+	internal.WriteScope(os.Stdout, scope)
+	if err := printDirectories(results); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *similar {
+		internal.WriteSimilarityGroups(os.Stdout, results)
+	}
+	if *format == "table" {
+		if err := internal.WriteTable(os.Stdout, results); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *format == "diagnostics" {
+		if err := internal.WriteDiagnostics(os.Stdout, results); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *report != "" || *reportTemplate != "" {
+		if err := writeReport(results, *report, *reportTemplate); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if stream != nil {
+		if err := stream.Err(); err != nil {
+			fmt.Printf("Error: streaming the results to %s: %v\n", streamTarget(*out), err)
+			os.Exit(1)
+		}
+		fmt.Printf("Results streamed to %s\n", streamTarget(*out))
+	} else if *format == "json" || *format == "jsonl" || *format == "codeclimate" {
+		if *out == "" {
+			*out = "results." + *format
+			if *format == "codeclimate" {
+				*out = "gl-code-quality-report.json" // The name GitLab's documentation uses
+			}
+		}
+		if err := internal.WriteResults(results, *format, *out); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Results written to %s\n", *out)
+	} else if *format == "json-per-template" {
+		if *out == "" {
+			*out = "results"
+		}
+		paths, err := internal.WriteTemplateResults(results, *out)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Results of %d template(s) written to %s\n", len(paths), *out)
+	}
+
+	if ctx.Err() != nil {
+		os.Exit(exitInterrupted)
+	}
+	if *strict && results.Failures() > 0 {
+		fmt.Printf("%d file(s) or template(s) failed in strict mode\n", results.Failures())
+		os.Exit(failureExitCode(results))
+	}
+	if *postProcessFail && results.HookFailures() > 0 {
+		fmt.Printf("The -post-process command failed for %d template(s)\n", results.HookFailures())
+		os.Exit(exitHookFailed)
+	}
 }
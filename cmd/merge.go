@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+)
+
+// runMerge implements the merge subcommand, which consolidates the findings
+// of several stored runs into one deduplicated list
+func runMerge(args []string) {
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "Print the merged findings as JSON Lines, with the runs reporting each")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: circuit-analyzer merge [flags] <results.json|results.jsonl>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	names := flags.Args()
+	runs := make([]internal.Results, len(names))
+	total := 0
+	for i, path := range names {
+		results, err := internal.LoadResults(path)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		runs[i] = results
+		total += results.Findings()
+	}
+
+	merged := internal.MergeFindings(names, runs)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		for _, finding := range merged {
+			encoder.Encode(finding)
+		}
+		return
+	}
+	for _, match := range merged {
+		fmt.Printf("%s: %s: [%s] %s %s: %s (%s)\n", match.File, match.Template, match.Finding.Severity,
+			match.Finding.Category, match.Finding.Signal, match.Finding.Message, strings.Join(match.Runs, ", "))
+	}
+	fmt.Printf("Merged %d finding(s) of %d run(s) into %d\n", total, len(names), len(merged))
+}
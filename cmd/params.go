@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// runCompareParams implements the compare-params subcommand, which analyzes
+// one template with several sets of arguments and compares the structures
+func runCompareParams(args []string) {
+	flags := flag.NewFlagSet("compare-params", flag.ExitOnError)
+	inputPath := flags.String("input", "", "File declaring the template")
+	template := flags.String("template", "", "Name of the template to analyze")
+	var argSets listFlag
+	flags.Var(&argSets, "args", "Comma-separated arguments of the template, e.g. 4,2 (repeat for every set, at least twice)")
+	circomPath := flags.String("circom-path", os.Getenv("CIRCOM_PATH"), "Path to the circom binary (default: $CIRCOM_PATH, then PATH)")
+	circomDocker := flags.String("circom-docker", "", "Run circom inside the given Docker image instead of the local binary")
+	minCircomVersion := flags.String("min-circom-version", internal.DefaultMinCircomVersion, "Oldest circom version to accept")
+	timeout := flags.Duration("timeout", 0, "Maximum compilation time per template, e.g. 2m (default: no limit)")
+	projection := flags.String("projection", "clique", "Turn constraints into edges between all their signals (clique) or through a constraint node (star)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: circuit-analyzer compare-params -input <file> -template Name -args 4,2 -args 8,2 [flags]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *inputPath == "" || *template == "" || len(argSets) < 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *projection != string(circuitgraph.ProjectionClique) && *projection != string(circuitgraph.ProjectionStar) {
+		fmt.Println("The -projection flag accepts clique or star")
+		os.Exit(1)
+	}
+
+	circom := internal.Circom{Path: *circomPath, DockerImage: *circomDocker, MinVersion: *minCircomVersion}
+	if err := internal.CheckCircomInstallation(circom); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		if errors.Is(err, internal.ErrCircomNotFound) {
+			os.Exit(exitCircomNotFound)
+		}
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	var runs []internal.ParamRun
+	for _, argSet := range argSets {
+		values := strings.Split(argSet, ",")
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+		analyzer := internal.NewAnalyzer(internal.Options{
+			Parallelism:    1,
+			MainComponents: map[string]string{*template: internal.MainComponent(*template, values)},
+			Compiler:       internal.LocalCircom{Circom: circom},
+			Timeout:        *timeout,
+			Projection:     circuitgraph.Projection(*projection),
+			Only:           *template,
+			SignalFamilies: true,
+			Quiet:          true,
+		})
+		if err := analyzer.AnalyzeFileContext(ctx, *inputPath); err != nil {
+			fmt.Printf("Error analyzing %s: %v\n", *inputPath, err)
+			os.Exit(1)
+		}
+		results := analyzer.Wait()
+		if ctx.Err() != nil {
+			fmt.Println("Comparison interrupted")
+			os.Exit(exitInterrupted)
+		}
+		if len(results.Templates) == 0 {
+			fmt.Printf("Template %s not found in %s\n", *template, *inputPath)
+			os.Exit(1)
+		}
+		runs = append(runs, internal.ParamRun{Args: strings.Join(values, ", "), Result: results.Templates[0]})
+	}
+
+	comparison := internal.CompareParams(runs)
+	if err := internal.WriteParamComparison(os.Stdout, runs, comparison); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(comparison.Findings) > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// runQuery implements the query subcommand, which filters findings in stored results
+func runQuery(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	template := flags.String("template", "", "Only include templates whose name matches this glob")
+	signal := flags.String("signal", "", "Only include findings whose signal matches this glob, e.g. '*nullifier*'")
+	category := flags.String("category", "", "Only include findings of this category")
+	severity := flags.String("severity", "", "Only include findings of at least this severity (info, low, medium, high, critical)")
+	asJSON := flags.Bool("json", false, "Print the matches as JSON Lines")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: circuit-analyzer query [flags] <results.json|results.jsonl>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *severity != "" && circuitgraph.Severity(*severity).Rank() < 0 {
+		fmt.Printf("Unknown severity %q\n", *severity)
+		os.Exit(1)
+	}
+
+	query := internal.Query{
+		Template:    *template,
+		Signal:      *signal,
+		Category:    *category,
+		MinSeverity: circuitgraph.Severity(*severity),
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	for _, path := range flags.Args() {
+		results, err := internal.LoadResults(path)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, match := range internal.QueryFindings(results, query) {
+			if *asJSON {
+				encoder.Encode(match)
+				continue
+			}
+			fmt.Printf("%s: %s: [%s] %s %s: %s\n", match.File, match.Template, match.Finding.Severity,
+				match.Finding.Category, match.Finding.Signal, match.Finding.Message)
+		}
+	}
+}
@@ -4,9 +4,13 @@ go 1.22.0
 
 toolchain go1.22.7
 
-require gonum.org/v1/gonum v0.15.1
+require (
+	github.com/go-echarts/go-echarts/v2 v2.4.2
+	golang.org/x/term v0.29.0
+	gonum.org/v1/gonum v0.15.1
+)
 
 require (
-	github.com/go-echarts/go-echarts/v2 v2.4.2 // indirect
 	golang.org/x/exp v0.0.0-20240909161429-701f63a606c0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
 )
@@ -1,265 +1,1369 @@
 package internal
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/go-echarts/go-echarts/v2/charts"
 	"github.com/go-echarts/go-echarts/v2/opts"
-	"gonum.org/v1/gonum/graph"
-	"gonum.org/v1/gonum/graph/simple"
-	"gonum.org/v1/gonum/graph/topo"
+	"github.com/go-echarts/go-echarts/v2/types"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
 )
 
+type Options struct {
+	Parallelism int            // Number of files analyzed concurrently
+	Workers     chan struct{}  // Worker slots shared with other analyzers, Parallelism slots of its own if nil
+	OutputDir   string         // Directory of the files written per template, the working directory if empty
+	Visualize   bool           // Render the constraint graphs to HTML
+	ArgCounts   map[string]int // Per-template overrides for the detected argument count
+	// Per-template main components used verbatim instead of the generated one,
+	// by template name or by file and name as in FixedMainComponents
+	MainComponents map[string]string
+	Compiler       Compiler      // Compiles the generated circuits, LocalCircom by default
+	Observer       Observer      // Notified of the stages of every template, nil for none
+	Timeout        time.Duration // Maximum compilation time per template, 0 for no limit
+
+	// Directory receiving the temporary sources and the compiler outputs,
+	// next to the analyzed files if empty. The directory of every analyzed
+	// file is then passed to the compiler as a library, for its includes.
+	WorkDir string
+	// Library directories passed to the compiler with -l, after that of the
+	// analyzed file with a WorkDir
+	Libraries []string
+	// Compile files declaring a main component as they are and analyze the
+	// template it instantiates, instead of every template with generated
+	// arguments
+	RespectMain bool
+
+	// Per-template settings of a -config file, on top of the other options
+	Templates map[string]TemplateConfig
+	// Input directory the paths of the results are relative to, empty to leave them out
+	Root string
+	// Files declaring every template name declared by more than one, as found
+	// by DuplicateTemplates, whose outputs are named after their file as well
+	Duplicates map[string][]string
+
+	DegreeHistogram string                  // Export the degree distribution as "json" or "csv", empty to disable
+	SignalDegrees   string                  // Export the degree, percentile and z-score of every signal as "json" or "csv", empty to disable
+	Cooccurrence    string                  // Glob selecting the signals of the co-occurrence matrix export, empty to disable
+	Strict          bool                    // Abort a template on malformed compiler output instead of warning
+	MaxFileSize     int64                   // Files larger than this many bytes are skipped, 0 for no limit
+	ArityCap        int                     // Constraints over more signals are drawn as a star, 0 for no cap
+	Projection      circuitgraph.Projection // Clique or star projection of all constraints, clique if empty
+	ShowCommands    bool                    // Print the compiler command line of every template
+	HotSpots        int                     // Number of edges with the highest betweenness to report, 0 to skip
+	GroupFindings   bool                    // Print the findings of every template grouped by top-level component
+	HubThreshold    float64                 // Report signals connected to more than this percentage of the graph, 0 to skip
+	ListComponents  int                     // Components up to this many signals are listed in full, larger ones summarized
+	Report          bool                    // Keep a chart of every graph small enough for WriteReport
+	HideHubs        int                     // Leave signals of a higher degree out of the graph charts, 0 to draw all
+	Only            string                  // Analyze only the template of this name, all templates if empty
+	SignalFamilies  bool                    // Keep the signal families of every template for CompareParams
+	SignalsCSV      bool                    // Write the metrics of every signal to <template>_signals.csv
+	Optimization    string                  // circom simplification level, O0 if empty
+	CircomArgs      []string                // Flags passed to circom verbatim, whose effect is up to the user
+	KeepSignals     bool                    // Keep the signals of every graph for CompareSignals
+	ComponentTotals bool                    // Attribute the constraints to the components of the circuit
+	GroupArrays     bool                    // Merge the instances of component arrays in the component totals
+	DropConstant    bool                    // Leave the "1" signal out of the graph and everything computed from it
+	Patterns        bool                    // Report the repeated local structures of array signal families
+	Coloring        bool                    // Report a greedy coloring of the signals, see circuitgraph.GreedyColoring
+	ArgSamples      int                     // Analyze templates with random arguments this many times, 0 or 1 for once
+	ArgAggregate    ArgAggregate            // How the findings of several samples are combined, intersect by default
+	LowMemory       bool                    // Analyze one template at a time and keep only the results of finished ones
+	PostProcess     string                  // Command receiving the result of every template as JSON, whose output replaces it
+	PrefixStats     int                     // Print statistics per signal name prefix this many levels deep, 0 to skip
+	Verbose         bool                    // Add detail to the report, such as the statistics of every array index
+	PinnedThreshold float64                 // Report signals with at least this share of their constraints mentioning "1", 0 to skip
+	RemoveSignals   []string                // Globs of signals to also remove for a what-if component analysis
+	Quiet           bool                    // Only print warnings and errors, not the report of every template
+	Quick           bool                    // Only run the cheap checks, see circuitgraph.AnalyzeOptions
+	Checks          circuitgraph.CheckSet   // Analyses to run, all of them if nil, see circuitgraph.ParseChecks
+	HashOnly        bool                    // Only hash the topology of the graph, skipping the analysis and all exports
+	Deterministic   bool                    // Seed the generated arguments per template and leave out timings, so that runs over the same inputs give identical outputs
+
+	// Guards keeping a giant graph from stalling a run with -visualize
+	MaxVisualizeNodes int           // Write larger graphs as DOT instead of rendering them, 0 for no limit
+	MaxVisualizeEdges int           // Write graphs with more edges as DOT instead of rendering them, 0 for no limit
+	RenderTimeout     time.Duration // Give up on rendering a visualization after this long, 0 for no limit
+}
+
+// Analyzer can be reused: every Wait ends a run and the next AnalyzeFile
+// starts a new one. The files queued by every caller belong to the same run,
+// so callers analyzing files at the same time start runs of their own with
+// Start instead.
 type Analyzer struct {
 	workerPool chan struct{}
 	wg         sync.WaitGroup
-	visualize  bool
+	options    Options
+	results    collector
+	report     io.Writer // Receives the report of every template
+
+	mu      sync.Mutex
+	waiting int // Number of Wait calls collecting a run, no new files are accepted meanwhile
 }
 
-func NewAnalyzer(parallelism int, visualize bool) *Analyzer {
+func NewAnalyzer(options Options) *Analyzer {
+	if options.Compiler == nil {
+		options.Compiler = LocalCircom{}
+	}
+	if options.LowMemory {
+		options.Parallelism = 1
+	}
+	if options.HashOnly {
+		// The graph is all the hash needs, and it only compares across runs with the same arguments
+		options.Visualize, options.Report, options.Cooccurrence, options.ComponentTotals = false, false, "", false
+		options.ArgSamples, options.Deterministic = 1, true
+	}
+	report := io.Writer(os.Stdout)
+	if options.Quiet {
+		report = io.Discard
+	}
+	workerPool := options.Workers
+	if workerPool == nil || (options.LowMemory && cap(workerPool) > 1) {
+		workerPool = make(chan struct{}, options.Parallelism)
+	}
 	return &Analyzer{
-		workerPool: make(chan struct{}, parallelism),
-		visualize:  visualize,
+		workerPool: workerPool,
+		options:    options,
+		report:     report,
 	}
 }
 
+// Run is a run started with Analyzer.Start, whose results are only those of
+// the files queued on it
+type Run struct {
+	analyzer *Analyzer
+}
+
+// Start begins a run of its own, sharing the options and the workers of the
+// analyzer but none of the files queued on it or on other runs
+func (a *Analyzer) Start() *Run {
+	return &Run{analyzer: &Analyzer{workerPool: a.workerPool, options: a.options, report: a.report}}
+}
+
+// AnalyzeFile queues a file for the run, as Analyzer.AnalyzeFile
+func (r *Run) AnalyzeFile(filePath string) error {
+	return r.analyzer.AnalyzeFile(filePath)
+}
+
+// AnalyzeFileContext queues a file for the run, as Analyzer.AnalyzeFileContext
+func (r *Run) AnalyzeFileContext(ctx context.Context, filePath string) error {
+	return r.analyzer.AnalyzeFileContext(ctx, filePath)
+}
+
+// Wait blocks until all files queued for the run are analyzed and returns
+// their results, as Analyzer.Wait
+func (r *Run) Wait() Results {
+	return r.analyzer.Wait()
+}
+
+// AnalyzeFile queues a file for the current run. It fails with ErrAnalyzerBusy while Wait is running.
 func (a *Analyzer) AnalyzeFile(filePath string) error {
+	return a.AnalyzeFileContext(context.Background(), filePath)
+}
+
+// AnalyzeFileContext is AnalyzeFile, giving up on the file once ctx is done.
+// Running compilations are killed and their artifacts removed.
+func (a *Analyzer) AnalyzeFileContext(ctx context.Context, filePath string) error {
+	a.mu.Lock()
+	if a.waiting > 0 {
+		a.mu.Unlock()
+		return ErrAnalyzerBusy
+	}
 	a.wg.Add(1)
+	a.mu.Unlock()
+
 	go func() {
 		defer a.wg.Done()
-		a.workerPool <- struct{}{}        // Acquire a worker
-		defer func() { <-a.workerPool }() // Release the worker
-
-		if err := a.processFile(filePath); err != nil {
+		err := a.acquireWorker(ctx)
+		if err == nil {
+			defer func() { <-a.workerPool }() // Release the worker
+			err = recovered(func() error { return a.processFile(ctx, filePath) })
+		}
+		if err != nil {
+			result := TemplateResult{File: filePath, Path: a.relativePath(filePath), Error: err.Error(), err: err}
+			if a.options.Observer != nil {
+				a.options.Observer.TemplateFinished(result)
+			}
+			a.results.add(result)
 			fmt.Printf("Error processing %s: %v\n", filePath, err)
 		}
 	}()
 	return nil
 }
 
-func (a *Analyzer) processFile(filePath string) error {
-	content, err := os.ReadFile(filePath)
+// acquireWorker waits for a free worker slot, or for ctx to be done
+func (a *Analyzer) acquireWorker(ctx context.Context) error {
+	select {
+	case a.workerPool <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return interrupted(ctx, "queue")
+	}
+}
+
+// relativePath returns the path of a file relative to the root of the
+// options with forward slashes, empty if there is no root or the file is
+// outside of it
+func (a *Analyzer) relativePath(filePath string) string {
+	if a.options.Root == "" {
+		return ""
+	}
+	rel, err := filepath.Rel(a.options.Root, filePath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// interrupted returns ctx.Err() annotated with the stage it stopped, or nil if ctx is not done
+func interrupted(ctx context.Context, stage string) error {
+	if err := ctx.Err(); err != nil {
+		return &InterruptedError{Stage: stage, Err: err}
+	}
+	return nil
+}
+
+func (a *Analyzer) processFile(ctx context.Context, filePath string) error {
+	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
+
+	if a.options.MaxFileSize > 0 {
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if info.Size() > a.options.MaxFileSize {
+			result := TemplateResult{
+				File:    filePath,
+				Path:    a.relativePath(filePath),
+				Skipped: fmt.Sprintf("its size of %d bytes exceeds the limit of %d bytes", info.Size(), a.options.MaxFileSize),
+			}
+			fmt.Fprintf(a.report, "Skipping %s, %s\n", filePath, result.Skipped)
+			if a.options.Observer != nil {
+				a.options.Observer.TemplateFinished(result)
+			}
+			a.results.add(result)
+			return nil
+		}
+	}
 
-	templates := extractTemplates(string(content))
+	templates, err := extractTemplates(file)
+	if err != nil {
+		return err
+	}
+	if err := interrupted(ctx, "read"); err != nil {
+		return err
+	}
+	var main fileMain
+	asIs := false
+	if a.options.RespectMain {
+		if main, asIs, err = readFileMain(filePath); err != nil {
+			return err
+		}
+	}
+	if asIs {
+		// The instantiated template may be declared in an included file,
+		// whose signals are then unknown
+		instantiated := TemplateInfo{Name: main.template}
+		for _, template := range templates {
+			if template.Name == main.template {
+				instantiated = template
+			}
+		}
+		templates = []TemplateInfo{instantiated}
+	}
 
 	for _, template := range templates {
-		if err := a.analyzeTemplate(filePath, template); err != nil {
+		if a.options.Only != "" && template.Name != a.options.Only {
+			continue
+		}
+		result := TemplateResult{File: filePath, Path: a.relativePath(filePath), Template: template.Name, source: &template}
+		if len(a.options.Duplicates[template.Name]) > 1 {
+			result.Output = qualifiedOutput(result)
+		} else if asIs && template.Line == 0 {
+			// Compiled from the main component of a file that does not
+			// declare it, the template may be analyzed from its own file as well
+			result.Output = entryPointOutput(result)
+		}
+		if a.options.Deterministic {
+			result.random = argSource(filePath, template.Name)
+		}
+		if a.options.Observer != nil {
+			a.options.Observer.TemplateStarted(filePath, template.Name)
+		}
+		analyze := a.analyzeTemplate
+		_, custom := a.mainComponent(filePath, template.Name)
+		if asIs {
+			analyze = func(ctx context.Context, filePath string, template TemplateInfo, result *TemplateResult) error {
+				return a.analyzeMainFile(ctx, filePath, main, template, result)
+			}
+		} else if configured := len(a.options.Templates[template.Name].Args) > 0; !custom && !configured && a.options.ArgSamples > 1 {
+			analyze = a.analyzeSamples
+		}
+		start := time.Now()
+		err := recovered(func() error { return analyze(ctx, filePath, template, &result) })
+		if err != nil {
+			result.Error = err.Error()
+			result.err = err
 			fmt.Printf("Error analyzing template %s in %s: %v\n", template.Name, filePath, err)
 		}
+		if !a.options.Deterministic {
+			result.Seconds = time.Since(start).Seconds()
+		}
+		if a.options.PostProcess != "" {
+			if err := postProcess(ctx, a.options.PostProcess, &result); err != nil {
+				printWarning(fmt.Sprintf("post-processing template %s: %v", template.Name, err))
+			}
+		}
+		if a.options.Visualize {
+			if err := writeAnalysis(a.options.OutputDir, result); err != nil {
+				printWarning(fmt.Sprintf("writing the analysis of template %s: %v", template.Name, err))
+			}
+		}
+		if a.options.Observer != nil {
+			a.options.Observer.TemplateFinished(result)
+		}
+		if a.options.LowMemory {
+			// The full result went to the observer, keep only its counts so the
+			// graph, findings and details are garbage before the next compilation
+			a.results.add(result.summary())
+			runtime.GC()
+			continue
+		}
+		a.results.add(result)
 	}
 
 	return nil
 }
 
-func (a *Analyzer) analyzeTemplate(filePath string, template TemplateInfo) error {
-	tempFile, err := CreateTempCircomFile(filePath)
+// analyzeTemplate fills in result as it goes, so a failed analysis keeps what was learned before the error
+func (a *Analyzer) analyzeTemplate(ctx context.Context, filePath string, template TemplateInfo, result *TemplateResult) error {
+	if err := interrupted(ctx, "read"); err != nil {
+		return err
+	}
+	var work workSet
+	defer work.remove()
+	tempFile, err := createTemplateWorkFile(filePath, a.options.WorkDir, template.Name)
+	if err != nil {
+		return err
+	}
+	// The outputs are tracked before compiling, so that a compiler killed
+	// halfway leaves nothing behind either
+	constraintsFile, symFile, r1csFile := circomOutputs(tempFile)
+	work.track(tempFile, constraintsFile, symFile, r1csFile)
+	work.track(circomOutputDirs(tempFile)...)
+
+	if mainComponent, ok := a.mainComponent(filePath, template.Name); ok {
+		fmt.Fprintf(a.report, "Using custom main component for template %s: %s\n", template.Name, mainComponent)
+		if err := AddCustomMainComponent(tempFile, template.Name, mainComponent); err != nil {
+			return err
+		}
+		result.MainComponent = mainComponent
+	} else if config := a.options.Templates[template.Name]; len(config.Args) > 0 {
+		if err := config.checkArgs(template.Params, config.Args); err != nil {
+			return err
+		}
+		mainComponent := config.mainComponent(template.Name)
+		fmt.Fprintf(a.report, "Using configured main component for template %s: %s\n", template.Name, mainComponent)
+		if err := appendMainComponent(tempFile, mainComponent); err != nil {
+			return err
+		}
+		result.Args = config.Args
+		result.MainComponent = mainComponent
+	} else {
+		params := template.Params
+		if override, ok := a.options.ArgCounts[template.Name]; ok {
+			fmt.Fprintf(a.report, "Using argument count override for template %s: %d (detected %d)\n", template.Name, override, template.ArgCount)
+			params = make([]TemplateParam, override)
+		} else if arrays := template.arrayParams(); len(arrays) > 0 {
+			fmt.Fprintf(a.report, "Template %s takes array parameters %s, generating random array literals (use -main-component to override)\n",
+				template.Name, strings.Join(arrays, ", "))
+		}
+
+		args, err := a.options.Templates[template.Name].generateArgs(result.random, params)
+		if err != nil {
+			return err
+		}
+		result.Args = args
+		result.MainComponent = MainComponent(template.Name, args)
+		if err := AddMainComponent(tempFile, template.Name, args); err != nil {
+			return err
+		}
+	}
+
+	return a.compileTemplate(ctx, filePath, tempFile, template, result, &work)
+}
+
+// analyzeMainFile compiles a file with the main component it declares, as
+// it is, and analyzes the template the main component instantiates
+func (a *Analyzer) analyzeMainFile(ctx context.Context, filePath string, main fileMain, template TemplateInfo, result *TemplateResult) error {
+	if err := interrupted(ctx, "read"); err != nil {
+		return err
+	}
+	var work workSet
+	defer work.remove()
+	tempFile, err := createMainWorkFile(filePath, a.options.WorkDir, template.Name)
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tempFile)
+	constraintsFile, symFile, r1csFile := circomOutputs(tempFile)
+	work.track(tempFile, constraintsFile, symFile, r1csFile)
+	work.track(circomOutputDirs(tempFile)...)
 
-	args := GenerateRandomArgs(template.ArgCount)
-	if err := AddMainComponent(tempFile, template.Name, args); err != nil {
+	fmt.Fprintf(a.report, "Using the main component of %s: %s\n", filePath, main.component)
+	result.Args = main.args
+	result.MainComponent = main.component
+	return a.compileTemplate(ctx, filePath, tempFile, template, result, &work)
+}
+
+// compileTemplate compiles a temporary source with its main component and
+// analyzes the outputs, which it adds to work
+func (a *Analyzer) compileTemplate(ctx context.Context, filePath, tempFile string, template TemplateInfo, result *TemplateResult, work *workSet) error {
+	compileCtx := ctx
+	if a.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		compileCtx, cancel = context.WithTimeout(ctx, a.options.Timeout)
+		defer cancel()
+	}
+
+	compileStart := time.Now()
+	libraries, err := a.libraries(filePath)
+	if err != nil {
 		return err
 	}
+	artifacts, err := a.options.Compiler.Compile(compileCtx, tempFile, CompileOptions{Libraries: libraries, Optimization: a.options.Optimization, Args: a.options.CircomArgs})
+	compileTime := time.Since(compileStart)
+	if !a.options.Deterministic {
+		result.CompileSeconds = compileTime.Seconds()
+	}
+	if a.options.Observer != nil {
+		a.options.Observer.CompileFinished(template.Name, compileTime, err)
+	}
+	if err != nil {
+		// A cancelled run is reported as such, an expired -timeout as a compile error
+		if ctxErr := interrupted(ctx, "compile"); ctxErr != nil {
+			return ctxErr
+		}
+		var compileErr *CompileError
+		if errors.As(err, &compileErr) {
+			compileErr.Template = template.Name
+			compileErr.Args = result.Args
+			compileErr.Stderr, compileErr.InMain = mapWorkFileLocations(compileErr.Stderr, tempFile, filePath)
+			result.Command = compileErr.Command
+			a.showCommand(result)
+		}
+		return err
+	}
+	result.Command = artifacts.Command
+	a.showCommand(result)
+	work.track(artifacts.ConstraintsFile, artifacts.SymFile)
+	if artifacts.R1CSFile != "" {
+		work.track(artifacts.R1CSFile)
+	}
+
+	fmt.Fprintf(a.report, "\nAnalyzing template %s from %s\n", template.Name, filePath)
+	return a.analyzeArtifacts(ctx, template, artifacts, result)
+}
+
+// libraries returns the include paths of the compilation of a file: its own
+// directory with a WorkDir, as its temporary copy is elsewhere, then those of
+// the options
+func (a *Analyzer) libraries(filePath string) ([]string, error) {
+	if a.options.WorkDir == "" {
+		return a.options.Libraries, nil
+	}
+	dir, err := filepath.Abs(filepath.Dir(filePath))
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{dir}, a.options.Libraries...), nil
+}
 
-	constraintsFile, symFile, err := CompileCircuit(tempFile)
+// AnalyzeArtifacts analyzes the outputs of a compilation done elsewhere, for
+// a template whose source is not available. The checks relying on the
+// declared signals of the template are skipped. The artifacts are left in place.
+func (a *Analyzer) AnalyzeArtifacts(ctx context.Context, templateName string, artifacts Artifacts) TemplateResult {
+	result := TemplateResult{File: artifacts.ConstraintsFile, Template: templateName}
+	if a.options.Observer != nil {
+		a.options.Observer.TemplateStarted(result.File, templateName)
+	}
+	err := recovered(func() error { return a.analyzeArtifacts(ctx, TemplateInfo{Name: templateName}, artifacts, &result) })
 	if err != nil {
+		result.Error = err.Error()
+		result.err = err
+	}
+	if a.options.Observer != nil {
+		a.options.Observer.TemplateFinished(result)
+	}
+	return result
+}
+
+// analyzeArtifacts is the part of analyzeTemplate after the compilation
+func (a *Analyzer) analyzeArtifacts(ctx context.Context, template TemplateInfo, artifacts Artifacts, result *TemplateResult) error {
+	config := a.options.Templates[template.Name]
+	checks := config.Checks.apply(a.options)
+	output := result.OutputName()
+
+	parseOptions := circuitgraph.ParseOptions{Strict: a.options.Strict, Warn: printWarning}
+	constraints, err := circuitgraph.LoadFromJsonContext(ctx, artifacts.ConstraintsFile, parseOptions)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "parse"); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	symTable, err := circuitgraph.LoadSymTableContext(ctx, artifacts.SymFile, parseOptions)
+	if err != nil {
+		if ctxErr := interrupted(ctx, "parse"); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+	signals := symTable.Signals
+	if err := circuitgraph.CheckArtifacts(constraints, symTable, parseOptions); err != nil {
+		return err
+	}
+	var header *circuitgraph.R1CSHeader
+	if artifacts.R1CSFile != "" {
+		loaded, err := circuitgraph.LoadR1CSHeader(artifacts.R1CSFile)
+		if err != nil {
+			return err
+		}
+		header = &loaded
+		// Without its source, the roles of the signals of the main component
+		// are those circom declared
+		if template.Signals == nil {
+			template.Signals = header.Kinds(signals)
+		}
+	}
+	if a.options.SignalFamilies {
+		result.families = signalFamilies(signals)
+	}
+	if err := interrupted(ctx, "parse"); err != nil {
 		return err
 	}
-	defer os.Remove(constraintsFile)
-	defer os.Remove(symFile)
 
-	fmt.Printf("\nAnalyzing template %s from %s\n", template.Name, filePath)
+	// The exports read the constraints directly, they leave out the "1"
+	// signal the way the graph does
+	exported, exportedSignals := constraints, signals
+	if a.options.DropConstant && (a.options.Cooccurrence != "" || a.options.ComponentTotals) {
+		exported, exportedSignals = withoutConstant(constraints, signals)
+	}
+	if a.options.Cooccurrence != "" && !result.noFiles {
+		if err := a.exportCooccurrence(exported, exportedSignals, output); err != nil {
+			return err
+		}
+	}
+
+	if a.options.ComponentTotals {
+		result.ComponentTotals = circuitgraph.ConstraintsByComponent(exported, exportedSignals, a.options.GroupArrays)
+		fmt.Fprintln(a.report, "Constraints by component (total, own):")
+		printComponentTotals(a.report, result.ComponentTotals, 1)
+	}
 
-	constraints, err := LoadFromJson(constraintsFile)
+	var graphOptions []circuitgraph.GraphOption
+	if a.options.ArityCap > 0 {
+		graphOptions = append(graphOptions, circuitgraph.WithArityCap(a.options.ArityCap))
+	}
+	if a.options.Projection != "" {
+		graphOptions = append(graphOptions, circuitgraph.WithProjection(a.options.Projection))
+	}
+	if a.options.DropConstant {
+		graphOptions = append(graphOptions, circuitgraph.WithoutConstant())
+	}
+	graph, err := circuitgraph.BuildGraphContext(ctx, constraints, signals, graphOptions...)
 	if err != nil {
+		if ctxErr := interrupted(ctx, "graph construction"); ctxErr != nil {
+			return ctxErr
+		}
 		return err
 	}
-	signals, err := LoadFromSym(symFile)
+	// The size of the circuit is recorded whatever else is skipped, to watch it over time
+	result.Stats.Constraints, result.Stats.Signals = len(constraints), graph.SignalCount()
+	if a.options.HashOnly {
+		result.Hash = circuitgraph.TopologyHash(graph)
+		return nil
+	}
+	if a.options.DegreeHistogram != "" && !result.noFiles {
+		histogram := degreeHistogram(graph)
+		if err := writeDegreeHistogram(a.options.OutputDir, histogram, output, a.options.DegreeHistogram); err != nil {
+			return err
+		}
+		if a.options.Visualize {
+			if err := visualizeDegreeHistogram(a.options.OutputDir, histogram, output); err != nil {
+				return err
+			}
+		}
+	}
+	if a.options.SignalDegrees != "" && !result.noFiles {
+		if err := writeSignalDegrees(a.options.OutputDir, signalDegrees(graph), output, a.options.SignalDegrees); err != nil {
+			return err
+		}
+	}
+	if a.options.KeepSignals {
+		result.signals = graphSignals(graph)
+	}
+	analyzeOptions := circuitgraph.AnalyzeOptions{
+		Kinds:           template.Signals,
+		Lines:           template.Lines,
+		HubThreshold:    checks.HubThreshold,
+		PinnedThreshold: checks.PinnedThreshold,
+		HotSpots:        checks.HotSpots,
+		Quick:           checks.Quick,
+		Checks:          checks.Checks,
+		Coloring:        checks.Coloring,
+		Header:          header,
+	}
+	if checks.Patterns {
+		analyzeOptions.PatternMembers = minPatternMembers
+	}
+	if len(a.options.RemoveSignals) > 0 {
+		analyzeOptions.Remove = func(name string) bool {
+			for _, pattern := range a.options.RemoveSignals {
+				if matchGlob(pattern, name) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	analysis, err := circuitgraph.AnalyzeGraph(graph, constraints, signals, analyzeOptions)
 	if err != nil {
 		return err
 	}
+	result.Stats = analysis.Stats
+	result.Findings = analysis.Findings
+	result.Subgraphs = analysis.Subgraphs
+	result.Blocks = analysis.Blocks
+	result.Connectivity = analysis.Connectivity
+	result.Removal = analysis.Removal
+	result.HotSpots = analysis.HotSpots
+	result.Patterns = analysis.Patterns
+	result.Coloring = analysis.Coloring
+	result.Hubs = analysis.Hubs
+	result.Twins = analysis.Twins
+	result.Inputs = analysis.Inputs
+	result.Findings = append(result.Findings, config.Expect.check(result.Stats, len(result.Findings))...)
 
-	graph := buildGraph(constraints, signals)
-	if a.visualize {
-		visualizeGraph(graph, template.Name)
+	// The graphs are drawn once the findings are known, to mark their signals
+	if (a.options.Visualize || a.options.Report) && a.options.HideHubs > 0 {
+		if hidden := hiddenHubs(graph, a.options.HideHubs); len(hidden) > 0 {
+			fmt.Fprintf(a.report, "Hiding %d hub(s) of degree > %d from the visualization: %s\n", len(hidden), a.options.HideHubs, strings.Join(hidden, ", "))
+		}
+	}
+	if a.options.Visualize && !result.noFiles {
+		if err := a.visualizeGraph(graph, output, result.Findings); err != nil {
+			printWarning(err.Error())
+		}
+	}
+	if a.options.Report && graph.Nodes().Len() <= maxReportGraphNodes {
+		snippet := graphChart(graph, chartID("graph", result.File, template.Name), template.Name, a.options.HideHubs, result.Findings).RenderSnippet()
+		result.graph = &snippet
 	}
-	analyzeGraph(graph)
 
+	printStats(a.report, result.Stats)
+	if !a.options.DropConstant && result.Stats.LowConstantDegree() {
+		printWarning(fmt.Sprintf("the \"1\" signal of template %s only shares constraints with %.1f%% of the signals, signal keys might have been misparsed",
+			template.Name, 100*result.Stats.ConstantCoverage))
+	}
+	printAnalysis(a.report, analysis.Analysis, a.options.ListComponents)
+	if result.Removal != nil {
+		if len(result.Removal.Removed) == 0 {
+			printWarning(fmt.Sprintf("no signal of template %s matches -remove-signals %s", template.Name, strings.Join(a.options.RemoveSignals, ",")))
+		} else {
+			printRemoval(a.report, *result.Removal)
+		}
+	}
+	if checks.HotSpots > 0 {
+		printHotSpots(a.report, result.HotSpots)
+	}
+
+	if a.options.SignalsCSV && !result.noFiles {
+		columns := SignalColumns{Slots: !checks.Quick, Twins: !checks.Quick && checks.Checks.Has("twins"), Visibility: header != nil}
+		if err := writeSignalMetrics(a.options.OutputDir, signalMetrics(graph, template.Signals, analysis.Slots, analysis.Twins, analysis.Inputs), columns, output); err != nil {
+			return err
+		}
+	}
+
+	if checks.Patterns {
+		printPatterns(a.report, result.Patterns)
+	}
+	if result.Coloring != nil {
+		printColoring(a.report, *result.Coloring)
+	}
+	printSignalFindings(a.report, template.Name, result.Findings)
+	for _, hub := range result.Hubs {
+		fmt.Fprintf(a.report, "Hub: %s %s shares constraints with %d signals (%.1f%% of the graph).\n", hub.Role, hub.Signal, hub.Degree, hub.Coverage)
+	}
+	if a.options.PrefixStats > 0 {
+		flagged := make(map[string]bool)
+		for _, finding := range result.Findings {
+			if finding.Signal != "" {
+				flagged[finding.Signal] = true
+			}
+		}
+		result.Prefixes = circuitgraph.PrefixStatistics(graph, constraints, signals, flagged, a.options.Verbose)
+		fmt.Fprintln(a.report, "Signals by name prefix (signals, average degree, flagged, share of constraints):")
+		printPrefixStats(a.report, result.Prefixes, 1, a.options.PrefixStats)
+	}
+	if a.options.GroupFindings {
+		printFindingGroups(a.report, result.Findings)
+	}
+
+	return nil
+}
+
+// printSignalFindings reports the findings of the checks on declared and
+// constrained signals, in the order AnalyzeGraph returns them
+func printSignalFindings(w io.Writer, templateName string, findings []circuitgraph.Finding) {
+	for _, finding := range findings {
+		switch finding.Category {
+		case circuitgraph.CategoryNoOutputs:
+			fmt.Fprintf(w, "Template %s declares no output signals. It might only assert constraints, or compute nothing visible to its users.\n", templateName)
+		case circuitgraph.CategoryFloatingInput, circuitgraph.CategoryIsolatedInput, circuitgraph.CategoryInputIsland:
+			fmt.Fprintf(w, "Input %s: %s.\n", finding.Signal, finding.Message)
+		case circuitgraph.CategoryNarrowSlots, circuitgraph.CategoryPinnedSignal, circuitgraph.CategoryTwinSignals:
+			fmt.Fprintf(w, "Signal %s: %s.\n", finding.Signal, finding.Message)
+		case circuitgraph.CategoryLinearOutput:
+			fmt.Fprintf(w, "Output %s: %s.\n", finding.Signal, finding.Message)
+		case circuitgraph.CategoryVacuousConstraint, CategoryUnexpectedMetric:
+			fmt.Fprintf(w, "Template %s: %s.\n", templateName, finding.Message)
+		}
+	}
+}
+
+// withoutConstant returns copies of the constraints and signals without the
+// "1" signal, ID 0
+func withoutConstant(constraints circuitgraph.Constraints, signals map[int64]string) (circuitgraph.Constraints, map[int64]string) {
+	stripped := make(circuitgraph.Constraints, len(constraints))
+	for i, constraint := range constraints {
+		for j, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				if signal != 0 {
+					stripped[i][j] = append(stripped[i][j], signal)
+				}
+			}
+		}
+	}
+	named := make(map[int64]string, len(signals))
+	for id, name := range signals {
+		if id != 0 {
+			named[id] = name
+		}
+	}
+	return stripped, named
+}
+
+// exportCooccurrence writes the co-occurrence matrix of the selected signals,
+// skipping templates where the selection is empty or too large
+func (a *Analyzer) exportCooccurrence(constraints circuitgraph.Constraints, signals map[int64]string, templateName string) error {
+	matrix, err := cooccurrence(constraints, signals, a.options.Cooccurrence)
+	if err != nil {
+		printWarning(fmt.Sprintf("skipping the co-occurrence matrix of template %s: %v", templateName, err))
+		return nil
+	}
+	if len(matrix.Signals) == 0 {
+		return nil
+	}
+	if err := writeCooccurrence(a.options.OutputDir, matrix, templateName); err != nil {
+		return err
+	}
+	if a.options.Visualize {
+		return visualizeCooccurrence(a.options.OutputDir, matrix, templateName)
+	}
 	return nil
 }
 
-func (a *Analyzer) Wait() {
+// mainComponent returns the main component given for the template of the
+// file, looked up by file and name first
+func (a *Analyzer) mainComponent(filePath, templateName string) (string, bool) {
+	if mainComponent, ok := a.options.MainComponents[qualifiedName(filePath, templateName)]; ok {
+		return mainComponent, true
+	}
+	mainComponent, ok := a.options.MainComponents[templateName]
+	return mainComponent, ok
+}
+
+func (a *Analyzer) showCommand(result *TemplateResult) {
+	if a.options.ShowCommands {
+		fmt.Printf("Compiling %s with %s\nusing %s\n", result.Template, result.MainComponent, result.Command)
+	}
+}
+
+// Wait blocks until all queued files are analyzed and returns the results of
+// this run. The analyzer is then empty and ready for the next run.
+func (a *Analyzer) Wait() Results {
+	a.mu.Lock()
+	a.waiting++
+	a.mu.Unlock()
+
 	a.wg.Wait()
+	results := a.results.drain()
+
+	a.mu.Lock()
+	a.waiting--
+	a.mu.Unlock()
+	return results
 }
 
 type TemplateInfo struct {
 	Name     string
 	ArgCount int
+	Params   []TemplateParam
+	Signals  map[string]circuitgraph.SignalKind // Signals declared in the body by name, without array dimensions
+	Lines    map[string]int                     // Line of the source file declaring each signal
+	Columns  map[string]int                     // Column of the name of each signal on its line, from 1
+	Line     int                                // Line of the source file the template keyword is on, from 1
+	Column   int                                // Column of the template keyword on its line, from 1
+}
+
+// TemplateParam is a template parameter, with the dimensions of array parameters like arr[k]
+type TemplateParam struct {
+	Name string
+	Dims []string // Literal sizes or names of other parameters, empty for scalars
+}
+
+func (t TemplateInfo) arrayParams() []string {
+	var arrays []string
+	for _, param := range t.Params {
+		if len(param.Dims) > 0 {
+			arrays = append(arrays, param.Name+"["+strings.Join(param.Dims, "][")+"]")
+		}
+	}
+	return arrays
+}
+
+var paramRegexp = regexp.MustCompile(`^(\w+)\s*((?:\[[^\]]*\]\s*)*)$`)
+
+// parseParams splits a template signature into its parameters
+func parseParams(args string) []TemplateParam {
+	var params []TemplateParam
+	if strings.TrimSpace(args) == "" {
+		return params
+	}
+	for _, arg := range strings.Split(args, ",") {
+		arg = strings.TrimSpace(arg)
+		param := TemplateParam{Name: arg}
+		if match := paramRegexp.FindStringSubmatch(arg); match != nil {
+			param.Name = match[1]
+			for _, dim := range strings.Split(match[2], "[")[1:] {
+				param.Dims = append(param.Dims, strings.TrimSpace(strings.TrimRight(strings.TrimSpace(dim), "]")))
+			}
+		}
+		params = append(params, param)
+	}
+	return params
 }
 
-func extractTemplates(content string) []TemplateInfo {
-	// Update the regular expression to capture template names and arguments
-	re := regexp.MustCompile(`(?m)^\s*template\s+(\w+)\(([^)]*)\)`)
-	matches := re.FindAllStringSubmatch(content, -1)
+var (
+	templateRegexp      = regexp.MustCompile(`^\s*template\s+(\w+)\(([^)]*)\)`)
+	templateStartRegexp = regexp.MustCompile(`^\s*template\s+\w+\(`)
+	functionStartRegexp = regexp.MustCompile(`^\s*function\s+\w+\(`)
+	// signal input {tag} a[2], b; declares a and b as inputs
+	signalRegexp = regexp.MustCompile(`\bsignal\s+(?:(input|output)\b\s*)?(?:\{[^}]*\}\s*)?(\w+(?:\s*\[[^\]]*\])*(?:\s*,\s*\w+(?:\s*\[[^\]]*\])*)*)`)
+)
+
+// extractTemplates scans the source line by line, so large files are never held in memory as a whole
+func extractTemplates(r io.Reader) ([]TemplateInfo, error) {
+	var templates []TemplateInfo
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Machine-emitted circuits can have very long lines
 
-	templates := make([]TemplateInfo, len(matches))
-	for i, match := range matches {
+	// A signature spanning several lines is collected until its closing parenthesis
+	var pending string
+	var current *TemplateInfo // Template whose body is being read
+	lineNumber, declarationLine := 0, 0
+	for scanner.Scan() {
+		line := scanner.Text()
+		lineNumber++
+		declarationLine = lineNumber
+		if pending != "" {
+			declarationLine = lineNumber - strings.Count(pending, "\n") - 1
+			pending += "\n" + line
+			if !strings.Contains(line, ")") {
+				continue
+			}
+			line, pending = pending, ""
+		} else if templateStartRegexp.MatchString(line) && !strings.Contains(line, ")") {
+			pending = line
+			continue
+		}
+
+		match := templateRegexp.FindStringSubmatch(line)
+		if match == nil {
+			if functionStartRegexp.MatchString(line) {
+				current = nil
+			} else if current != nil {
+				collectSignals(line, 0, lineNumber, current)
+			}
+			continue
+		}
 		templateName := match[1]
 		args := match[2]
 
 		// Count the arguments by splitting on commas, and handling empty arguments
-		argCount := 0
-		if strings.TrimSpace(args) != "" {
-			argCount = len(strings.Split(args, ","))
-		}
+		params := parseParams(args)
 
-		templates[i] = TemplateInfo{
+		templates = append(templates, TemplateInfo{
 			Name:     templateName,
-			ArgCount: argCount,
+			ArgCount: len(params),
+			Params:   params,
+			Signals:  make(map[string]circuitgraph.SignalKind),
+			Lines:    make(map[string]int),
+			Columns:  make(map[string]int),
+			Line:     declarationLine,
+			Column:   strings.Index(line, "template") + 1,
+		})
+		current = &templates[len(templates)-1]
+		// The body may start on the signature line
+		if end := strings.Index(line, "{"); end >= 0 {
+			offset := end - strings.LastIndex(line[:end], "\n") - 1 // On the last line of a multi-line signature
+			collectSignals(line[end:], offset, lineNumber, current)
 		}
 	}
 
-	return templates
+	return templates, scanner.Err()
 }
 
-type NamedNode struct {
-	IDVal int64  // Node ID
-	Name  string // Node name or title
+// collectSignals records the signals declared on a line of a template body,
+// which starts at the byte offset of the source line
+func collectSignals(line string, offset, lineNumber int, template *TemplateInfo) {
+	if comment := strings.Index(line, "//"); comment >= 0 {
+		line = line[:comment]
+	}
+	for _, match := range signalRegexp.FindAllStringSubmatchIndex(line, -1) {
+		kind := circuitgraph.KindIntermediate
+		if match[2] >= 0 {
+			kind = circuitgraph.SignalKind(line[match[2]:match[3]])
+		}
+		start := match[4]
+		for _, name := range strings.Split(line[match[4]:match[5]], ",") {
+			column := offset + start + len(name) - len(strings.TrimLeft(name, " \t")) + 1
+			start += len(name) + 1
+			if bracket := strings.Index(name, "["); bracket >= 0 {
+				name = name[:bracket]
+			}
+			name = strings.TrimSpace(name)
+			template.Signals[name] = kind
+			template.Lines[name] = lineNumber
+			template.Columns[name] = column
+		}
+	}
 }
 
-// ID satisfies the gonum Node interface
-func (n NamedNode) ID() int64 {
-	return n.IDVal
+// visualizeGraph renders the graph to <template>_circuit_graph.html, with the
+// signals of the findings colored by their most severe finding. Graphs
+// above the size limits of the options are written as DOT instead, and a
+// render taking longer than RenderTimeout is abandoned so it cannot stall
+// the worker. The abandoned render finishes in the background and is discarded.
+func (a *Analyzer) visualizeGraph(g *circuitgraph.CircuitGraph, templateName string, findings []circuitgraph.Finding) error {
+	nodes, edges := g.Nodes().Len(), g.Edges().Len()
+	if (a.options.MaxVisualizeNodes > 0 && nodes > a.options.MaxVisualizeNodes) || (a.options.MaxVisualizeEdges > 0 && edges > a.options.MaxVisualizeEdges) {
+		fileName := outputFile(a.options.OutputDir, fmt.Sprintf("%s_circuit_graph.dot", templateName))
+		fmt.Fprintf(a.report, "The graph of template %s has %d nodes and %d edges, too large to render in a browser, writing %s instead\n",
+			templateName, nodes, edges, fileName)
+		return writeDOT(g, templateName, fileName)
+	}
+
+	rendered := make(chan []byte, 1)
+	go func() {
+		var buf bytes.Buffer
+		graphChart(g, chartID("graph", templateName), templateName, a.options.HideHubs, findings).Render(&buf)
+		rendered <- buf.Bytes()
+	}()
+	var timeout <-chan time.Time
+	if a.options.RenderTimeout > 0 {
+		timer := time.NewTimer(a.options.RenderTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case html := <-rendered:
+		return os.WriteFile(outputFile(a.options.OutputDir, fmt.Sprintf("%s_circuit_graph.html", templateName)), html, 0644)
+	case <-timeout:
+		return fmt.Errorf("rendering the graph of template %s took longer than %s, skipped", templateName, a.options.RenderTimeout)
+	}
 }
 
-func buildGraph(data Constraints, signals []string) *simple.UndirectedGraph {
-	graph := simple.NewUndirectedGraph()
+// writeDOT writes the graph in the DOT language, which Graphviz lays out far
+// faster than a browser does for large graphs
+func writeDOT(g *circuitgraph.CircuitGraph, templateName, fileName string) error {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-	for _, constraint := range data {
-		// Collect all unique signals in this constraint
-		signalSet := make(map[int64]struct{})
-		for _, linearExpression := range constraint {
-			for _, signal := range linearExpression {
-				signalSet[signal] = struct{}{}
-			}
-		}
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "graph %q {\n", templateName)
+	for _, node := range g.SortedNodes() {
+		fmt.Fprintf(w, "  %d [label=%q];\n", node.ID(), node.Name)
+	}
+	for _, edge := range g.SortedEdges() {
+		fmt.Fprintf(w, "  %d -- %d;\n", edge[0].ID(), edge[1].ID())
+	}
+	fmt.Fprintln(w, "}")
+	return w.Flush()
+}
 
-		// Create or get nodes for all signals in this constraint
-		nodes := make([]*NamedNode, 0, len(signalSet))
-		for signal := range signalSet {
-			node, ok := graph.Node(signal).(*NamedNode)
-			if !ok {
-				// Add node if it doesn't exist
-				node = &NamedNode{IDVal: signal, Name: signals[signal]}
-				graph.AddNode(node)
-			}
-			nodes = append(nodes, node)
-		}
+// maxListedHubs limits the hidden hubs named in the chart subtitle
+const maxListedHubs = 10
 
-		// Connect all nodes with each other
-		for i := 0; i < len(nodes); i++ {
-			for j := i + 1; j < len(nodes); j++ {
-				graph.SetEdge(simple.Edge{F: nodes[i], T: nodes[j]})
-			}
+// hiddenHubs returns the signals with a degree above hideHubs, sorted by name,
+// none if hideHubs is 0
+func hiddenHubs(g *circuitgraph.CircuitGraph, hideHubs int) []string {
+	if hideHubs <= 0 {
+		return nil
+	}
+	var hidden []string
+	nodes := g.Nodes()
+	for nodes.Next() {
+		n := nodes.Node().(*circuitgraph.NamedNode)
+		if !n.Synthetic() && g.SignalDegree(n.ID()) > hideHubs {
+			hidden = append(hidden, n.Name)
 		}
 	}
+	sort.Strings(hidden)
+	return hidden
+}
 
-	return graph
+// chartID derives the ID of a chart from what it shows. go-echarts otherwise
+// draws a random one, which would make every rendering of a chart differ.
+func chartID(parts ...string) string {
+	hash := fnv.New64a()
+	for _, part := range parts {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return fmt.Sprintf("chart%016x", hash.Sum64())
 }
 
-func visualizeGraph(dataGraph *simple.UndirectedGraph, templateName string) {
+// graphChart draws the constraint graph for visualizeGraph and the combined
+// report under the given chart ID. Signals with a degree above hideHubs are
+// left out of the drawing and named in the subtitle, 0 draws all of them.
+// Signals with findings take the color of the most severe one, explained by
+// the legend, and list their findings in a tooltip. Nodes and links are
+// ordered by ID, so the same graph renders the same page.
+func graphChart(g *circuitgraph.CircuitGraph, id, templateName string, hideHubs int, findings []circuitgraph.Finding) *charts.Graph {
+	hidden := hiddenHubs(g, hideHubs)
+	title := opts.Title{Title: "Circuit Constraint Graph: " + templateName}
+	if len(hidden) > 0 {
+		listed := hidden
+		if len(listed) > maxListedHubs {
+			listed = listed[:maxListedHubs]
+		}
+		title.Subtitle = fmt.Sprintf("%d hub(s) of degree > %d hidden: %s", len(hidden), hideHubs, strings.Join(listed, ", "))
+		if len(hidden) > maxListedHubs {
+			title.Subtitle += fmt.Sprintf(" and %d more", len(hidden)-maxListedHubs)
+		}
+	}
+	isHidden := make(map[string]bool, len(hidden))
+	for _, name := range hidden {
+		isHidden[name] = true
+	}
+
+	// The most severe finding of every signal, and all of them for the tooltip
+	worst := make(map[string]circuitgraph.Severity)
+	notes := make(map[string][]string)
+	for _, finding := range bySeverity(findings) {
+		if finding.Signal == "" {
+			continue
+		}
+		if _, ok := worst[finding.Signal]; !ok {
+			worst[finding.Signal] = finding.Severity
+		}
+		notes[finding.Signal] = append(notes[finding.Signal], fmt.Sprintf("[%s] %s", finding.Severity, finding.Category))
+	}
+	var legend []string
+	var categories []*opts.GraphCategory
+	category := make(map[circuitgraph.Severity]int)
+	for _, severity := range graphSeverities {
+		category[severity] = len(categories)
+		name := string(severity)
+		if severity == "" {
+			name = "no finding"
+		}
+		legend = append(legend, name)
+		categories = append(categories, &opts.GraphCategory{Name: name, ItemStyle: &opts.ItemStyle{Color: graphColor(severity)}})
+	}
+
 	viewGraph := charts.NewGraph()
-	viewGraph.SetGlobalOptions(charts.WithTitleOpts(opts.Title{Title: "Circuit Constraint Graph: " + templateName}))
+	viewGraph.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{ChartID: id}),
+		charts.WithTitleOpts(title),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true), Data: legend, Bottom: "0"}),
+		charts.WithTooltipOpts(opts.Tooltip{Show: opts.Bool(true)}),
+	)
 
 	nodes := make([]opts.GraphNode, 0)
 	links := make([]opts.GraphLink, 0)
 
-	nodesIterator := dataGraph.Nodes()
-	for nodesIterator.Next() { // Loop through the nodes
-		n := nodesIterator.Node().(*NamedNode) // Get the current node
-		nodes = append(nodes, opts.GraphNode{
-			Name: fmt.Sprintf(n.Name), // Format the node name
-		})
+	for _, n := range g.SortedNodes() {
+		if isHidden[n.Name] {
+			continue
+		}
+		node := opts.GraphNode{
+			Name:     fmt.Sprintf(n.Name), // Format the node name
+			Category: category[worst[n.Name]],
+		}
+		if len(notes[n.Name]) > 0 {
+			node.Tooltip = &opts.Tooltip{Formatter: types.FuncStr(n.Name + "<br/>" + strings.Join(notes[n.Name], "<br/>"))}
+		}
+		nodes = append(nodes, node)
 	}
 
-	edgesIterator := dataGraph.Edges()
-	for edgesIterator.Next() {
-		e := edgesIterator.Edge() // Type assertion to get the edge details
-		sourceNode := e.From()
-		targetNode := e.To()
-
-		// Convert source and target nodes to *NamedNode to access the Name attribute
-		sourceNamedNode, _ := sourceNode.(*NamedNode)
-		targetNamedNode, _ := targetNode.(*NamedNode)
+	for _, e := range g.SortedEdges() {
+		if isHidden[e[0].Name] || isHidden[e[1].Name] {
+			continue
+		}
 
 		links = append(links, opts.GraphLink{
-			Source: fmt.Sprintf(sourceNamedNode.Name),
-			Target: fmt.Sprintf(targetNamedNode.Name),
+			Source: fmt.Sprintf(e[0].Name),
+			Target: fmt.Sprintf(e[1].Name),
 		})
 	}
 
-	viewGraph.AddSeries("graph", nodes, links)
-	fileName := fmt.Sprintf("%s_circuit_graph.html", templateName)
-	f, _ := os.Create(fileName)
-	viewGraph.Render(f)
+	viewGraph.AddSeries("graph", nodes, links, charts.WithGraphChartOpts(opts.GraphChart{Categories: categories}))
+	return viewGraph
+}
+
+// graphSeverities are the categories of the graph legend, most severe first,
+// the empty severity standing for the signals without findings
+var graphSeverities = []circuitgraph.Severity{
+	circuitgraph.SeverityCritical,
+	circuitgraph.SeverityHigh,
+	circuitgraph.SeverityMedium,
+	circuitgraph.SeverityLow,
+	circuitgraph.SeverityInfo,
+	"",
+}
+
+// graphColor is the color of the signals whose most severe finding has the
+// severity, the default node color of echarts for those without findings
+func graphColor(severity circuitgraph.Severity) string {
+	if color, ok := severityColors[severity]; ok {
+		return color
+	}
+	return "#5470c6"
+}
+
+// sanitizeFileName replaces characters that are invalid in file names on NTFS
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
 }
 
-func analyzeGraph(g *simple.UndirectedGraph) {
-	fmt.Printf("There are %d nodes (signals) in this graph.\n", g.Nodes().Len())
+// outputFile returns the path of a file written per template in dir
+func outputFile(dir, name string) string {
+	return filepath.Join(dir, sanitizeFileName(name))
+}
 
-	// Check for signals with one or no connections
-	underconstrained := findUnderconstrainedSignals(g)
-	if len(underconstrained) > 0 {
-		fmt.Println("Potentially underconstrained signals (one or no connections):", underconstrained)
+func printWarning(msg string) {
+	fmt.Println("Warning:", msg)
+}
+
+func printAnalysis(w io.Writer, analysis circuitgraph.Analysis, listComponents int) {
+	if len(analysis.Underconstrained) > 0 {
+		fmt.Fprintln(w, "Potentially underconstrained signals (one or no connections):", analysis.Underconstrained)
 	} else {
-		fmt.Println("No potentially underconstrained signals found.")
-	}
-
-	// Create a copy of the graph for subgraph analysis
-	gc := simple.NewUndirectedGraph()
-	graph.Copy(gc, g)
-
-	// Remove node 0 from the copy, which is the "1" signal
-	gc.RemoveNode(int64(0))
-
-	// Check for independent subgraphs in the modified copy
-	subgraphs := topo.ConnectedComponents(gc)
-	if len(subgraphs) > 1 {
-		fmt.Printf("Found %d independent subgraphs after removing \"1\" signal. The circuit might be underconstrained or should be broken into separate templates.\n", len(subgraphs))
-		for i, subgraph := range subgraphs {
-			fmt.Printf("Subgraph %d:\n", i+1)
-			for _, node := range subgraph {
-				nodeID := node.ID()
-				// Use the original graph to get the node name
-				if namedNode, ok := g.Node(nodeID).(*NamedNode); ok {
-					fmt.Printf("  - %s\n", namedNode.Name)
-				} else {
-					fmt.Printf("  - Node ID: %d\n", nodeID)
-				}
+		fmt.Fprintln(w, "No potentially underconstrained signals found.")
+	}
+
+	if len(analysis.Subgraphs) > 1 {
+		fmt.Fprintf(w, "Found %d independent subgraphs after removing \"1\" signal. The circuit might be underconstrained or should be broken into separate templates.\n", len(analysis.Subgraphs))
+		printSubgraphs(w, analysis.Subgraphs, listComponents)
+	} else {
+		fmt.Fprintln(w, "The graph remains fully connected after removing node 0.")
+	}
+
+	printBlocks(w, analysis.Blocks)
+
+	if analysis.Connectivity.ComponentSize > 1 {
+		fmt.Fprintf(w, "Algebraic connectivity of the largest component (%d signals): %.4g\n",
+			analysis.Connectivity.ComponentSize, analysis.Connectivity.Fiedler)
+		if analysis.Connectivity.Fiedler < circuitgraph.BottleneckThreshold {
+			fmt.Fprintf(w, "The largest component hinges on a bottleneck, separating %d signals from the rest.\n", len(analysis.Connectivity.Cut))
+		}
+		if split := analysis.Connectivity.Split; split != nil {
+			fmt.Fprintf(w, "The largest component would split in two if %d edge(s) were removed, region A (%s) and region B (%s):\n", len(split.Edges), split.Regions[0], split.Regions[1])
+			for _, edge := range split.Edges {
+				fmt.Fprintf(w, "  - %s -- %s, constraint(s) %v\n", edge.From, edge.To, edge.Constraints)
 			}
 		}
-	} else {
-		fmt.Println("The graph remains fully connected after removing node 0.")
 	}
 }
 
-func findUnderconstrainedSignals(graph *simple.UndirectedGraph) []string {
-	underconstrained := []string{}
-	nodes := graph.Nodes()
-	for nodes.Next() {
-		n := nodes.Node().(*NamedNode)
-		if graph.From(n.ID()).Len() <= 1 {
-			underconstrained = append(underconstrained, n.Name)
+// maxSubgraphPrefixes is the number of prefixes summarizing a subgraph too large to list
+const maxSubgraphPrefixes = 3
+
+// printSubgraphs lists the signals of the subgraphs of at most listComponents
+// signals, smallest first as they are the most actionable, and summarizes the
+// larger ones by size and most common prefixes
+func printSubgraphs(w io.Writer, subgraphs [][]string, listComponents int) {
+	ordered := make([][]string, len(subgraphs))
+	copy(ordered, subgraphs)
+	sort.SliceStable(ordered, func(i, j int) bool { return len(ordered[i]) < len(ordered[j]) })
+	for i, subgraph := range ordered {
+		if len(subgraph) > listComponents {
+			fmt.Fprintf(w, "Subgraph %d: %d signals", i+1, len(subgraph))
+			if prefixes := circuitgraph.CommonPrefixes(subgraph, maxSubgraphPrefixes); len(prefixes) > 0 {
+				fmt.Fprintf(w, ", mostly in %s", strings.Join(prefixes, ", "))
+			}
+			fmt.Fprintln(w)
+			continue
+		}
+		fmt.Fprintf(w, "Subgraph %d:\n", i+1)
+		for _, name := range subgraph {
+			fmt.Fprintf(w, "  - %s\n", name)
+		}
+	}
+}
+
+// maxPrintedComponents limits the children printed per component, the JSON results contain all of them
+const maxPrintedComponents = 10
+
+func printComponentTotals(w io.Writer, component *circuitgraph.ComponentConstraints, depth int) {
+	note := ""
+	if component.Suspicious {
+		note = ", far fewer than its sibling instances"
+	}
+	fmt.Fprintf(w, "%s%s: %d, %d%s\n", strings.Repeat("  ", depth), component.Name, component.Total, component.Own, note)
+	for i, child := range component.Children {
+		if i == maxPrintedComponents {
+			fmt.Fprintf(w, "%s  ... and %d more\n", strings.Repeat("  ", depth), len(component.Children)-maxPrintedComponents)
+			break
+		}
+		printComponentTotals(w, child, depth+1)
+	}
+}
+
+func printPrefixStats(w io.Writer, prefix *circuitgraph.PrefixStats, depth, maxDepth int) {
+	fmt.Fprintf(w, "%s- %s: %d, %.2f, %d, %.1f%%\n", strings.Repeat("  ", depth), prefix.Prefix,
+		prefix.Signals, prefix.AverageDegree, prefix.Flagged, prefix.ConstraintShare)
+	for _, index := range prefix.Indices {
+		fmt.Fprintf(w, "%s  %s: %d, %.2f, %d, %.1f%%\n", strings.Repeat("  ", depth), index.Prefix,
+			index.Signals, index.AverageDegree, index.Flagged, index.ConstraintShare)
+	}
+	if depth >= maxDepth {
+		return
+	}
+	for _, child := range prefix.Children {
+		printPrefixStats(w, child, depth+1, maxDepth)
+	}
+}
+
+// maxListedSizes limits the component sizes listed by printRemoval
+const maxListedSizes = 10
+
+func printRemoval(w io.Writer, removal circuitgraph.Removal) {
+	fmt.Fprintf(w, "What if %d signal(s) were removed as well (%s):\n", len(removal.Removed), strings.Join(removal.Removed, ", "))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\t\twithout \"1\"\twithout \"1\" and removed")
+	fmt.Fprintf(tw, "\tcomponents\t%d\t%d\n", len(removal.Before), len(removal.After))
+	fmt.Fprintf(tw, "\tsignals per component\t%s\t%s\n", listSizes(removal.Before), listSizes(removal.After))
+	tw.Flush()
+	if len(removal.After) > len(removal.Before) {
+		fmt.Fprintln(w, "The circuit falls apart without the removed signals.")
+	}
+}
+
+func listSizes(sizes []int) string {
+	listed := make([]string, 0, maxListedSizes+1)
+	for i, size := range sizes {
+		if i == maxListedSizes {
+			listed = append(listed, "...")
+			break
+		}
+		listed = append(listed, strconv.Itoa(size))
+	}
+	return strings.Join(listed, " ")
+}
+
+// minPatternMembers is the smallest signal family checked for repeated structures
+const minPatternMembers = 3
+
+func printPatterns(w io.Writer, patterns []circuitgraph.Pattern) {
+	if len(patterns) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Repeated structures of signal families, loop bodies repeat one structure per iteration:")
+	for _, pattern := range patterns {
+		structures := make([]string, len(pattern.Structures))
+		for i, structure := range pattern.Structures {
+			structures[i] = fmt.Sprintf("%d like %s", structure.Count, structure.Example)
+		}
+		fmt.Fprintf(w, "  - %s: %d signals, %s\n", pattern.Family, pattern.Signals, strings.Join(structures, ", "))
+	}
+}
+
+func printColoring(w io.Writer, coloring circuitgraph.Coloring) {
+	if coloring.Signals == 0 {
+		return
+	}
+	fmt.Fprintf(w, "A greedy coloring splits the signals into %d groups sharing no constraint, the largest of %d signals (%.1f%%).\n",
+		coloring.Colors, coloring.LargestClass, 100*float64(coloring.LargestClass)/float64(coloring.Signals))
+}
+
+func printHotSpots(w io.Writer, hotSpots []circuitgraph.HotSpot) {
+	if len(hotSpots) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Edges with the highest betweenness, the constraints behind them carry the most shortest paths:")
+	for _, hotSpot := range hotSpots {
+		approximate := ""
+		if hotSpot.Approximate {
+			approximate = "~"
+		}
+		fmt.Fprintf(w, "  - %s -- %s: %s%.1f, constraints %v\n", hotSpot.From, hotSpot.To, approximate, hotSpot.Betweenness, hotSpot.Constraints)
+	}
+}
+
+// maxPrintedBlocks limits the block listing, the JSON results contain all of them
+const maxPrintedBlocks = 10
+
+func printBlocks(w io.Writer, blocks []circuitgraph.Block) {
+	if len(blocks) <= 1 {
+		return
+	}
+	fmt.Fprintf(w, "Found %d biconnected blocks after removing node 0, small blocks hanging off a separator are often sub-gadgets sharing a single signal:\n", len(blocks))
+	for i, block := range blocks {
+		if i == maxPrintedBlocks {
+			fmt.Fprintf(w, "  ... and %d more\n", len(blocks)-maxPrintedBlocks)
+			break
+		}
+		fmt.Fprintf(w, "  - %d signals", len(block.Signals))
+		if len(block.Prefixes) > 0 {
+			fmt.Fprintf(w, " in %s", strings.Join(block.Prefixes, ", "))
+		}
+		if len(block.Separators) > 0 {
+			fmt.Fprintf(w, ", separated by %s", strings.Join(block.Separators, ", "))
 		}
+		fmt.Fprintln(w)
 	}
-	return underconstrained
 }
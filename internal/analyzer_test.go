@@ -0,0 +1,727 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+	"github.com/Artifex1/circuit-graph-analysis/internal/compilertest"
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// newFixtureCompiler returns a FakeCompiler serving the square fixture
+func newFixtureCompiler(t *testing.T) *compilertest.FakeCompiler {
+	t.Helper()
+	fake, err := compilertest.FromFiles(filepath.Join("testdata", "square_constraints.json"), filepath.Join("testdata", "square.sym"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake
+}
+
+// newFixtureAnalyzer returns an analyzer compiling with a FakeCompiler that
+// serves the square fixture, unless options set another compiler, and writing
+// its work files to a directory of its own
+func newFixtureAnalyzer(t *testing.T, options internal.Options) (*internal.Analyzer, *compilertest.FakeCompiler, string) {
+	t.Helper()
+	fake := newFixtureCompiler(t)
+	workDir := t.TempDir()
+	if options.Compiler == nil {
+		options.Compiler = fake
+	}
+	options.WorkDir = workDir
+	options.OutputDir = t.TempDir()
+	options.Quiet = true
+	if options.Parallelism == 0 {
+		options.Parallelism = 1
+	}
+	return internal.NewAnalyzer(options), fake, workDir
+}
+
+// analyzeFiles runs one analysis of the given files and returns its results
+func analyzeFiles(t *testing.T, analyzer *internal.Analyzer, files ...string) internal.Results {
+	t.Helper()
+	for _, file := range files {
+		if err := analyzer.AnalyzeFile(file); err != nil {
+			t.Fatalf("AnalyzeFile(%s): %v", file, err)
+		}
+	}
+	return analyzer.Wait()
+}
+
+// assertEmptyDir fails if the analysis left anything in dir
+func assertEmptyDir(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("left behind %s", entry.Name())
+	}
+}
+
+func TestAnalyzeFile(t *testing.T) {
+	analyzer, fake, workDir := newFixtureAnalyzer(t, internal.Options{})
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+
+	if len(results.Templates) != 1 {
+		t.Fatalf("got %d results, want 1", len(results.Templates))
+	}
+	result := results.Templates[0]
+	if result.Error != "" {
+		t.Fatalf("analysis failed: %s", result.Error)
+	}
+	if result.Template != "Square" {
+		t.Errorf("template = %q, want Square", result.Template)
+	}
+	if result.MainComponent != "component main = Square();" {
+		t.Errorf("main component = %q", result.MainComponent)
+	}
+	if result.Stats.Constraints != 1 || result.Stats.Signals != 2 {
+		t.Errorf("stats = %d constraints and %d signals, want 1 and 2", result.Stats.Constraints, result.Stats.Signals)
+	}
+	if sources := fake.Sources(); len(sources) != 1 || filepath.Dir(sources[0]) != workDir {
+		t.Errorf("compiled %v, want one work file in %s", sources, workDir)
+	}
+	assertEmptyDir(t, workDir)
+}
+
+func TestAnalyzeFileFindings(t *testing.T) {
+	analyzer, _, _ := newFixtureAnalyzer(t, internal.Options{})
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+
+	// x * x = y leaves both signals with a single neighbor
+	underconstrained := make(map[string]bool)
+	for _, finding := range results.Templates[0].Findings {
+		if finding.Category == circuitgraph.CategoryUnderconstrained {
+			underconstrained[finding.Signal] = true
+		}
+	}
+	for _, signal := range []string{"main.x", "main.y"} {
+		if !underconstrained[signal] {
+			t.Errorf("no %s finding for %s in %+v", circuitgraph.CategoryUnderconstrained, signal, results.Templates[0].Findings)
+		}
+	}
+}
+
+func TestAnalyzeFileCompileError(t *testing.T) {
+	analyzer, fake, workDir := newFixtureAnalyzer(t, internal.Options{})
+	fake.Err = errors.New("circom crashed")
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+
+	if len(results.Templates) != 1 {
+		t.Fatalf("got %d results, want 1", len(results.Templates))
+	}
+	if err := results.Templates[0].Err(); !errors.Is(err, fake.Err) {
+		t.Errorf("error = %v, want %v", err, fake.Err)
+	}
+	if results.Failures() != 1 {
+		t.Errorf("failures = %d, want 1", results.Failures())
+	}
+	assertEmptyDir(t, workDir)
+}
+
+func TestAnalyzeFileMissingFile(t *testing.T) {
+	analyzer, fake, _ := newFixtureAnalyzer(t, internal.Options{})
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "missing.circom"))
+
+	if len(results.Templates) != 1 || !errors.Is(results.Templates[0].Err(), os.ErrNotExist) {
+		t.Fatalf("results = %+v, want one file not found", results.Templates)
+	}
+	if sources := fake.Sources(); len(sources) != 0 {
+		t.Errorf("compiled %v for a missing file", sources)
+	}
+}
+
+func TestAnalyzerReuse(t *testing.T) {
+	analyzer, _, _ := newFixtureAnalyzer(t, internal.Options{})
+	for run := 0; run < 2; run++ {
+		results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+		if len(results.Templates) != 1 {
+			t.Fatalf("run %d: got %d results, want 1", run, len(results.Templates))
+		}
+	}
+}
+
+func TestAnalyzeFileTooLarge(t *testing.T) {
+	analyzer, fake, _ := newFixtureAnalyzer(t, internal.Options{MaxFileSize: 16})
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+
+	if len(results.Templates) != 1 {
+		t.Fatalf("got %d results, want 1", len(results.Templates))
+	}
+	result := results.Templates[0]
+	if result.Skipped == "" || result.Error != "" || result.Template != "" {
+		t.Errorf("result = %+v, want the file skipped", result)
+	}
+	if results.Failures() != 0 {
+		t.Errorf("failures = %d, want 0", results.Failures())
+	}
+	if sources := fake.Sources(); len(sources) != 0 {
+		t.Errorf("compiled %v for a skipped file", sources)
+	}
+}
+
+// compilerFunc adapts a function to the Compiler interface
+type compilerFunc func(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error)
+
+func (f compilerFunc) Compile(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error) {
+	return f(ctx, sourcePath, options)
+}
+
+func TestAnalyzeFileContextStages(t *testing.T) {
+	tests := []struct {
+		stage string
+		// compiler wraps the fake one, cancelling the analysis at the stage
+		compiler func(fake *compilertest.FakeCompiler, cancel context.CancelFunc) internal.Compiler
+	}{
+		{"compile", func(fake *compilertest.FakeCompiler, cancel context.CancelFunc) internal.Compiler {
+			return compilerFunc(func(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error) {
+				cancel()
+				return fake.Compile(ctx, sourcePath, options)
+			})
+		}},
+		{"parse", func(fake *compilertest.FakeCompiler, cancel context.CancelFunc) internal.Compiler {
+			return compilerFunc(func(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error) {
+				artifacts, err := fake.Compile(ctx, sourcePath, options)
+				cancel()
+				return artifacts, err
+			})
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.stage, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			analyzer, _, workDir := newFixtureAnalyzer(t, internal.Options{Compiler: test.compiler(newFixtureCompiler(t), cancel)})
+			if err := analyzer.AnalyzeFileContext(ctx, filepath.Join("testdata", "square.circom")); err != nil {
+				t.Fatal(err)
+			}
+			results := analyzer.Wait()
+
+			if len(results.Templates) != 1 {
+				t.Fatalf("got %d results, want 1", len(results.Templates))
+			}
+			var interrupted *internal.InterruptedError
+			if err := results.Templates[0].Err(); !errors.As(err, &interrupted) || interrupted.Stage != test.stage || !errors.Is(err, context.Canceled) {
+				t.Errorf("error = %v, want interrupted at %s", err, test.stage)
+			}
+			assertEmptyDir(t, workDir)
+		})
+	}
+}
+
+func TestAnalyzeFileContextQueue(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fake := newFixtureCompiler(t)
+	blocking := compilerFunc(func(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error) {
+		close(started)
+		<-release
+		return fake.Compile(ctx, sourcePath, options)
+	})
+	analyzer, _, _ := newFixtureAnalyzer(t, internal.Options{Compiler: blocking})
+
+	// The first file holds the only worker while the second one waits for it
+	if err := analyzer.AnalyzeFile(filepath.Join("testdata", "square.circom")); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := analyzer.AnalyzeFileContext(ctx, filepath.Join("testdata", "missing.circom")); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		// Give the second file the time to give up on the queue
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	results := analyzer.Wait()
+
+	if len(results.Templates) != 2 {
+		t.Fatalf("got %d results, want 2", len(results.Templates))
+	}
+	var interrupted *internal.InterruptedError
+	if err := results.Templates[0].Err(); !errors.As(err, &interrupted) || interrupted.Stage != "queue" {
+		t.Errorf("error = %v, want interrupted in the queue", err)
+	}
+	if results.Templates[1].Error != "" {
+		t.Errorf("first file failed: %s", results.Templates[1].Error)
+	}
+}
+
+func TestAnalyzerOverlappingRuns(t *testing.T) {
+	analyzer, _, _ := newFixtureAnalyzer(t, internal.Options{Parallelism: 2})
+	files := [][]string{
+		{filepath.Join("testdata", "square.circom")},
+		{filepath.Join("testdata", "square.circom"), filepath.Join("testdata", "missing.circom")},
+	}
+	results := make([]internal.Results, len(files))
+	var wg sync.WaitGroup
+	for i := range files {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run := analyzer.Start()
+			for _, file := range files[i] {
+				if err := run.AnalyzeFile(file); err != nil {
+					t.Error(err)
+				}
+			}
+			results[i] = run.Wait()
+		}()
+	}
+	wg.Wait()
+
+	for i, run := range results {
+		if len(run.Templates) != len(files[i]) {
+			t.Errorf("run %d got %d results, want %d", i, len(run.Templates), len(files[i]))
+		}
+	}
+	if results[0].Failures() != 0 || results[1].Failures() != 1 {
+		t.Errorf("failures = %d and %d, want 0 and 1", results[0].Failures(), results[1].Failures())
+	}
+}
+
+// TestAnalyzeParallel is meant for go test -race: the templates of several
+// files are analyzed by concurrent workers sharing the collector and the
+// observer
+func TestAnalyzeParallel(t *testing.T) {
+	observer := &countingObserver{}
+	analyzer, fake, workDir := newFixtureAnalyzer(t, internal.Options{Parallelism: 4, Observer: observer})
+	const files = 8
+	var paths []string
+	for i := 0; i < files; i++ {
+		paths = append(paths, filepath.Join("testdata", "square.circom"), filepath.Join("testdata", "pair.circom"))
+	}
+	results := analyzeFiles(t, analyzer, paths...)
+
+	// Every file of pair.circom yields two templates
+	if want := 3 * files; len(results.Templates) != want || observer.finished() != want {
+		t.Fatalf("got %d results and %d observed, want %d", len(results.Templates), observer.finished(), want)
+	}
+	if results.Failures() != 0 {
+		t.Errorf("failures = %d, want 0", results.Failures())
+	}
+	if sources := fake.Sources(); len(sources) != 3*files {
+		t.Errorf("compiled %d sources, want %d", len(sources), 3*files)
+	}
+	assertEmptyDir(t, workDir)
+}
+
+// countingObserver counts the templates it is told about
+type countingObserver struct {
+	mu   sync.Mutex
+	done int
+}
+
+func (o *countingObserver) TemplateStarted(file, template string) {}
+
+func (o *countingObserver) CompileFinished(template string, elapsed time.Duration, err error) {}
+
+func (o *countingObserver) TemplateFinished(result internal.TemplateResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.done++
+}
+
+func (o *countingObserver) finished() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.done
+}
+
+// TestAnalyzeSameFileConcurrently stands for several processes analyzing the
+// same source at once: every analysis writes its work files next to the
+// source, and none may clash with or remove those of another
+func TestAnalyzeSameFileConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "pair.circom")
+	content, err := os.ReadFile(filepath.Join("testdata", "pair.circom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(source, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	const analyses = 16
+	fake := newFixtureCompiler(t)
+	results := make([]internal.Results, analyses)
+	var wg sync.WaitGroup
+	for i := 0; i < analyses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			analyzer := internal.NewAnalyzer(internal.Options{Parallelism: 2, Quiet: true, Compiler: fake, OutputDir: t.TempDir()})
+			if err := analyzer.AnalyzeFile(source); err != nil {
+				t.Error(err)
+			}
+			results[i] = analyzer.Wait()
+		}()
+	}
+	wg.Wait()
+
+	for i, run := range results {
+		if len(run.Templates) != 2 || run.Failures() != 0 {
+			t.Errorf("analysis %d: %+v, want both templates analyzed", i, run.Templates)
+		}
+	}
+	sources := fake.Sources()
+	unique := make(map[string]bool)
+	for _, compiled := range sources {
+		if unique[compiled] {
+			t.Errorf("%s compiled twice", compiled)
+		}
+		unique[compiled] = true
+		if filepath.Dir(compiled) != dir || !internal.IsWorkFile(filepath.Base(compiled)) {
+			t.Errorf("compiled %s, want a work file next to the source", compiled)
+		}
+	}
+	if len(sources) != 2*analyses {
+		t.Errorf("compiled %d sources, want %d", len(sources), 2*analyses)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "pair.circom" {
+		for _, entry := range entries {
+			t.Errorf("found %s next to the source", entry.Name())
+		}
+	}
+}
+
+// includeRegexp matches the include directives of a source
+var includeRegexp = regexp.MustCompile(`(?m)^\s*include\s+"([^"]+)"\s*;`)
+
+// resolvingCompiler fails the way circom does when an include of the source
+// resolves neither next to it nor in one of the libraries, and compiles with
+// fake otherwise
+func resolvingCompiler(fake *compilertest.FakeCompiler) internal.Compiler {
+	return compilerFunc(func(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error) {
+		content, err := os.ReadFile(sourcePath)
+		if err != nil {
+			return internal.Artifacts{}, err
+		}
+		for _, include := range includeRegexp.FindAllStringSubmatch(string(content), -1) {
+			found := false
+			for _, dir := range append([]string{filepath.Dir(sourcePath)}, options.Libraries...) {
+				if _, err := os.Stat(filepath.Join(dir, include[1])); err == nil {
+					found = true
+				}
+			}
+			if !found {
+				return internal.Artifacts{}, &internal.CompileError{
+					Stderr: fmt.Sprintf("error[P1014]: The file %s to be included has not been found", include[1]),
+					Err:    errors.New("exit status 1"),
+				}
+			}
+		}
+		return fake.Compile(ctx, sourcePath, options)
+	})
+}
+
+func TestAnalyzeFileIncludes(t *testing.T) {
+	shared, err := filepath.Abs(filepath.Join("testdata", "includes", "shared"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join("testdata", "includes", "circuits", "main.circom")
+	tests := []struct {
+		name      string
+		workDir   bool
+		libraries []string
+		wantErr   bool
+	}{
+		{name: "next to the source", libraries: []string{shared}},
+		{name: "in the work dir", workDir: true, libraries: []string{shared}},
+		{name: "next to the source without the library", wantErr: true},
+		{name: "in the work dir without the library", workDir: true, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := newFixtureCompiler(t)
+			options := internal.Options{Parallelism: 1, Quiet: true, Compiler: resolvingCompiler(fake), OutputDir: t.TempDir(), Libraries: test.libraries}
+			if test.workDir {
+				options.WorkDir = t.TempDir()
+			}
+			results := analyzeFiles(t, internal.NewAnalyzer(options), source)
+
+			if len(results.Templates) != 1 || results.Templates[0].Template != "Main" {
+				t.Fatalf("results = %+v, want the Main template", results.Templates)
+			}
+			var compileErr *internal.CompileError
+			if failed := errors.As(results.Templates[0].Err(), &compileErr); failed != test.wantErr {
+				t.Errorf("error = %v, want a compile error: %v", results.Templates[0].Err(), test.wantErr)
+			}
+			sources := fake.Sources()
+			wantDir := filepath.Dir(source)
+			if test.workDir {
+				wantDir = options.WorkDir
+			}
+			if !test.wantErr && (len(sources) != 1 || filepath.Dir(sources[0]) != wantDir) {
+				t.Errorf("compiled %v, want a work file in %s", sources, wantDir)
+			}
+		})
+	}
+}
+
+// deterministicRun analyzes the fixtures with -deterministic and returns the
+// files it wrote by name: the results in every format and the exports
+func deterministicRun(t *testing.T) map[string][]byte {
+	t.Helper()
+	outputDir := t.TempDir()
+	analyzer := internal.NewAnalyzer(internal.Options{Parallelism: 4, Quiet: true, Deterministic: true, Visualize: true, Compiler: newFixtureCompiler(t), WorkDir: t.TempDir(), OutputDir: outputDir})
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "params.circom"), filepath.Join("testdata", "pair.circom"))
+	if results.Failures() != 0 {
+		t.Fatalf("%d failures", results.Failures())
+	}
+	for _, format := range []string{"json", "jsonl", "codeclimate"} {
+		if err := internal.WriteResults(results, format, filepath.Join(outputDir, "results."+format)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := make(map[string][]byte)
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(outputDir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = content
+	}
+	return files
+}
+
+func TestDeterministicRuns(t *testing.T) {
+	first, second := deterministicRun(t), deterministicRun(t)
+	if len(first) != len(second) {
+		t.Fatalf("wrote %d and %d files", len(first), len(second))
+	}
+	for name, content := range first {
+		if !bytes.Equal(content, second[name]) {
+			t.Errorf("%s differs between runs", name)
+		}
+	}
+	for _, name := range []string{"Scale_analysis.json", "Scale_circuit_graph.html"} {
+		if _, ok := first[name]; !ok {
+			t.Errorf("no %s among %d files", name, len(first))
+		}
+	}
+}
+
+// writeDuplicateTemplates writes two files declaring a template named Square
+// below a directory of their own each, and returns their paths
+func writeDuplicateTemplates(t *testing.T, root string) []string {
+	t.Helper()
+	source, err := os.ReadFile(filepath.Join("testdata", "square.circom"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, dir := range []string{"a", "b"} {
+		file := filepath.Join(root, dir, "square.circom")
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, source, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func TestAnalyzeDuplicateTemplatesSampled(t *testing.T) {
+	files := writeDuplicateTemplates(t, t.TempDir())
+	outputDir := t.TempDir()
+	analyzer := internal.NewAnalyzer(internal.Options{Parallelism: 1, Quiet: true, Visualize: true, ArgSamples: 2,
+		Duplicates: map[string][]string{"Square": files}, Compiler: newFixtureCompiler(t), WorkDir: t.TempDir(), OutputDir: outputDir})
+	results := analyzeFiles(t, analyzer, files...)
+
+	outputs := make(map[string]bool)
+	for _, result := range results.Templates {
+		if result.Error != "" {
+			t.Fatalf("analysis of %s failed: %s", result.File, result.Error)
+		}
+		if len(result.Samples) != 2 {
+			t.Errorf("%s has %d samples, want 2", result.File, len(result.Samples))
+		}
+		outputs[result.OutputName()] = true
+	}
+	if len(outputs) != 2 || outputs["Square"] {
+		t.Errorf("output names = %v, want one per file", outputs)
+	}
+	charts, err := filepath.Glob(filepath.Join(outputDir, "*_circuit_graph.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charts) != 2 {
+		t.Errorf("wrote charts %v, want one per file", charts)
+	}
+}
+
+func TestAnalyzeSampledPaths(t *testing.T) {
+	for _, fail := range []bool{false, true} {
+		t.Run(fmt.Sprintf("fail=%v", fail), func(t *testing.T) {
+			root := t.TempDir()
+			files := writeDuplicateTemplates(t, root)
+			fake := newFixtureCompiler(t)
+			if fail {
+				fake.Err = errors.New("compilation failed")
+			}
+			analyzer, _, _ := newFixtureAnalyzer(t, internal.Options{ArgSamples: 2, Root: root, Compiler: fake})
+			results := analyzeFiles(t, analyzer, files...)
+
+			for _, result := range results.Templates {
+				if want := filepath.Base(filepath.Dir(result.File)) + "/square.circom"; result.Path != want {
+					t.Errorf("path of %s = %q, want %q", result.File, result.Path, want)
+				}
+			}
+			summaries := internal.DirectorySummaries(results)
+			if len(summaries) != 2 {
+				t.Fatalf("got %d directory summaries, want 2: %+v", len(summaries), summaries)
+			}
+			for _, summary := range summaries {
+				if summary.Templates != 1 || (summary.Failures == 1) != fail {
+					t.Errorf("summary of %s = %+v, want 1 template", summary.Directory, summary)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeSamplesKeptFiles(t *testing.T) {
+	// The first sample compiles to the square fixture, the later ones to a
+	// larger circuit whose files must not replace those of the kept sample
+	square := newFixtureCompiler(t)
+	cube := &compilertest.FakeCompiler{
+		Constraints: []byte(`{"constraints":[[{"3":"1"},{"3":"1"},{"2":"1"}],[{"2":"1"},{"3":"1"},{"1":"1"}]]}`),
+		Sym:         []byte("1,1,0,main.y\n2,2,0,main.x2\n3,3,0,main.x\n"),
+	}
+	compiled := 0
+	compiler := compilerFunc(func(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error) {
+		compiled++
+		if compiled == 1 {
+			return square.Compile(ctx, sourcePath, options)
+		}
+		return cube.Compile(ctx, sourcePath, options)
+	})
+	outputDir := t.TempDir()
+	analyzer := internal.NewAnalyzer(internal.Options{Parallelism: 1, Quiet: true, SignalsCSV: true, ArgSamples: 3,
+		Compiler: compiler, WorkDir: t.TempDir(), OutputDir: outputDir})
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+
+	if result := results.Templates[0]; result.Error != "" || result.Stats.Signals != 2 || len(result.Samples) != 3 {
+		t.Fatalf("result = %d signals over %d samples, error %q, want the 2 signals of the first of 3 samples", result.Stats.Signals, len(result.Samples), result.Error)
+	}
+	content, err := os.ReadFile(filepath.Join(outputDir, "Square_signals.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A header and the two signals of the square fixture
+	if rows := bytes.Count(content, []byte("\n")); rows != 3 {
+		t.Errorf("Square_signals.csv has %d rows, want those of the kept sample:\n%s", rows, content)
+	}
+}
+
+// TestAnalyzeLowMemory checks that -low-memory hands the full result to the
+// observer and keeps only its stats and finding counts
+func TestAnalyzeLowMemory(t *testing.T) {
+	observer := &keepingObserver{}
+	analyzer, _, _ := newFixtureAnalyzer(t, internal.Options{LowMemory: true, Observer: observer})
+	results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+
+	if len(results.Templates) != 1 || len(observer.results) != 1 {
+		t.Fatalf("got %d results and %d observed, want 1", len(results.Templates), len(observer.results))
+	}
+	kept, full := results.Templates[0], observer.results[0]
+	if len(full.Findings) == 0 {
+		t.Fatal("the observer got no findings")
+	}
+	if kept.Findings != nil || kept.Subgraphs != nil || kept.Blocks != nil || kept.HotSpots != nil {
+		t.Errorf("kept the details of the template: %+v", kept)
+	}
+	if kept.Stats.Constraints != full.Stats.Constraints || kept.Stats.Signals != full.Stats.Signals || kept.Stats.Edges != full.Stats.Edges {
+		t.Errorf("stats = %+v, want %+v", kept.Stats, full.Stats)
+	}
+	if kept.FindingCount() != len(full.Findings) || results.Findings() != len(full.Findings) {
+		t.Errorf("finding count = %d and %d in total, want %d", kept.FindingCount(), results.Findings(), len(full.Findings))
+	}
+	severities := 0
+	for _, finding := range full.Findings {
+		if finding.Severity == full.Findings[0].Severity {
+			severities++
+		}
+	}
+	if got := kept.Severities[full.Findings[0].Severity]; got != severities {
+		t.Errorf("%s findings = %d, want %d", full.Findings[0].Severity, got, severities)
+	}
+}
+
+// keepingObserver keeps the results it is told about
+type keepingObserver struct {
+	mu      sync.Mutex
+	results []internal.TemplateResult
+}
+
+func (o *keepingObserver) TemplateStarted(file, template string) {}
+
+func (o *keepingObserver) CompileFinished(template string, elapsed time.Duration, err error) {}
+
+func (o *keepingObserver) TemplateFinished(result internal.TemplateResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.results = append(o.results, result)
+}
+
+func TestAnalyzeDropConstantExports(t *testing.T) {
+	// (1 + x) * x = y, the only constraint mentioning the "1" signal
+	fake := &compilertest.FakeCompiler{
+		Constraints: []byte(`{"constraints":[[{"0":"1","2":"1"},{"2":"1"},{"1":"1"}]]}`),
+		Sym:         []byte("1,1,0,main.y\n2,2,0,main.x\n"),
+	}
+	tests := []struct {
+		dropConstant bool
+		header       string
+	}{
+		{false, "signal,1,main.y,main.x"},
+		{true, "signal,main.y,main.x"},
+	}
+	for _, test := range tests {
+		outputDir := t.TempDir()
+		analyzer := internal.NewAnalyzer(internal.Options{Parallelism: 1, Quiet: true, Cooccurrence: "*", ComponentTotals: true,
+			DropConstant: test.dropConstant, Compiler: fake, WorkDir: t.TempDir(), OutputDir: outputDir})
+		results := analyzeFiles(t, analyzer, filepath.Join("testdata", "square.circom"))
+
+		result := results.Templates[0]
+		if result.Error != "" {
+			t.Fatalf("analysis failed: %s", result.Error)
+		}
+		content, err := os.ReadFile(filepath.Join(outputDir, "Square_cooccurrence.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header, _, _ := bytes.Cut(content, []byte("\n")); string(header) != test.header {
+			t.Errorf("drop constant %v: header = %q, want %q", test.dropConstant, header, test.header)
+		}
+		if totals := result.ComponentTotals; totals == nil || totals.Total != 1 || totals.Own != 1 {
+			t.Errorf("drop constant %v: component totals = %+v, want the constraint on main", test.dropConstant, totals)
+		}
+	}
+}
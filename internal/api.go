@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxMultipartMemory is the part of a request kept in memory while parsing,
+// larger uploads are buffered in temporary files
+const maxMultipartMemory = 8 << 20
+
+// APIOptions configures the HTTP service returned by NewAPIHandler
+type APIOptions struct {
+	Circom          Circom        // Compiler for uploaded sources, checked by /healthz
+	Analysis        Options       // Analysis settings of every request, always quiet and sequential
+	Workers         int           // Requests analyzed concurrently, others wait for a free worker
+	MaxRequestBytes int64         // Largest accepted request body
+	Timeout         time.Duration // Maximum time per request, waiting for a worker included
+}
+
+// NewAPIHandler serves the analysis over HTTP:
+//
+//	POST /analyze  multipart form with either a "circuit" .circom file, or
+//	               "constraints" and "sym" files compiled elsewhere, plus an
+//	               optional "template" name. Returns the results as JSON.
+//	GET  /healthz  checks the circom installation.
+//	GET  /metrics  counts templates, compile times, busy workers and findings
+//	               by rule since the start, in the Prometheus text format.
+//
+// Every request gets its own temporary directory, removed when it completes,
+// and its own analyzer, so requests share nothing but the worker pool.
+func NewAPIHandler(options APIOptions) http.Handler {
+	workers := make(chan struct{}, max(options.Workers, 1))
+	analysis := options.Analysis
+	analysis.Compiler = LocalCircom{Circom: options.Circom}
+	analysis.Parallelism = 1
+	analysis.Quiet = true
+	metrics := newMetrics()
+	analysis.Observer = metrics
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+		if err := CheckCircomInstallation(options.Circom); err != nil {
+			writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		version, _ := CircomVersion(options.Circom)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "circom": version})
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, "use GET")
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		metrics.write(w, len(workers), cap(workers))
+	})
+	mux.HandleFunc("/analyze", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeAPIError(w, http.StatusMethodNotAllowed, "use POST")
+			return
+		}
+		ctx := r.Context()
+		if options.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+			defer cancel()
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, options.MaxRequestBytes)
+		if err := r.ParseMultipartForm(maxMultipartMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeAPIError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request larger than %d bytes", options.MaxRequestBytes))
+				return
+			}
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		select {
+		case workers <- struct{}{}:
+			defer func() { <-workers }()
+		case <-ctx.Done():
+			writeAPIError(w, http.StatusServiceUnavailable, "no worker became available in time")
+			return
+		}
+
+		dir, err := os.MkdirTemp("", "circuit-api-*")
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		defer os.RemoveAll(dir)
+
+		results, status, err := analyzeUpload(ctx, analysis, r.MultipartForm, dir)
+		if err != nil {
+			writeAPIError(w, status, err.Error())
+			return
+		}
+		if ctx.Err() != nil {
+			writeAPIError(w, http.StatusGatewayTimeout, "analysis did not finish in time")
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+	})
+	return mux
+}
+
+// analyzeUpload runs the analysis on the files of a request, saved to dir,
+// and returns the HTTP status to report along with an error
+func analyzeUpload(ctx context.Context, options Options, form *multipart.Form, dir string) (Results, int, error) {
+	templateName := ""
+	if values := form.Value["template"]; len(values) > 0 {
+		templateName = values[0]
+	}
+
+	if _, ok := form.File["circuit"]; ok {
+		header := form.File["circuit"][0]
+		name := filepath.Base(header.Filename)
+		if !strings.HasSuffix(name, ".circom") {
+			return Results{}, http.StatusBadRequest, errors.New(`the "circuit" file must be a .circom file`)
+		}
+		path := filepath.Join(dir, name)
+		if err := saveUpload(header, path); err != nil {
+			return Results{}, http.StatusInternalServerError, err
+		}
+
+		options.Only = templateName
+		analyzer := NewAnalyzer(options)
+		if err := analyzer.AnalyzeFileContext(ctx, path); err != nil {
+			return Results{}, http.StatusInternalServerError, err
+		}
+		results := analyzer.Wait()
+		for i := range results.Templates {
+			results.Templates[i].File = name // Not the temporary path
+		}
+		return results, http.StatusOK, nil
+	}
+
+	constraints, sym := form.File["constraints"], form.File["sym"]
+	if len(constraints) == 0 || len(sym) == 0 {
+		return Results{}, http.StatusBadRequest, errors.New(`send a "circuit" file, or both "constraints" and "sym" files`)
+	}
+	artifacts := Artifacts{
+		ConstraintsFile: filepath.Join(dir, "circuit_constraints.json"),
+		SymFile:         filepath.Join(dir, "circuit.sym"),
+	}
+	if err := saveUpload(constraints[0], artifacts.ConstraintsFile); err != nil {
+		return Results{}, http.StatusInternalServerError, err
+	}
+	if err := saveUpload(sym[0], artifacts.SymFile); err != nil {
+		return Results{}, http.StatusInternalServerError, err
+	}
+	if r1cs := form.File["r1cs"]; len(r1cs) > 0 {
+		artifacts.R1CSFile = filepath.Join(dir, "circuit.r1cs")
+		if err := saveUpload(r1cs[0], artifacts.R1CSFile); err != nil {
+			return Results{}, http.StatusInternalServerError, err
+		}
+	}
+	if templateName == "" {
+		templateName = "main"
+	}
+	result := NewAnalyzer(options).AnalyzeArtifacts(ctx, templateName, artifacts)
+	result.File = constraints[0].Filename
+	return Results{Templates: []TemplateResult{result}}, http.StatusOK, nil
+}
+
+func saveUpload(header *multipart.FileHeader, path string) error {
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
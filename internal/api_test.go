@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// postCircuit uploads a source to /analyze and returns the results
+func postCircuit(t *testing.T, server *httptest.Server, path string) Results {
+	t.Helper()
+	results, err := analyzeRequest(server, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+// analyzeRequest is postCircuit returning an error, for goroutines
+func analyzeRequest(server *httptest.Server, path string) (Results, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Results{}, err
+	}
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("circuit", filepath.Base(path))
+	if err != nil {
+		return Results{}, err
+	}
+	part.Write(content)
+	form.Close()
+
+	response, err := http.Post(server.URL+"/analyze", form.FormDataContentType(), &body)
+	if err != nil {
+		return Results{}, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(response.Body)
+		return Results{}, fmt.Errorf("status %d: %s", response.StatusCode, message)
+	}
+	var results Results
+	err = json.NewDecoder(response.Body).Decode(&results)
+	return results, err
+}
+
+// scrapeMetrics returns the samples of /metrics by name, labels included
+func scrapeMetrics(t *testing.T, server *httptest.Server) map[string]string {
+	t.Helper()
+	response, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		if name, value, ok := strings.Cut(line, " "); ok && !strings.HasPrefix(line, "#") {
+			samples[name] = value
+		}
+	}
+	return samples
+}
+
+func TestAPIMetrics(t *testing.T) {
+	circom := Circom{Path: writeFakeCircom(t, t.TempDir())}
+	server := httptest.NewServer(NewAPIHandler(APIOptions{Circom: circom, Workers: 2, MaxRequestBytes: 1 << 20, Timeout: time.Minute}))
+	defer server.Close()
+
+	// Identical requests are analyzed again, with new random arguments
+	first := postCircuit(t, server, filepath.Join("testdata", "square.circom"))
+	postCircuit(t, server, filepath.Join("testdata", "square.circom"))
+	if len(first.Templates) != 1 || first.Templates[0].Error != "" {
+		t.Fatalf("results = %+v", first.Templates)
+	}
+
+	samples := scrapeMetrics(t, server)
+	want := map[string]string{
+		"circuit_analyzer_templates_started_total":        "2",
+		"circuit_analyzer_templates_completed_total":      "2",
+		"circuit_analyzer_templates_failed_total":         "0",
+		"circuit_analyzer_files_failed_total":             "0",
+		"circuit_analyzer_compile_duration_seconds_count": "2",
+		"circuit_analyzer_compile_errors_total":           "0",
+		"circuit_analyzer_workers_in_flight":              "0",
+		"circuit_analyzer_workers":                        "2",
+	}
+	for name, value := range want {
+		if samples[name] != value {
+			t.Errorf("%s = %q, want %s", name, samples[name], value)
+		}
+	}
+	findings := 0
+	for _, finding := range first.Templates[0].Findings {
+		if samples["circuit_analyzer_findings_total{rule=\""+finding.Category+"\"}"] == "" {
+			t.Errorf("no findings_total sample for %s", finding.Category)
+		}
+		findings++
+	}
+	if findings == 0 {
+		t.Error("the square fixture reported no findings")
+	}
+}
+
+func TestMetricsFileFailures(t *testing.T) {
+	m := newMetrics()
+	m.TemplateStarted("a.circom", "Square")
+	m.TemplateFinished(TemplateResult{File: "a.circom", Template: "Square", Error: "compile error"})
+	m.TemplateFinished(TemplateResult{File: "b.circom", Error: "permission denied"})
+	m.TemplateFinished(TemplateResult{File: "c.circom", Skipped: "too large"})
+
+	var out strings.Builder
+	if err := m.write(&out, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, sample := range []string{
+		"circuit_analyzer_templates_started_total 1\n",
+		"circuit_analyzer_templates_failed_total 1\n",
+		"circuit_analyzer_files_failed_total 1\n",
+		"circuit_analyzer_templates_completed_total 0\n",
+	} {
+		if !strings.Contains(out.String(), sample) {
+			t.Errorf("metrics lack %q:\n%s", sample, out.String())
+		}
+	}
+}
+
+func TestAPIConcurrentRequests(t *testing.T) {
+	// The temporary directories of the requests go to TMPDIR
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	circom := Circom{Path: writeFakeCircom(t, t.TempDir())}
+	server := httptest.NewServer(NewAPIHandler(APIOptions{Circom: circom, Workers: 3, MaxRequestBytes: 1 << 20, Timeout: time.Minute}))
+	defer server.Close()
+
+	circuits := map[string][]string{
+		"square.circom": {"Square"},
+		"pair.circom":   {"Cube", "Square"},
+		"params.circom": {"Scale"},
+	}
+	var wg sync.WaitGroup
+	for round := 0; round < 3; round++ {
+		for name, want := range circuits {
+			wg.Add(1)
+			go func(name string, want []string) {
+				defer wg.Done()
+				results, err := analyzeRequest(server, filepath.Join("testdata", name))
+				if err != nil {
+					t.Errorf("%s: %v", name, err)
+					return
+				}
+				var templates []string
+				for _, result := range results.Templates {
+					if result.File != name || result.Error != "" {
+						t.Errorf("%s: result %+v", name, result)
+					}
+					templates = append(templates, result.Template)
+				}
+				sort.Strings(templates)
+				if !reflect.DeepEqual(templates, want) {
+					t.Errorf("%s: templates = %v, want %v", name, templates, want)
+				}
+			}(name, want)
+		}
+	}
+	wg.Wait()
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "circuit-api-") {
+			t.Errorf("temporary directory %s was left behind", entry.Name())
+		}
+	}
+}
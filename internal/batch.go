@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Project is one logical run of a batch, the analysis of a single project root
+type Project struct {
+	Name    string // Unique name of the project, also its output directory
+	Root    string
+	Files   int // .circom files analyzed
+	Results Results
+	Err     error // Error that kept the project from being analyzed, e.g. an unreadable root
+
+	// Files declaring every template name declared by more than one
+	Duplicates map[string][]string
+	// Files of the root left out of the analysis, see ScopeFiles
+	Scope Scope
+}
+
+// ProjectNames returns a unique directory name for every project root, its
+// base name with a numeric suffix for roots sharing one
+func ProjectNames(roots []string) []string {
+	names := make([]string, len(roots))
+	used := make(map[string]bool)
+	for i, root := range roots {
+		base := sanitizeFileName(filepath.Base(filepath.Clean(root)))
+		if base == "." || base == ".." || base == "_" {
+			base = "project"
+		}
+		name := base
+		for n := 2; used[name]; n++ {
+			name = base + "-" + strconv.Itoa(n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
+
+// seconds returns the time spent on the templates of the project
+func (p Project) seconds() float64 {
+	total := 0.0
+	for _, t := range p.Results.Templates {
+		total += t.Seconds
+	}
+	return total
+}
+
+// WriteRollup compares the projects of a batch, with one row per project and
+// the slowest templates across all of them, up to slowest
+func WriteRollup(w io.Writer, projects []Project, slowest int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROJECT\tFILES\tTEMPLATES\tFINDINGS\tFAILURES\tSECONDS")
+	for _, p := range projects {
+		if p.Err != nil {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\tfailed\t-\n", p.Name)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.1f\n", p.Name, p.Files, len(p.Results.Templates),
+			p.Results.Findings(), p.Results.Failures(), p.seconds())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	type timed struct {
+		project string
+		result  TemplateResult
+	}
+	var templates []timed
+	for _, p := range projects {
+		for _, t := range p.Results.Templates {
+			if t.Seconds > 0 {
+				templates = append(templates, timed{p.Name, t})
+			}
+		}
+	}
+	if len(templates) == 0 || slowest <= 0 {
+		return nil
+	}
+	sort.SliceStable(templates, func(i, j int) bool { return templates[i].result.Seconds > templates[j].result.Seconds })
+	templates = templates[:min(slowest, len(templates))]
+
+	fmt.Fprintln(w, "Slowest templates:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, t := range templates {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%.1fs\n", t.project, t.result.Template, t.result.File, t.result.Seconds)
+	}
+	return tw.Flush()
+}
@@ -1,54 +1,291 @@
 package internal
 
 import (
-	"encoding/csv"
-	"encoding/json"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	mathrand "math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
-func CheckCircomInstallation() error {
-	cmd := exec.Command("circom", "--version")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("circom is not installed or not in PATH")
+// Names circom is installed under: the Rust binary, its Windows build and the npm shim
+var circomBinaries = []string{"circom", "circom.exe", "circom.cmd"}
+
+// findCircom resolves the circom executable through PATH
+func findCircom() (string, error) {
+	for _, name := range circomBinaries {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
 	}
-	return nil
+	return "", ErrCircomNotFound
 }
 
-func GetCircomFiles(path string) ([]string, error) {
-	var files []string
+// Circom describes how the circom compiler is invoked
+type Circom struct {
+	Path        string // Explicit circom binary, otherwise resolved through PATH
+	DockerImage string // Run circom inside this Docker image instead of the local binary
+	MinVersion  string // Oldest circom version accepted by CheckCircomInstallation
+}
+
+// Oldest circom release whose --json and --sym output this tool understands
+const DefaultMinCircomVersion = "2.0.0"
+
+// Matches both "circom compiler 2.1.8" from the Rust compiler and the bare "0.5.46" of the npm package
+var versionRegexp = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+type circomVersion [3]int
+
+func parseCircomVersion(s string) (circomVersion, error) {
+	var v circomVersion
+	match := versionRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return v, fmt.Errorf("unrecognized circom version %q", s)
+	}
+	for i := range v {
+		fmt.Sscanf(match[i+1], "%d", &v[i])
+	}
+	return v, nil
+}
 
-	err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+func (v circomVersion) less(other circomVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] < other[i]
+		}
+	}
+	return false
+}
+
+func (v circomVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// binary returns the circom executable to run
+func (c Circom) binary() (string, error) {
+	if c.Path == "" {
+		return findCircom()
+	}
+	if _, err := os.Stat(c.Path); err != nil {
+		return "", fmt.Errorf("circom binary %s does not exist: %w", c.Path, ErrCircomNotFound)
+	}
+	path, err := exec.LookPath(c.Path)
+	if err != nil {
+		return "", fmt.Errorf("circom binary %s is not executable: %w", c.Path, ErrCircomNotFound)
+	}
+	return path, nil
+}
+
+func (c Circom) String() string {
+	if c.DockerImage != "" {
+		return "circom in Docker image " + c.DockerImage
+	}
+	if path, err := c.binary(); err == nil {
+		return path
+	}
+	return "circom"
+}
+
+// command builds the circom invocation. With a Docker image only dir and the
+// libraries, read-only, are mounted into the container, at the same paths, so
+// file paths need no translation.
+func (c Circom) command(ctx context.Context, dir string, libraries []string, args ...string) (*exec.Cmd, error) {
+	if c.DockerImage == "" {
+		circomPath, err := c.binary()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".circom") {
-			files = append(files, path)
+		return exec.CommandContext(ctx, circomPath, args...), nil
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return nil, fmt.Errorf("docker is not installed or not in PATH: %w", ErrCircomNotFound)
+	}
+
+	dockerArgs := []string{"run", "--rm"}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	containerName := "circuit-analyzer-" + hex.EncodeToString(suffix)
+	dockerArgs = append(dockerArgs, "--name", containerName)
+	if dir != "" {
+		dockerArgs = append(dockerArgs, "-v", dir+":"+dir, "-w", dir)
+	}
+	for _, library := range libraries {
+		if library, err := filepath.Abs(library); err == nil && library != dir {
+			dockerArgs = append(dockerArgs, "-v", library+":"+library+":ro")
 		}
+	}
+	dockerArgs = append(dockerArgs, c.DockerImage, "circom")
+	dockerArgs = append(dockerArgs, args...)
+
+	cmd := exec.CommandContext(ctx, "docker", dockerArgs...)
+	// Killing the docker client leaves the container running, so kill the container itself
+	cmd.Cancel = func() error {
+		exec.Command("docker", "kill", containerName).Run()
+		return cmd.Process.Kill()
+	}
+	return cmd, nil
+}
+
+// run executes circom, returning its stdout and folding the exit code and stderr into the error
+func (c Circom) run(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd, err := c.command(ctx, dir, nil, args...)
+	if err != nil {
+		return "", err
+	}
+	return runCommand(ctx, cmd)
+}
+
+func runCommand(ctx context.Context, cmd *exec.Cmd) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return "", &exitError{code: exitErr.ExitCode(), stderr: strings.TrimSpace(stderr.String())}
+	}
+	return stdout.String(), err
+}
+
+// CircomVersion returns the version string reported by circom --version
+func CircomVersion(c Circom) (string, error) {
+	out, err := c.run(context.Background(), "", "--version")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func CheckCircomInstallation(c Circom) error {
+	version, err := CircomVersion(c)
+	if err != nil {
+		if c.DockerImage != "" {
+			return fmt.Errorf("circom could not be executed in image %s: %w", c.DockerImage, err)
+		}
+		return err
+	}
+
+	if c.MinVersion == "" {
 		return nil
-	})
+	}
+	required, err := parseCircomVersion(c.MinVersion)
+	if err != nil {
+		return err
+	}
+	detected, err := parseCircomVersion(version)
+	if err != nil {
+		return err
+	}
+	if detected.less(required) {
+		return fmt.Errorf("circom %s (%s) is too old, version %s or newer is required. "+
+			"Install a newer compiler from https://docs.circom.io/getting-started/installation/", detected, c, required)
+	}
+	return nil
+}
+
+func GetCircomFiles(path string, options WalkOptions) ([]string, WalkStats, error) {
+	if manifest, ok := strings.CutPrefix(path, "@"); ok {
+		files, err := readManifest(manifest)
+		return files, WalkStats{}, err
+	}
+
+	return walkCircomFiles(path, options)
+}
+
+// readManifest reads a newline-delimited list of files, such as the output of
+// git diff --name-only. Entries that are not .circom files are skipped.
+func readManifest(manifest string) ([]string, error) {
+	content, err := os.ReadFile(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(string(content), "\n") {
+		path := strings.TrimSpace(line)
+		if path == "" || !strings.HasSuffix(path, ".circom") {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s listed in %s: %v", path, manifest, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("%s listed in %s is a directory", path, manifest)
+		}
+		files = append(files, path)
+	}
 
-	return files, err
+	return files, nil
 }
 
+// CreateTempCircomFile copies a source file without its main component to a
+// uniquely named file next to it, so that its includes still resolve. The
+// main component is blanked rather than removed, so every other line keeps
+// its number and compiler errors can be mapped back to the source.
 func CreateTempCircomFile(originalPath string) (string, error) {
+	return createTempCircomFile(originalPath, "", workFilePrefix+"*.circom")
+}
+
+// createTemplateWorkFile is CreateTempCircomFile for the analysis of one
+// template, named after it as well, e.g. circuit-analyzer_Main_123456.circom.
+// The copy is written to workDir instead if set, whose compilation then needs
+// the directory of the original as a library.
+func createTemplateWorkFile(originalPath, workDir, templateName string) (string, error) {
+	return createTempCircomFile(originalPath, workDir, workFilePrefix+sanitizeFileName(templateName)+"_*.circom")
+}
+
+// createMainWorkFile is createTemplateWorkFile keeping the main component of
+// the source, to compile the file as it is
+func createMainWorkFile(originalPath, workDir, templateName string) (string, error) {
 	content, err := os.ReadFile(originalPath)
 	if err != nil {
 		return "", err
 	}
+	return writeWorkFile(originalPath, workDir, workFilePrefix+sanitizeFileName(templateName)+"_*.circom", content)
+}
 
-	// Remove existing main component
-	re := regexp.MustCompile(`(?m)^component\s+main\s*=.*$`)
-	content = re.ReplaceAll(content, []byte{})
+// mainDeclarationRegexp matches the main component a source declares, up to
+// its semicolon, whatever lines its public signals and arguments span
+var mainDeclarationRegexp = regexp.MustCompile(`(?m)^\s*component\s+main\s*(\{[^}]*\})?\s*=[^;]*;`)
 
-	originalDir := filepath.Dir(originalPath)
+func createTempCircomFile(originalPath, dir, pattern string) (string, error) {
+	content, err := os.ReadFile(originalPath)
+	if err != nil {
+		return "", err
+	}
 
-	tempFile, err := os.CreateTemp(originalDir, "circom_*.circom")
+	// Blank the existing main component, keeping its line breaks
+	content = mainDeclarationRegexp.ReplaceAllFunc(content, func(main []byte) []byte {
+		return bytes.Repeat([]byte("\n"), bytes.Count(main, []byte("\n")))
+	})
+	return writeWorkFile(originalPath, dir, pattern, content)
+}
+
+// writeWorkFile writes the content of a temporary source to a file named
+// after pattern in dir, or next to the original if dir is empty
+func writeWorkFile(originalPath, dir, pattern string, content []byte) (string, error) {
+	if dir == "" {
+		dir = filepath.Dir(originalPath)
+	}
+
+	tempFile, err := os.CreateTemp(dir, pattern)
 	if err != nil {
 		return "", err
 	}
@@ -61,129 +298,310 @@ func CreateTempCircomFile(originalPath string) (string, error) {
 	return tempFile.Name(), nil
 }
 
-func AddMainComponent(tempFilePath, templateName string, args []int) error {
+// fileMainRegexp matches the main component a source declares, e.g.
+// component main {public [a]} = Top(20);
+var fileMainRegexp = regexp.MustCompile(`(?m)^\s*(component\s+main\s*(?:\{[^}]*\})?\s*=\s*(\w+)\s*\(([^;]*)\)\s*;)`)
+
+// fileMain is the main component declared by a source file
+type fileMain struct {
+	component string   // Declaration as written
+	template  string   // Template it instantiates
+	args      []string // Arguments as written
+}
+
+// readFileMain returns the main component declared by a source file, ok
+// false if it declares none
+func readFileMain(path string) (main fileMain, ok bool, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fileMain{}, false, err
+	}
+	match := fileMainRegexp.FindSubmatch(content)
+	if match == nil {
+		return fileMain{}, false, nil
+	}
+	main = fileMain{component: strings.Join(strings.Fields(string(match[1])), " "), template: string(match[2])}
+	if args := strings.TrimSpace(string(match[3])); args != "" {
+		main.args = splitArgs(args)
+	}
+	return main, true, nil
+}
+
+// splitArgs splits the arguments of a template instantiation at the commas
+// outside of brackets and parentheses, e.g. 2, [1, 2], f(3, 4)
+func splitArgs(args string) []string {
+	var split []string
+	depth, start := 0, 0
+	for i, c := range args {
+		switch c {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				split = append(split, strings.TrimSpace(args[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(split, strings.TrimSpace(args[start:]))
+}
+
+// MainComponent returns the main component instantiating templateName with args
+func MainComponent(templateName string, args []string) string {
+	return fmt.Sprintf("component main = %s(%s);", templateName, strings.Join(args, ", "))
+}
+
+func AddMainComponent(tempFilePath, templateName string, args []string) error {
+	return appendMainComponent(tempFilePath, MainComponent(templateName, args))
+}
+
+var mainComponentRegexp = regexp.MustCompile(`^\s*component\s+main\s*(\{[^}]*\})?\s*=\s*(\w+)\s*\(`)
+
+// AddCustomMainComponent appends a main component supplied verbatim, which must instantiate templateName
+func AddCustomMainComponent(tempFilePath, templateName, mainComponent string) error {
+	match := mainComponentRegexp.FindStringSubmatch(mainComponent)
+	if match == nil {
+		return fmt.Errorf("custom main component %q is not of the form component main = %s(...);", mainComponent, templateName)
+	}
+	if match[2] != templateName {
+		return fmt.Errorf("custom main component instantiates %s, expected %s", match[2], templateName)
+	}
+	return appendMainComponent(tempFilePath, mainComponent)
+}
+
+func appendMainComponent(tempFilePath, mainComponent string) error {
 	f, err := os.OpenFile(tempFilePath, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	mainComponent := fmt.Sprintf("\ncomponent main = %s(%s);\n", templateName, joinInts(args))
-	if _, err := f.WriteString(mainComponent); err != nil {
+	if _, err := f.WriteString("\n" + mainComponent + "\n"); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func CompileCircuit(tempFilePath string) (string, string, error) {
-	outputPath := strings.TrimSuffix(tempFilePath, filepath.Ext(tempFilePath))
-	cmd := exec.Command("circom", "--json", "--sym", "--O0", "-o", filepath.Dir(tempFilePath), tempFilePath)
-	err := cmd.Run()
+func CompileCircuit(ctx context.Context, c Circom, tempFilePath string, options CompileOptions) (Artifacts, error) {
+	tempFilePath, err := filepath.Abs(tempFilePath)
 	if err != nil {
-		return "", "", fmt.Errorf("compilation failed: %v", err)
+		return Artifacts{}, err
 	}
 
-	constraintsFile := outputPath + "_constraints.json"
-	if _, err := os.Stat(constraintsFile); os.IsNotExist(err) {
-		return "", "", fmt.Errorf("constraints file not generated")
+	outputDir := filepath.Dir(tempFilePath)
+	optimization := options.Optimization
+	if optimization == "" {
+		optimization = "O0"
+	}
+	args := []string{"--json", "--sym", "--r1cs"}
+	if !setsOptimization(options.Args) {
+		args = append(args, "--"+optimization)
+	}
+	args = append(args, "-o", outputDir)
+	for _, library := range options.Libraries {
+		args = append(args, "-l", library)
+	}
+	args = append(args, options.Args...)
+	args = append(args, tempFilePath)
+	cmd, err := c.command(ctx, outputDir, options.Libraries, args...)
+	if err != nil {
+		return Artifacts{}, err
+	}
+	command := shellJoin(cmd.Args)
+
+	constraintsFile, symFile, r1csFile := circomOutputs(tempFilePath)
+	// Remove whatever a failed or killed compilation left behind
+	cleanup := func() {
+		os.Remove(constraintsFile)
+		os.Remove(symFile)
+		os.Remove(r1csFile)
 	}
 
-	symFile := outputPath + ".sym"
+	if _, err := runCommand(ctx, cmd); err != nil {
+		cleanup()
+		compileErr := &CompileError{Command: command, Err: err}
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			compileErr.Err = fmt.Errorf("exit code %d", exitErr.code)
+			compileErr.Stderr = exitErr.stderr
+		}
+		return Artifacts{}, compileErr
+	}
+
+	if _, err := os.Stat(constraintsFile); os.IsNotExist(err) {
+		cleanup()
+		return Artifacts{}, &CompileError{Command: command, Err: notGenerated("constraints", options.Args)}
+	}
 	if _, err := os.Stat(symFile); os.IsNotExist(err) {
-		return "", "", fmt.Errorf("sym file not generated")
+		cleanup()
+		return Artifacts{}, &CompileError{Command: command, Err: notGenerated("sym", options.Args)}
 	}
 
-	return constraintsFile, symFile, nil
+	artifacts := Artifacts{ConstraintsFile: constraintsFile, SymFile: symFile, Command: command}
+	// Only the visibility of the inputs depends on the r1cs file, which some
+	// circom wrappers do not write
+	if _, err := os.Stat(r1csFile); err == nil {
+		artifacts.R1CSFile = r1csFile
+	}
+	return artifacts, nil
 }
 
-func joinInts(ints []int) string {
-	strInts := make([]string, len(ints))
-	for i, v := range ints {
-		strInts[i] = fmt.Sprintf("%d", v)
+// optimizationFlags are the circom flags setting the simplification level
+var optimizationFlags = map[string]bool{"--O0": true, "--O1": true, "--O2": true, "--O2round": true}
+
+// setsOptimization reports whether passthrough flags set the simplification
+// level, which then replaces that of CompileOptions
+func setsOptimization(args []string) bool {
+	for _, arg := range args {
+		if optimizationFlags[strings.SplitN(arg, "=", 2)[0]] {
+			return true
+		}
 	}
-	return strings.Join(strInts, ", ")
+	return false
 }
 
-func GenerateRandomArgs(count int) []int {
-	args := make([]int, count)
-	for i := range args {
-		args[i] = rand.Intn(14) + 2 // Random int in range [2, 15]
+// notGenerated is the error of a compilation that succeeded without writing
+// one of the outputs the analysis reads, pointing at passthrough flags if any
+func notGenerated(output string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%s file not generated, check the circom arguments %s", output, strings.Join(args, " "))
 	}
-	return args
+	return fmt.Errorf("%s file not generated", output)
 }
 
-// Each constraint is an array of three linear expressions. Each expression contains the signals used.
-type Constraints [][3][]int64
-
-func LoadFromJson(constraintsFile string) (Constraints, error) {
-	// Variable to hold the unmarshaled data
-	var constraints Constraints
-
-	// Read the entire JSON file
-	data, err := os.ReadFile(constraintsFile)
-	if err != nil {
-		return constraints, err
+// CheckCircomArgs rejects passthrough flags that would move or drop the
+// outputs the analysis reads. Any other flag is passed as it is, and what it
+// does is up to the user.
+func CheckCircomArgs(args []string) error {
+	for _, arg := range args {
+		switch strings.SplitN(arg, "=", 2)[0] {
+		case "-o", "--output":
+			return fmt.Errorf("circom argument %s is not supported, the outputs are written next to the temporary copy of the source (see -work-dir)", arg)
+		}
 	}
+	return nil
+}
 
-	// Temp variable to hold the unmarshaled data
-	var tempData struct {
-		Constraints [][3]map[string]string `json:"constraints"`
-	}
-	err = json.Unmarshal(data, &tempData)
-	if err != nil {
-		return constraints, err
-	}
+// circomOutputDirs returns the directories circom writes next to a source
+// compiled with --wasm or --c, which passthrough flags may ask for
+func circomOutputDirs(sourcePath string) []string {
+	base := strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath))
+	return []string{base + "_js", base + "_cpp"}
+}
 
-	// Convert keys from string to integers
-	for _, tempConstraint := range tempData.Constraints {
-		var intConstraints [3][]int64
-		for i, linearExpression := range tempConstraint {
-			for key := range linearExpression {
-				intKey := stringToInt(key)
-				intConstraints[i] = append(intConstraints[i], intKey)
-			}
+// circomOutputs returns the files circom writes next to a source compiled
+// with --json, --sym and --r1cs, named after it without the extension
+func circomOutputs(sourcePath string) (constraintsFile, symFile, r1csFile string) {
+	base := strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath))
+	return base + "_constraints.json", base + ".sym", base + ".r1cs"
+}
+
+// shellJoin renders a command line that can be pasted into a POSIX shell
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.Trim(arg, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-+=./:@%,") == "" {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
 		}
-		constraints = append(constraints, intConstraints)
 	}
+	return strings.Join(quoted, " ")
+}
 
-	return constraints, nil
+func GenerateRandomArgs(count int) []int {
+	return generateRandomArgs(nil, count)
 }
 
-func LoadFromSym(symFile string) ([]string, error) {
-	var signals []string
+// The range random arguments are drawn from unless configured otherwise
+const (
+	minRandomArg = 2
+	maxRandomArg = 15
+)
 
-	// Open the file
-	file, err := os.Open(symFile)
-	if err != nil {
-		return signals, err
+// generateRandomArgs draws from random, or from the global source if it is nil
+func generateRandomArgs(random *mathrand.Rand, count int) []int {
+	return generateRandomArgsIn(random, count, minRandomArg, maxRandomArg)
+}
+
+// generateRandomArgsIn draws count values in [low, high]
+func generateRandomArgsIn(random *mathrand.Rand, count, low, high int) []int {
+	intn := mathrand.Intn
+	if random != nil {
+		intn = random.Intn
 	}
-	defer file.Close()
+	args := make([]int, count)
+	for i := range args {
+		args[i] = intn(high-low+1) + low
+	}
+	return args
+}
 
-	// Create a new CSV reader
-	reader := csv.NewReader(file)
-	reader.Comma = ',' // Set the delimiter to a comma (default)
+// GenerateArgs returns circom literals for the parameters. Scalars are random,
+// arrays are filled with random values and sized by their literal dimensions
+// or by the value generated for the parameter they refer to.
+func GenerateArgs(params []TemplateParam) []string {
+	return GenerateArgsFrom(nil, params)
+}
 
-	// Read all lines
-	records, err := reader.ReadAll()
-	if err != nil {
-		return signals, err
-	}
+// GenerateArgsFrom is GenerateArgs drawing the values from random, which
+// makes them reproducible for a fixed seed. A nil random uses the global source.
+func GenerateArgsFrom(random *mathrand.Rand, params []TemplateParam) []string {
+	return argLiterals(random, params, generateRandomArgs(random, len(params)))
+}
 
-	// Ensure index 0 has "1"
-	signals = append(signals, "1")
+// argLiterals returns the circom literals of the parameters given the values
+// drawn for them, which are used as is for scalars and to size arrays
+func argLiterals(random *mathrand.Rand, params []TemplateParam, values []int) []string {
+	scalars := make(map[string]int)
+	for i, param := range params {
+		if len(param.Dims) == 0 {
+			scalars[param.Name] = values[i]
+		}
+	}
 
-	// Loop through each record and extract the name (4th column)
-	for _, record := range records {
-		name := record[3] // The 'name' field is the 4th column (index 3)
-		signals = append(signals, name)
+	args := make([]string, len(params))
+	for i, param := range params {
+		if len(param.Dims) == 0 {
+			args[i] = strconv.Itoa(values[i])
+			continue
+		}
+		dims := make([]int, len(param.Dims))
+		for d, dim := range param.Dims {
+			if n, err := strconv.Atoi(dim); err == nil {
+				dims[d] = n
+			} else if n, ok := scalars[dim]; ok {
+				dims[d] = n
+			} else {
+				dims[d] = generateRandomArgs(random, 1)[0]
+			}
+		}
+		args[i] = arrayLiteral(random, dims)
 	}
+	return args
+}
 
-	return signals, nil
+// argSource returns the source of the arguments generated for a template in
+// deterministic mode, seeded by the file and template names so that the
+// values do not depend on the order in which workers pick up templates
+func argSource(filePath, templateName string) *mathrand.Rand {
+	hash := fnv.New64a()
+	hash.Write([]byte(filepath.ToSlash(filePath)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(templateName))
+	return mathrand.New(mathrand.NewSource(int64(hash.Sum64())))
 }
 
-func stringToInt(s string) int64 {
-	var result int64
-	fmt.Sscanf(s, "%d", &result)
-	return result
+func arrayLiteral(random *mathrand.Rand, dims []int) string {
+	if len(dims) == 0 {
+		return strconv.Itoa(generateRandomArgs(random, 1)[0])
+	}
+	elems := make([]string, dims[0])
+	for i := range elems {
+		elems[i] = arrayLiteral(random, dims[1:])
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
 }
@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeCircomUnix stands in for circom on Unix: it prints a version, fails
+// with the message of $FAKE_CIRCOM_FAIL if set, and otherwise copies the
+// fixtures next to the source, its last argument, as circom names its
+// outputs, creating the directories of --wasm and --c as well
+const fakeCircomUnix = `#!/bin/sh
+if [ "$1" = "--version" ]; then
+	echo "circom compiler 2.1.8"
+	exit 0
+fi
+if [ -n "$FAKE_CIRCOM_FAIL" ]; then
+	echo "error[T3001]: $FAKE_CIRCOM_FAIL" >&2
+	exit 1
+fi
+for arg; do
+	case "$arg" in
+	--wasm) wasm=1 ;;
+	--c) c=1 ;;
+	esac
+	source="$arg"
+done
+base="${source%.circom}"
+cp 'CONSTRAINTS' "${base}_constraints.json"
+cp 'SYM' "${base}.sym"
+[ -n "$wasm" ] && mkdir -p "${base}_js"
+[ -n "$c" ] && mkdir -p "${base}_cpp"
+exit 0
+`
+
+// fakeCircomWindows is fakeCircomUnix as a batch script, for Windows
+const fakeCircomWindows = `@echo off
+if "%~1"=="--version" (
+	echo circom compiler 2.1.8
+	exit /b 0
+)
+if defined FAKE_CIRCOM_FAIL (
+	echo error[T3001]: %FAKE_CIRCOM_FAIL% 1>&2
+	exit /b 1
+)
+set wasm=
+set c=
+:next
+if "%~1"=="" goto compile
+if "%~1"=="--wasm" set wasm=1
+if "%~1"=="--c" set c=1
+set source=%~1
+shift
+goto next
+:compile
+for %%f in ("%source%") do set base=%%~dpnf
+copy /y "CONSTRAINTS" "%base%_constraints.json" >nul
+copy /y "SYM" "%base%.sym" >nul
+if defined wasm mkdir "%base%_js"
+if defined c mkdir "%base%_cpp"
+exit /b 0
+`
+
+// writeFakeCircom writes a fake circom serving the square fixture to dir,
+// as a shell script or, on Windows, a batch script named circom.cmd as the
+// npm shim is, and returns its path
+func writeFakeCircom(t *testing.T, dir string) string {
+	t.Helper()
+	constraints, err := filepath.Abs(filepath.Join("testdata", "square_constraints.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sym, err := filepath.Abs(filepath.Join("testdata", "square.sym"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, script := "circom", fakeCircomUnix
+	if runtime.GOOS == "windows" {
+		name, script = "circom.cmd", strings.ReplaceAll(fakeCircomWindows, "\n", "\r\n")
+	}
+	script = strings.NewReplacer("CONSTRAINTS", constraints, "SYM", sym).Replace(script)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFindCircom(t *testing.T) {
+	for _, name := range circomBinaries {
+		t.Run(name, func(t *testing.T) {
+			if runtime.GOOS == "windows" && filepath.Ext(name) == "" {
+				t.Skip("Windows only runs files with an extension of PATHEXT")
+			}
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", dir)
+			path, err := findCircom()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.EqualFold(filepath.Base(path), name) {
+				t.Errorf("found %s, want %s", path, name)
+			}
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		t.Setenv("PATH", t.TempDir())
+		if _, err := findCircom(); !errors.Is(err, ErrCircomNotFound) {
+			t.Errorf("error = %v, want %v", err, ErrCircomNotFound)
+		}
+	})
+}
+
+func TestCircomOutputs(t *testing.T) {
+	tests := []struct {
+		source, constraints, sym, r1cs string
+	}{
+		{"/work/circuit.circom", "/work/circuit_constraints.json", "/work/circuit.sym", "/work/circuit.r1cs"},
+		{"/work/v1.2/circuit.circom", "/work/v1.2/circuit_constraints.json", "/work/v1.2/circuit.sym", "/work/v1.2/circuit.r1cs"},
+		{"/work/circuit.v2.circom", "/work/circuit.v2_constraints.json", "/work/circuit.v2.sym", "/work/circuit.v2.r1cs"},
+		{`C:\Users\dev\circuit.circom`, `C:\Users\dev\circuit_constraints.json`, `C:\Users\dev\circuit.sym`, `C:\Users\dev\circuit.r1cs`},
+		{`C:\My Circuits\circuit.circom`, `C:\My Circuits\circuit_constraints.json`, `C:\My Circuits\circuit.sym`, `C:\My Circuits\circuit.r1cs`},
+	}
+	for _, test := range tests {
+		constraints, sym, r1cs := circomOutputs(test.source)
+		if constraints != test.constraints || sym != test.sym || r1cs != test.r1cs {
+			t.Errorf("circomOutputs(%q) = %q, %q, %q, want %q, %q, %q", test.source, constraints, sym, r1cs, test.constraints, test.sym, test.r1cs)
+		}
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Poseidon", "Poseidon"},
+		{"circuits/hash.circom:Poseidon", "circuits_hash.circom_Poseidon"},
+		{`a<b>c"d\e|f?g*h`, "a_b_c_d_e_f_g_h"},
+		{"tab\there", "tab_here"},
+	}
+	for _, test := range tests {
+		if got := sanitizeFileName(test.name); got != test.want {
+			t.Errorf("sanitizeFileName(%q) = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestCheckCircomInstallation(t *testing.T) {
+	circom := writeFakeCircom(t, t.TempDir())
+	tests := []struct {
+		name    string
+		circom  Circom
+		wantErr bool
+	}{
+		{"recent enough", Circom{Path: circom, MinVersion: DefaultMinCircomVersion}, false},
+		{"any version", Circom{Path: circom}, false},
+		{"too old", Circom{Path: circom, MinVersion: "2.2.0"}, true},
+		{"missing", Circom{Path: filepath.Join(t.TempDir(), "circom")}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := CheckCircomInstallation(test.circom); (err != nil) != test.wantErr {
+				t.Errorf("error = %v, want an error: %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileCircuit(t *testing.T) {
+	dir := t.TempDir()
+	circom := Circom{Path: writeFakeCircom(t, t.TempDir())}
+	source := filepath.Join(dir, "circuit-analyzer_Square_1.circom")
+	if err := os.WriteFile(source, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := CompileCircuit(context.Background(), circom, source, CompileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	constraints, sym, _ := circomOutputs(source)
+	if artifacts.ConstraintsFile != constraints || artifacts.SymFile != sym {
+		t.Errorf("artifacts = %+v, want %s and %s", artifacts, constraints, sym)
+	}
+	for _, file := range []string{constraints, sym} {
+		if _, err := os.Stat(file); err != nil {
+			t.Error(err)
+		}
+	}
+	if !strings.Contains(artifacts.Command, "--O0") || !strings.HasSuffix(artifacts.Command, filepath.Base(source)) {
+		t.Errorf("command = %s", artifacts.Command)
+	}
+}
+
+func TestSetsOptimization(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"--inspect"}, false},
+		{[]string{"--O2"}, true},
+		{[]string{"--O2round", "3"}, true},
+		{[]string{"--O1=x"}, true},
+		{[]string{"--O3"}, false},
+		{[]string{"--wasm", "--O0"}, true},
+	}
+	for _, test := range tests {
+		if got := setsOptimization(test.args); got != test.want {
+			t.Errorf("setsOptimization(%q) = %v, want %v", test.args, got, test.want)
+		}
+	}
+}
+
+func TestCheckCircomArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"--inspect", "--O2round", "3"}, false},
+		{[]string{"--wasm", "--c"}, false},
+		{[]string{"-o", "dir"}, true},
+		{[]string{"--output=dir"}, true},
+		{[]string{"--inspect", "--output", "dir"}, true},
+	}
+	for _, test := range tests {
+		if err := CheckCircomArgs(test.args); (err != nil) != test.wantErr {
+			t.Errorf("CheckCircomArgs(%q) = %v, want an error: %v", test.args, err, test.wantErr)
+		}
+	}
+}
+
+func TestCircomOutputDirs(t *testing.T) {
+	source := filepath.Join("work", "circuit-analyzer_Square_1.circom")
+	want := []string{filepath.Join("work", "circuit-analyzer_Square_1_js"), filepath.Join("work", "circuit-analyzer_Square_1_cpp")}
+	if got := circomOutputDirs(source); !reflect.DeepEqual(got, want) {
+		t.Errorf("circomOutputDirs(%q) = %q, want %q", source, got, want)
+	}
+}
+
+func TestCompileCircuitArgs(t *testing.T) {
+	dir := t.TempDir()
+	circom := Circom{Path: writeFakeCircom(t, t.TempDir())}
+	source := filepath.Join(dir, "circuit-analyzer_Square_1.circom")
+	if err := os.WriteFile(source, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := CompileCircuit(context.Background(), circom, source, CompileOptions{Optimization: "O1", Args: []string{"--O2round", "3", "--wasm", "--c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(artifacts.Command, "--O1") || !strings.Contains(artifacts.Command, "--O2round 3 --wasm --c") {
+		t.Errorf("command = %s, want the passthrough simplification level only", artifacts.Command)
+	}
+	for _, dir := range circomOutputDirs(source) {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			t.Errorf("%s was not created: %v", dir, err)
+		}
+	}
+}
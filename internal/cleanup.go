@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sync"
+)
+
+// workFilePrefix starts the names of the temporary sources of the analysis,
+// telling them apart from user sources such as circom_poseidon_3.circom
+const workFilePrefix = "circuit-analyzer_"
+
+// workFileRegexp matches the temporary sources written next to the analyzed
+// files, e.g. circuit-analyzer_Main_123456.circom, and the outputs compiled
+// from them
+var workFileRegexp = regexp.MustCompile(`^` + regexp.QuoteMeta(workFilePrefix) + `(\w+_)?\d+(\.circom|\.sym|\.r1cs|_constraints\.json)$`)
+
+// legacyWorkFileRegexp matches the temporary sources of older releases, e.g.
+// circom_123456.circom, and their outputs. os.CreateTemp numbers them with
+// a random 32-bit value, so at least six digits tell them apart from user
+// sources such as circom_poseidon_3.circom.
+var legacyWorkFileRegexp = regexp.MustCompile(`^circom_(\w+_)?\d{6,}(\.circom|\.sym|_constraints\.json)$`)
+
+// IsWorkFile reports whether a file name is that of a temporary file of the
+// analysis, this release or an older one, left behind if a run crashed
+func IsWorkFile(name string) bool {
+	return workFileRegexp.MatchString(name) || legacyWorkFileRegexp.MatchString(name)
+}
+
+// liveWork is every work set of the process with files to remove, so that
+// RemoveWorkFiles can remove them on the way out
+var liveWork = struct {
+	sync.Mutex
+	sets map[*workSet]bool
+}{sets: make(map[*workSet]bool)}
+
+// workSet is the files of the analysis of one template: its temporary source
+// and everything compiled from it, including the directories of --wasm or
+// --c. Each analysis has its own, so concurrent analyses of the same source,
+// in this process or another, never remove the files of one another.
+type workSet struct {
+	files []string
+}
+
+// track registers files to remove once the analysis is done, whether or not
+// they are ever created
+func (w *workSet) track(files ...string) {
+	liveWork.Lock()
+	defer liveWork.Unlock()
+	w.files = append(w.files, files...)
+	liveWork.sets[w] = true
+}
+
+// remove deletes every tracked file or directory that exists
+func (w *workSet) remove() {
+	liveWork.Lock()
+	defer liveWork.Unlock()
+	w.removeLocked()
+	delete(liveWork.sets, w)
+}
+
+func (w *workSet) removeLocked() {
+	for _, file := range w.files {
+		if err := os.RemoveAll(file); err != nil {
+			printWarning(fmt.Sprintf("removing %s: %v", file, err))
+		}
+	}
+	w.files = nil
+}
+
+// RemoveWorkFiles removes the temporary files of every analysis still
+// running, for a process about to exit without waiting for them, e.g. on a
+// second Ctrl-C. The analyses fail if they carry on.
+func RemoveWorkFiles() {
+	liveWork.Lock()
+	defer liveWork.Unlock()
+	for w := range liveWork.sets {
+		w.removeLocked()
+		delete(liveWork.sets, w)
+	}
+}
+
+// recovered runs fn, turning a panic into a PanicError and printing its
+// stack. The deferred calls of fn run before, so its work files are removed
+// as well.
+func recovered(fn func() error) (err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			panicErr := &PanicError{Value: value, Stack: debug.Stack()}
+			printWarning(fmt.Sprintf("%v\n%s", panicErr, panicErr.Stack))
+			err = panicErr
+		}
+	}()
+	return fn()
+}
+
+// StaleWorkFiles returns the temporary files of the analysis found below
+// root, left behind by runs that crashed or were killed. Running analyses
+// have such files too, so they are only stale if no analysis is running on
+// the same sources.
+func StaleWorkFiles(root string) ([]string, error) {
+	var stale []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && IsWorkFile(entry.Name()) {
+			stale = append(stale, path)
+		}
+		return nil
+	})
+	return stale, err
+}
@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsWorkFile(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"circuit-analyzer_123456.circom", true},
+		{"circuit-analyzer_Main_123456.circom", true},
+		{"circuit-analyzer_Poseidon_Ex_123456.sym", true},
+		{"circuit-analyzer_Main_123456.r1cs", true},
+		{"circuit-analyzer_Main_123456_constraints.json", true},
+		{"circom_poseidon_3.circom", false},
+		{"circom_123456.circom", true},
+		{"circom_2718281828.sym", true},
+		{"circom_Main_123456_constraints.json", true},
+		{"circom_123456.r1cs", false},
+		{"circom_sha256_512.circom", false},
+		{"circuit-analyzer_Main.circom", false},
+		{"circuit-analyzer_Main_123456.wasm", false},
+		{"main.circom", false},
+	}
+	for _, test := range tests {
+		if got := IsWorkFile(test.name); got != test.want {
+			t.Errorf("IsWorkFile(%q) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestWorkFileNames(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "circom_poseidon_3.circom")
+	if err := os.WriteFile(source, []byte("template T() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, create := range []func() (string, error){
+		func() (string, error) { return CreateTempCircomFile(source) },
+		func() (string, error) { return createTemplateWorkFile(source, "", "T") },
+		func() (string, error) { return createMainWorkFile(source, t.TempDir(), "T") },
+	} {
+		workFile, err := create()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsWorkFile(filepath.Base(workFile)) {
+			t.Errorf("%s is not recognized as a work file", workFile)
+		}
+	}
+}
+
+func TestStaleWorkFiles(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"circom_poseidon_3.circom",
+		"circuit-analyzer_Main_42.circom",
+		filepath.Join("lib", "circuit-analyzer_Main_42_constraints.json"),
+		filepath.Join("lib", "circom_3141592653.circom"),
+		filepath.Join("lib", "hash.circom"),
+	}
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stale, err := StaleWorkFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, files[1]), filepath.Join(root, files[3]), filepath.Join(root, files[2])}
+	if !reflect.DeepEqual(stale, want) {
+		t.Errorf("stale = %v, want %v", stale, want)
+	}
+}
+
+func TestWorkSetRemove(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "circuit-analyzer_Square_1.circom")
+	constraints, sym, r1cs := circomOutputs(source)
+	var work workSet
+	work.track(source, constraints, sym, r1cs)
+	work.track(circomOutputDirs(source)...)
+	for _, file := range []string{source, constraints, sym} {
+		if err := os.WriteFile(file, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, outputDir := range circomOutputDirs(source) {
+		if err := os.MkdirAll(filepath.Join(outputDir, "witness"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	work.remove()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("%s was left behind", entry.Name())
+	}
+}
+
+func TestAnalyzeRemovesOutputDirs(t *testing.T) {
+	workDir := t.TempDir()
+	circom := Circom{Path: writeFakeCircom(t, t.TempDir())}
+	analyzer := NewAnalyzer(Options{Parallelism: 1, Quiet: true, Compiler: LocalCircom{circom}, CircomArgs: []string{"--wasm", "--c"},
+		WorkDir: workDir, OutputDir: t.TempDir()})
+	if err := analyzer.AnalyzeFile(filepath.Join("testdata", "square.circom")); err != nil {
+		t.Fatal(err)
+	}
+	results := analyzer.Wait()
+	if len(results.Templates) != 1 || results.Templates[0].Error != "" {
+		t.Fatalf("results = %+v", results.Templates)
+	}
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("%s was left behind in the work directory", entry.Name())
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// codeClimateSeverities maps the severity of a finding to the CodeClimate scale
+var codeClimateSeverities = map[circuitgraph.Severity]string{
+	circuitgraph.SeverityInfo:     "info",
+	circuitgraph.SeverityLow:      "minor",
+	circuitgraph.SeverityMedium:   "major",
+	circuitgraph.SeverityHigh:     "critical",
+	circuitgraph.SeverityCritical: "blocker",
+}
+
+// codeClimateIssue is an issue in the CodeClimate format read by GitLab's code quality widget
+type codeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+type codeClimateLocation struct {
+	Path  string `json:"path"`
+	Lines struct {
+		Begin int `json:"begin"`
+	} `json:"lines"`
+}
+
+// codeClimateIssues turns the findings and failed templates into CodeClimate
+// issues, located like the diagnostics of WriteDiagnostics
+func codeClimateIssues(results Results) []codeClimateIssue {
+	issues := make([]codeClimateIssue, 0)
+	seen := make(map[string]int)
+	add := func(t TemplateResult, rule, signal, description, severity string) {
+		issue := codeClimateIssue{
+			Type:        "issue",
+			CheckName:   rule,
+			Description: oneLine(description),
+			Categories:  []string{"Bug Risk"},
+			Severity:    severity,
+		}
+		issue.Location.Path = filepath.ToSlash(t.File)
+		issue.Location.Lines.Begin, _ = t.position(signal)
+		issue.Fingerprint = issueFingerprint(issue.Location.Path, t.Template, rule, signal, seen)
+		issues = append(issues, issue)
+	}
+
+	for _, t := range results.Templates {
+		if t.Error != "" {
+			add(t, "analysis-failed", "", t.Error, "critical")
+			continue
+		}
+		if t.Skipped != "" {
+			add(t, "file-skipped", "", "skipped, "+t.Skipped, "info")
+			continue
+		}
+		for _, finding := range t.Findings {
+			severity, ok := codeClimateSeverities[finding.Severity]
+			if !ok {
+				severity = "info"
+			}
+			description := finding.Message
+			if finding.Signal != "" {
+				description = finding.Signal + ": " + description
+			}
+			add(t, finding.Category, finding.Signal, description, severity)
+		}
+	}
+	return issues
+}
+
+// issueFingerprint identifies an issue across runs by what it is about:
+// the file, template, rule and signal name. Messages, signal IDs and
+// generated arguments change between runs of an unchanged circuit and are
+// left out. Issues sharing all four are told apart by their order, counted in seen.
+func issueFingerprint(path, template, rule, signal string, seen map[string]int) string {
+	key := path + "\x00" + template + "\x00" + rule + "\x00" + signal
+	occurrence := seen[key]
+	seen[key]++
+	if occurrence > 0 {
+		key += "\x00" + strconv.Itoa(occurrence)
+	}
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:16])
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// analyzedResult analyzes constraints over the named signals as a template of
+// circuits/chain.circom would be
+func analyzedResult(t *testing.T, constraints circuitgraph.Constraints, signals map[int64]string) TemplateResult {
+	t.Helper()
+	g, err := circuitgraph.BuildGraph(constraints, signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := circuitgraph.AnalyzeGraph(g, constraints, signals, circuitgraph.AnalyzeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return TemplateResult{File: "circuits/chain.circom", Template: "Chain", Stats: analysis.Stats, Findings: analysis.Findings}
+}
+
+// fingerprints returns the sorted fingerprints of the issues of a result
+func fingerprints(result TemplateResult) []string {
+	var prints []string
+	for _, issue := range codeClimateIssues(Results{Templates: []TemplateResult{result}}) {
+		prints = append(prints, issue.Fingerprint)
+	}
+	sort.Strings(prints)
+	return prints
+}
+
+func TestCodeClimateFingerprintStability(t *testing.T) {
+	// a * b = c, c * c = d, and e and f each in a constraint of their own,
+	// leaving dangling signals to report
+	signals := map[int64]string{0: "1", 1: "main.a", 2: "main.b", 3: "main.c", 4: "main.d", 5: "main.e", 6: "main.f"}
+	constraints := circuitgraph.Constraints{
+		{{1}, {2}, {3}},
+		{{3}, {3}, {4}},
+		{{0}, {5}, {}},
+		{{0}, {6}, {}},
+	}
+	want := fingerprints(analyzedResult(t, constraints, signals))
+	if len(want) == 0 {
+		t.Fatal("the fixture has no findings")
+	}
+
+	// The same circuit with its constraints reversed, and the witness
+	// indices a recompilation might assign instead
+	renumbered := map[int64]int64{0: 0, 1: 6, 2: 5, 3: 4, 4: 3, 5: 2, 6: 1}
+	renamed := make(map[int64]string, len(signals))
+	for id, name := range signals {
+		renamed[renumbered[id]] = name
+	}
+	var reordered circuitgraph.Constraints
+	for i := len(constraints) - 1; i >= 0; i-- {
+		var constraint [3][]int64
+		for j, expression := range constraints[i] {
+			for _, id := range expression {
+				constraint[j] = append(constraint[j], renumbered[id])
+			}
+		}
+		reordered = append(reordered, constraint)
+	}
+
+	got := fingerprints(analyzedResult(t, reordered, renamed))
+	if len(got) != len(want) {
+		t.Fatalf("got %d issues, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("fingerprints = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestCodeClimateFingerprintsUnique(t *testing.T) {
+	// Two findings of the same rule on the same signal still get distinct
+	// fingerprints, told apart by their order
+	finding := circuitgraph.Finding{Category: circuitgraph.CategoryUnderconstrained, Severity: circuitgraph.SeverityHigh, Signal: "main.x"}
+	result := TemplateResult{File: "a.circom", Template: "A", Findings: []circuitgraph.Finding{finding, finding}}
+	prints := fingerprints(result)
+	if len(prints) != 2 || prints[0] == prints[1] {
+		t.Errorf("fingerprints = %v, want two distinct ones", prints)
+	}
+
+	other := result
+	other.Template = "B"
+	if fingerprints(other)[0] == prints[0] || fingerprints(other)[0] == prints[1] {
+		t.Error("the fingerprints of another template collide")
+	}
+}
@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// TemplateDiff is the structure of one template as compiled by two compilers
+type TemplateDiff struct {
+	File     string
+	Template string
+	Old, New circuitgraph.Stats
+	OldError string // Set if the template failed with the first compiler
+	NewError string // Set if the template failed with the second compiler
+}
+
+// Changed reports whether the node, edge or constraint counts differ, or the
+// template only compiled with one of the compilers
+func (d TemplateDiff) Changed() bool {
+	if d.OldError != "" || d.NewError != "" {
+		return (d.OldError == "") != (d.NewError == "")
+	}
+	return d.Old.Signals != d.New.Signals || d.Old.Edges != d.New.Edges || d.Old.Constraints != d.New.Constraints
+}
+
+// FixedMainComponents returns the main component every analyzed template was
+// compiled with, so a second run compiles them with the same arguments. They
+// are keyed by file and name, as templates of different files may share a name.
+func FixedMainComponents(results Results) map[string]string {
+	mainComponents := make(map[string]string)
+	for _, t := range results.Templates {
+		if t.Template != "" && t.MainComponent != "" {
+			mainComponents[qualifiedName(t.File, t.Template)] = t.MainComponent
+		}
+	}
+	return mainComponents
+}
+
+// CompareResults pairs the templates of two runs by file and template name.
+// Templates missing from one run are reported as failed in it.
+func CompareResults(before, after Results) []TemplateDiff {
+	type key struct{ file, template string }
+	var diffs []TemplateDiff
+	index := make(map[key]int)
+	for _, t := range before.Templates {
+		index[key{t.File, t.Template}] = len(diffs)
+		diffs = append(diffs, TemplateDiff{File: t.File, Template: t.Template, Old: t.Stats, OldError: t.Error, NewError: "not analyzed"})
+	}
+	for _, t := range after.Templates {
+		i, ok := index[key{t.File, t.Template}]
+		if !ok {
+			diffs = append(diffs, TemplateDiff{File: t.File, Template: t.Template, New: t.Stats, OldError: "not analyzed", NewError: t.Error})
+			continue
+		}
+		diffs[i].New = t.Stats
+		diffs[i].NewError = t.Error
+	}
+	return diffs
+}
+
+// WriteComparison prints one row per template whose structure changed, or
+// that only compiles with one of the compilers as its status tells, and
+// returns the number of such templates
+func WriteComparison(w io.Writer, diffs []TemplateDiff) (int, error) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tTEMPLATE\tNODES\tEDGES\tCONSTRAINTS\tSTATUS")
+	changed := 0
+	for _, d := range diffs {
+		if !d.Changed() {
+			continue
+		}
+		changed++
+		if d.OldError != "" || d.NewError != "" {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t%s\n", d.File, d.Template, failedSide(d))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\tchanged\n", d.File, d.Template, countChange(d.Old.Signals, d.New.Signals),
+			countChange(d.Old.Edges, d.New.Edges), countChange(d.Old.Constraints, d.New.Constraints))
+	}
+	return changed, tw.Flush()
+}
+
+func countChange(before, after int) string {
+	if before == after {
+		return fmt.Sprint(before)
+	}
+	return fmt.Sprintf("%d -> %d (%+d)", before, after, after-before)
+}
+
+func failedSide(d TemplateDiff) string {
+	if d.OldError != "" {
+		return "only compiles with the second compiler"
+	}
+	return "only compiles with the first compiler"
+}
+
+// graphSignals returns the names of the signals in the graph, the "1" signal excluded
+func graphSignals(g *circuitgraph.CircuitGraph) map[string]bool {
+	signals := make(map[string]bool)
+	nodes := g.Nodes()
+	for nodes.Next() {
+		if node := nodes.Node().(*circuitgraph.NamedNode); !node.Synthetic() && node.ID() != 0 {
+			signals[node.Name] = true
+		}
+	}
+	return signals
+}
+
+// SignalDiff is how the signals of a template changed between two compilations, matched by name
+type SignalDiff struct {
+	Removed []string // Signals only in the first graph, e.g. eliminated by simplification
+	Added   []string // Signals only in the second graph
+	// Underconstrained signals of the first graph that are gone from the
+	// second, which often means they never mattered
+	RemovedUnderconstrained []string
+}
+
+// CompareSignals matches the graph signals of two runs of a template by
+// name. Both runs must have been analyzed with KeepSignals.
+func CompareSignals(before, after TemplateResult) SignalDiff {
+	var diff SignalDiff
+	for signal := range before.signals {
+		if !after.signals[signal] {
+			diff.Removed = append(diff.Removed, signal)
+		}
+	}
+	for signal := range after.signals {
+		if !before.signals[signal] {
+			diff.Added = append(diff.Added, signal)
+		}
+	}
+	for _, finding := range before.Findings {
+		if finding.Category == circuitgraph.CategoryUnderconstrained && !after.signals[finding.Signal] {
+			diff.RemovedUnderconstrained = append(diff.RemovedUnderconstrained, finding.Signal)
+		}
+	}
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Added)
+	sort.Strings(diff.RemovedUnderconstrained)
+	return diff
+}
+
+// maxListedSignals limits the signals listed per template in WriteSignalDiff
+const maxListedSignals = 20
+
+// WriteSignalDiff prints how the graph of a template changed between two compilations
+func WriteSignalDiff(w io.Writer, d TemplateDiff, signals SignalDiff) {
+	fmt.Fprintf(w, "\n%s in %s: %s signals, %s constraints, %s components\n", d.Template, d.File,
+		countChange(d.Old.Signals, d.New.Signals), countChange(d.Old.Constraints, d.New.Constraints),
+		countChange(d.Old.Components, d.New.Components))
+	listSignals(w, "Eliminated", signals.Removed)
+	listSignals(w, "Only in the second graph", signals.Added)
+	listSignals(w, "Underconstrained signals that disappear", signals.RemovedUnderconstrained)
+}
+
+func listSignals(w io.Writer, label string, signals []string) {
+	if len(signals) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  %s (%d):", label, len(signals))
+	for i, signal := range signals {
+		if i == maxListedSignals {
+			fmt.Fprintf(w, " ... and %d more", len(signals)-maxListedSignals)
+			break
+		}
+		fmt.Fprintf(w, " %s", signal)
+	}
+	fmt.Fprintln(w)
+}
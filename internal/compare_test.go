@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+func TestWriteComparison(t *testing.T) {
+	stats := func(signals, edges, constraints int) circuitgraph.Stats {
+		return circuitgraph.Stats{Signals: signals, Edges: edges, Constraints: constraints}
+	}
+	diffs := []TemplateDiff{
+		{File: "a.circom", Template: "Same", Old: stats(3, 3, 1), New: stats(3, 3, 1)},
+		{File: "a.circom", Template: "Grown", Old: stats(3, 3, 1), New: stats(4, 5, 2)},
+		{File: "b.circom", Template: "New", OldError: "compile error", New: stats(3, 3, 1)},
+		{File: "b.circom", Template: "Old", Old: stats(3, 3, 1), NewError: "not analyzed"},
+		{File: "c.circom", Template: "Broken", OldError: "compile error", NewError: "compile error"},
+	}
+	var out strings.Builder
+	changed, err := WriteComparison(&out, diffs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 3 {
+		t.Errorf("changed = %d, want 3", changed)
+	}
+
+	// Cells are padded by at least two spaces, and hold no more than one in a row
+	columns := regexp.MustCompile(`\s{2,}`)
+	want := [][]string{
+		{"FILE", "TEMPLATE", "NODES", "EDGES", "CONSTRAINTS", "STATUS"},
+		{"a.circom", "Grown", "3 -> 4 (+1)", "3 -> 5 (+2)", "1 -> 2 (+1)", "changed"},
+		{"b.circom", "New", "-", "-", "-", "only compiles with the second compiler"},
+		{"b.circom", "Old", "-", "-", "-", "only compiles with the first compiler"},
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), out.String())
+	}
+	for i, line := range lines {
+		if cells := columns.Split(strings.TrimSpace(line), -1); strings.Join(cells, "|") != strings.Join(want[i], "|") {
+			t.Errorf("line %d = %q, want the cells %q", i, cells, want[i])
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+// CompileOptions are the per-compilation settings passed to a Compiler
+type CompileOptions struct {
+	Libraries    []string // Additional include paths, passed to circom with -l
+	Optimization string   // circom simplification level, O0, O1 or O2, O0 if empty, unless Args sets one
+	Args         []string // Flags passed to circom verbatim before the source, e.g. --inspect
+}
+
+// Artifacts are the compiler outputs the analysis reads
+type Artifacts struct {
+	ConstraintsFile string // Constraints as written by circom --json
+	SymFile         string // Signal names as written by circom --sym
+	R1CSFile        string // Constraint system as written by circom --r1cs, for the visibility of the inputs, empty if not available
+	Command         string // Command line that produced the artifacts, for reproduction
+}
+
+// Compiler turns a circom source with a main component into constraint and
+// symbol files. The artifacts are owned by the caller, who removes them.
+type Compiler interface {
+	Compile(ctx context.Context, sourcePath string, options CompileOptions) (Artifacts, error)
+}
+
+// LocalCircom compiles with the circom binary, or Docker image, described by Circom
+type LocalCircom struct {
+	Circom Circom
+}
+
+func (l LocalCircom) Compile(ctx context.Context, sourcePath string, options CompileOptions) (Artifacts, error) {
+	return CompileCircuit(ctx, l.Circom, sourcePath, options)
+}
+
+// Observer is told about the stages of every template analyzed, e.g. to
+// export metrics. Workers call it concurrently.
+type Observer interface {
+	TemplateStarted(file, template string)
+	CompileFinished(template string, elapsed time.Duration, err error)
+	TemplateFinished(result TemplateResult) // Also for failed templates, and files failing before their templates are read, with Error set
+}
+
+// observers notifies several observers in turn
+type observers []Observer
+
+func (o observers) TemplateStarted(file, template string) {
+	for _, observer := range o {
+		observer.TemplateStarted(file, template)
+	}
+}
+
+func (o observers) CompileFinished(template string, elapsed time.Duration, err error) {
+	for _, observer := range o {
+		observer.CompileFinished(template, elapsed, err)
+	}
+}
+
+func (o observers) TemplateFinished(result TemplateResult) {
+	for _, observer := range o {
+		observer.TemplateFinished(result)
+	}
+}
+
+// MultiObserver returns an Observer notifying each given one in turn, nil if
+// there are none
+func MultiObserver(list ...Observer) Observer {
+	switch len(list) {
+	case 0:
+		return nil
+	case 1:
+		return list[0]
+	default:
+		return observers(list)
+	}
+}
@@ -0,0 +1,71 @@
+// Package compilertest provides a Compiler returning canned circom outputs, so
+// the analysis pipeline can be exercised without circom installed.
+package compilertest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Artifex1/circuit-graph-analysis/internal"
+)
+
+// FakeCompiler writes the canned outputs next to the source, named the way
+// circom names them, for every compilation
+type FakeCompiler struct {
+	Constraints []byte // Content of the _constraints.json output
+	Sym         []byte // Content of the .sym output
+	Err         error  // Returned instead of compiling, if set
+
+	mu      sync.Mutex
+	sources []string
+}
+
+// FromFiles creates a FakeCompiler returning the content of the given fixtures
+func FromFiles(constraintsFile, symFile string) (*FakeCompiler, error) {
+	constraints, err := os.ReadFile(constraintsFile)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := os.ReadFile(symFile)
+	if err != nil {
+		return nil, err
+	}
+	return &FakeCompiler{Constraints: constraints, Sym: sym}, nil
+}
+
+func (f *FakeCompiler) Compile(ctx context.Context, sourcePath string, options internal.CompileOptions) (internal.Artifacts, error) {
+	f.mu.Lock()
+	f.sources = append(f.sources, sourcePath)
+	f.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return internal.Artifacts{}, err
+	}
+	if f.Err != nil {
+		return internal.Artifacts{}, f.Err
+	}
+
+	base := strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath))
+	artifacts := internal.Artifacts{
+		ConstraintsFile: base + "_constraints.json",
+		SymFile:         base + ".sym",
+	}
+	if err := os.WriteFile(artifacts.ConstraintsFile, f.Constraints, 0644); err != nil {
+		return internal.Artifacts{}, err
+	}
+	if err := os.WriteFile(artifacts.SymFile, f.Sym, 0644); err != nil {
+		os.Remove(artifacts.ConstraintsFile)
+		return internal.Artifacts{}, err
+	}
+	return artifacts, nil
+}
+
+// Sources returns the source paths compiled so far
+func (f *FakeCompiler) Sources() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sources...)
+}
@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// CategoryUnexpectedMetric is reported for templates whose stats differ from
+// those their configuration expects
+const CategoryUnexpectedMetric = "unexpected-metric"
+
+// Config is the content of a -config file: settings per template, on top of
+// the command-line flags that apply to every template
+type Config struct {
+	Templates map[string]TemplateConfig `json:"templates"` // By template name
+}
+
+// maxArgAttempts bounds the random arguments drawn for a template until they
+// satisfy its argument rules
+const maxArgAttempts = 1000
+
+// TemplateConfig are the settings of a single template. Unset fields keep the
+// setting of the command line.
+type TemplateConfig struct {
+	Args     []string      `json:"args,omitempty"`      // Arguments of the main component, generated if empty
+	Public   []string      `json:"public,omitempty"`    // Input signals the main component declares public
+	ArgRange []int         `json:"arg_range,omitempty"` // Lowest and highest generated argument, [2, 15] if unset
+	ArgRules []string      `json:"arg_rules,omitempty"` // Relations the arguments must satisfy, e.g. "n > k" or "arg0 >= 1"
+	Checks   *Checks       `json:"checks,omitempty"`
+	Expect   *Expectations `json:"expect,omitempty"`
+
+	rules []argRule
+}
+
+// argRule is a comparison between two arguments, or an argument and a number
+type argRule struct {
+	text        string
+	left, right string // Parameter names, argN for the Nth argument, or integers
+	op          string
+}
+
+var argRuleRegexp = regexp.MustCompile(`^\s*(-?\w+)\s*(<=|>=|==|!=|<|>)\s*(-?\w+)\s*$`)
+
+// parseArgRule parses a rule of the form "left op right"
+func parseArgRule(text string) (argRule, error) {
+	match := argRuleRegexp.FindStringSubmatch(text)
+	if match == nil {
+		return argRule{}, fmt.Errorf("argument rule %q is not of the form a < b, with <, <=, >, >=, == or !=", text)
+	}
+	return argRule{text: text, left: match[1], op: match[2], right: match[3]}, nil
+}
+
+// holds reports whether the argument values satisfy the rule
+func (r argRule) holds(params []TemplateParam, values []string) (bool, error) {
+	left, err := r.operand(r.left, params, values)
+	if err != nil {
+		return false, err
+	}
+	right, err := r.operand(r.right, params, values)
+	if err != nil {
+		return false, err
+	}
+	switch r.op {
+	case "<":
+		return left < right, nil
+	case "<=":
+		return left <= right, nil
+	case ">":
+		return left > right, nil
+	case ">=":
+		return left >= right, nil
+	case "==":
+		return left == right, nil
+	default:
+		return left != right, nil
+	}
+}
+
+// operand returns the value of a number, a parameter name or argN
+func (r argRule) operand(name string, params []TemplateParam, values []string) (int, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		return n, nil
+	}
+	index := -1
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, "arg")); err == nil && strings.HasPrefix(name, "arg") {
+		index = n
+	}
+	for i, param := range params {
+		if param.Name == name {
+			index = i
+		}
+	}
+	if index < 0 || index >= len(values) {
+		return 0, fmt.Errorf("argument rule %q: the template has no argument %s", r.text, name)
+	}
+	if index < len(params) && len(params[index].Dims) > 0 {
+		return 0, fmt.Errorf("argument rule %q: argument %s is an array", r.text, name)
+	}
+	n, err := strconv.Atoi(values[index])
+	if err != nil {
+		return 0, fmt.Errorf("argument rule %q: argument %s is %s, not an integer", r.text, name, values[index])
+	}
+	return n, nil
+}
+
+// Checks selects the analyses run on a template, like the flags of the same name
+type Checks struct {
+	Quick           *bool    `json:"quick,omitempty"`
+	HotSpots        *int     `json:"hot_spots,omitempty"`
+	HubThreshold    *float64 `json:"hub_threshold,omitempty"`
+	PinnedThreshold *float64 `json:"pinned_threshold,omitempty"`
+	Patterns        *bool    `json:"patterns,omitempty"`
+	Coloring        *bool    `json:"coloring,omitempty"`
+}
+
+// Expectations are the metrics a template must have, each one differing is
+// reported as an unexpected-metric finding
+type Expectations struct {
+	Constraints *int `json:"constraints,omitempty"`
+	Signals     *int `json:"signals,omitempty"`
+	Edges       *int `json:"edges,omitempty"`
+	Components  *int `json:"components,omitempty"`
+	MaxFindings *int `json:"max_findings,omitempty"` // Findings of the other checks, at most
+}
+
+// LoadConfig reads a JSON -config file. Unknown fields are rejected, so that
+// a misspelled setting cannot be silently ignored.
+func LoadConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return config, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	for name, template := range config.Templates {
+		if len(template.Public) > 0 && len(template.Args) == 0 {
+			return config, fmt.Errorf("reading config %s: template %s declares public signals without args", path, name)
+		}
+		if template.ArgRange != nil && (len(template.ArgRange) != 2 || template.ArgRange[0] > template.ArgRange[1]) {
+			return config, fmt.Errorf("reading config %s: the arg_range of template %s is not [lowest, highest]", path, name)
+		}
+		for _, text := range template.ArgRules {
+			rule, err := parseArgRule(text)
+			if err != nil {
+				return config, fmt.Errorf("reading config %s: template %s: %w", path, name, err)
+			}
+			template.rules = append(template.rules, rule)
+		}
+		config.Templates[name] = template
+	}
+	return config, nil
+}
+
+// mainComponent returns the main component instantiating the template with
+// the configured arguments and public signals
+func (c TemplateConfig) mainComponent(templateName string) string {
+	if len(c.Public) == 0 {
+		return MainComponent(templateName, c.Args)
+	}
+	return fmt.Sprintf("component main {public [%s]} = %s(%s);", strings.Join(c.Public, ", "), templateName, strings.Join(c.Args, ", "))
+}
+
+// brokenRule returns the first argument rule the arguments break, if any
+func (c TemplateConfig) brokenRule(params []TemplateParam, args []string) (string, error) {
+	for _, rule := range c.rules {
+		ok, err := rule.holds(params, args)
+		if err != nil || !ok {
+			return rule.text, err
+		}
+	}
+	return "", nil
+}
+
+// checkArgs returns an error naming the first argument rule the arguments break
+func (c TemplateConfig) checkArgs(params []TemplateParam, args []string) error {
+	broken, err := c.brokenRule(params, args)
+	if err != nil {
+		return err
+	}
+	if broken != "" {
+		return fmt.Errorf("arguments (%s) break the argument rule %q", strings.Join(args, ", "), broken)
+	}
+	return nil
+}
+
+// generateArgs draws arguments in the configured range until they satisfy
+// the argument rules, and gives up after maxArgAttempts draws
+func (c TemplateConfig) generateArgs(random *mathrand.Rand, params []TemplateParam) ([]string, error) {
+	low, high := minRandomArg, maxRandomArg
+	if len(c.ArgRange) == 2 {
+		low, high = c.ArgRange[0], c.ArgRange[1]
+	}
+	for attempt := 0; attempt < maxArgAttempts; attempt++ {
+		values := generateRandomArgsIn(random, len(params), low, high)
+		scalars := make([]string, len(values))
+		for i, value := range values {
+			scalars[i] = strconv.Itoa(value)
+		}
+		broken, err := c.brokenRule(params, scalars)
+		if err != nil {
+			return nil, err
+		}
+		if broken == "" {
+			return argLiterals(random, params, values), nil
+		}
+	}
+	rules := make([]string, len(c.rules))
+	for i, rule := range c.rules {
+		rules[i] = rule.text
+	}
+	return nil, fmt.Errorf("no arguments in [%d, %d] satisfy the argument rules %s after %d attempts", low, high, strings.Join(rules, ", "), maxArgAttempts)
+}
+
+// apply returns the options with the checks overridden
+func (c *Checks) apply(options Options) Options {
+	if c == nil {
+		return options
+	}
+	if c.Quick != nil {
+		options.Quick = *c.Quick
+	}
+	if c.HotSpots != nil {
+		options.HotSpots = *c.HotSpots
+	}
+	if c.HubThreshold != nil {
+		options.HubThreshold = *c.HubThreshold
+	}
+	if c.PinnedThreshold != nil {
+		options.PinnedThreshold = *c.PinnedThreshold
+	}
+	if c.Patterns != nil {
+		options.Patterns = *c.Patterns
+	}
+	if c.Coloring != nil {
+		options.Coloring = *c.Coloring
+	}
+	return options
+}
+
+// check reports every expected metric the stats or findings differ from
+func (e *Expectations) check(stats circuitgraph.Stats, findings int) []circuitgraph.Finding {
+	if e == nil {
+		return nil
+	}
+	var unexpected []circuitgraph.Finding
+	expect := func(metric string, expected *int, actual int) {
+		if expected != nil && *expected != actual {
+			unexpected = append(unexpected, circuitgraph.Finding{
+				Category: CategoryUnexpectedMetric,
+				Severity: circuitgraph.SeverityMedium,
+				Message:  fmt.Sprintf("expected %d %s, got %d", *expected, metric, actual),
+			})
+		}
+	}
+	expect("constraints", e.Constraints, stats.Constraints)
+	expect("signals", e.Signals, stats.Signals)
+	expect("edges", e.Edges, stats.Edges)
+	expect("components", e.Components, stats.Components)
+	if e.MaxFindings != nil && findings > *e.MaxFindings {
+		unexpected = append(unexpected, circuitgraph.Finding{
+			Category: CategoryUnexpectedMetric,
+			Severity: circuitgraph.SeverityMedium,
+			Message:  fmt.Sprintf("expected at most %d findings, got %d", *e.MaxFindings, findings),
+		})
+	}
+	return unexpected
+}
@@ -0,0 +1,241 @@
+package internal
+
+import (
+	mathrand "math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// writeConfig writes a -config file with the given content and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := writeConfig(t, `{"templates": {
+		"Square": {"args": ["3"], "public": ["x"], "checks": {"quick": true, "hot_spots": 5}, "expect": {"constraints": 1}},
+		"Cube": {}
+	}}`)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	square, ok := config.Templates["Square"]
+	if !ok {
+		t.Fatalf("templates = %v, want Square", config.Templates)
+	}
+	if !reflect.DeepEqual(square.Args, []string{"3"}) || !reflect.DeepEqual(square.Public, []string{"x"}) {
+		t.Errorf("Square = %+v", square)
+	}
+	if want := "component main {public [x]} = Square(3);"; square.mainComponent("Square") != want {
+		t.Errorf("main component = %s, want %s", square.mainComponent("Square"), want)
+	}
+	if cube := config.Templates["Cube"]; cube.Checks != nil || cube.Expect != nil {
+		t.Errorf("Cube = %+v, want no checks or expectations", cube)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", `{"templates": {"Square": {"argz": ["3"]}}}`},
+		{"public without args", `{"templates": {"Square": {"public": ["x"]}}}`},
+		{"invalid json", `{"templates": `},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := LoadConfig(writeConfig(t, test.content)); err == nil {
+				t.Error("loaded without error")
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := LoadConfig(filepath.Join(t.TempDir(), "config.json")); err == nil {
+			t.Error("loaded without error")
+		}
+	})
+}
+
+func TestChecksApply(t *testing.T) {
+	quick, hotSpots, patterns := true, 7, false
+	options := Options{HotSpots: 3, Patterns: true, HubThreshold: 0.5}
+	got := (&Checks{Quick: &quick, HotSpots: &hotSpots, Patterns: &patterns}).apply(options)
+	want := Options{Quick: true, HotSpots: 7, Patterns: false, HubThreshold: 0.5}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apply = %+v, want %+v", got, want)
+	}
+
+	var unset *Checks
+	if got := unset.apply(options); !reflect.DeepEqual(got, options) {
+		t.Errorf("nil checks changed the options to %+v", got)
+	}
+}
+
+func TestExpectationsCheck(t *testing.T) {
+	constraints, signals, maxFindings := 2, 3, 1
+	expect := &Expectations{Constraints: &constraints, Signals: &signals, MaxFindings: &maxFindings}
+	tests := []struct {
+		name     string
+		stats    circuitgraph.Stats
+		findings int
+		want     []string
+	}{
+		{"as expected", circuitgraph.Stats{Constraints: 2, Signals: 3}, 1, nil},
+		{"other constraints", circuitgraph.Stats{Constraints: 4, Signals: 3}, 0, []string{"expected 2 constraints, got 4"}},
+		{"too many findings", circuitgraph.Stats{Constraints: 2, Signals: 3}, 2, []string{"expected at most 1 findings, got 2"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, finding := range expect.check(test.stats, test.findings) {
+				if finding.Category != CategoryUnexpectedMetric {
+					t.Errorf("category = %s, want %s", finding.Category, CategoryUnexpectedMetric)
+				}
+				got = append(got, finding.Message)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("findings = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseArgRule(t *testing.T) {
+	tests := []struct {
+		text            string
+		left, op, right string
+		wantErr         bool
+	}{
+		{text: "n > k", left: "n", op: ">", right: "k"},
+		{text: "arg0>=1", left: "arg0", op: ">=", right: "1"},
+		{text: " k != -1 ", left: "k", op: "!=", right: "-1"},
+		{text: "n => k", wantErr: true},
+		{text: "n > k + 1", wantErr: true},
+		{text: "n", wantErr: true},
+	}
+	for _, test := range tests {
+		rule, err := parseArgRule(test.text)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseArgRule(%q) error = %v, want an error: %v", test.text, err, test.wantErr)
+			continue
+		}
+		if err == nil && (rule.left != test.left || rule.op != test.op || rule.right != test.right) {
+			t.Errorf("parseArgRule(%q) = %s %s %s, want %s %s %s", test.text, rule.left, rule.op, rule.right, test.left, test.op, test.right)
+		}
+	}
+}
+
+func TestArgRuleHolds(t *testing.T) {
+	params := []TemplateParam{{Name: "n"}, {Name: "k"}, {Name: "coeffs", Dims: []string{"n"}}}
+	values := []string{"4", "2", "[1, 2, 3, 4]"}
+	tests := []struct {
+		text    string
+		want    bool
+		wantErr bool
+	}{
+		{text: "n > k", want: true},
+		{text: "n < k", want: false},
+		{text: "k <= 2", want: true},
+		{text: "arg1 >= arg0", want: false},
+		{text: "arg0 == 4", want: true},
+		{text: "n != 4", want: false},
+		{text: "m > 1", wantErr: true},
+		{text: "arg3 > 1", wantErr: true},
+		{text: "coeffs > 1", wantErr: true},
+	}
+	for _, test := range tests {
+		rule, err := parseArgRule(test.text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := rule.holds(params, values)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: error = %v, want an error: %v", test.text, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q holds = %v, want %v", test.text, got, test.want)
+		}
+	}
+
+	rule, err := parseArgRule("n > 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rule.holds(params[:1], []string{"x"}); err == nil {
+		t.Error("a non-integer argument satisfied the rule")
+	}
+}
+
+func TestLoadConfigArgRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"valid", `{"templates": {"Scale": {"arg_range": [1, 8], "arg_rules": ["n > k", "k >= 1"]}}}`, false},
+		{"malformed rule", `{"templates": {"Scale": {"arg_rules": ["n >> k"]}}}`, true},
+		{"reversed range", `{"templates": {"Scale": {"arg_range": [8, 1]}}}`, true},
+		{"short range", `{"templates": {"Scale": {"arg_range": [1]}}}`, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config, err := LoadConfig(writeConfig(t, test.content))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error = %v, want an error: %v", err, test.wantErr)
+			}
+			if err == nil && len(config.Templates["Scale"].rules) != 2 {
+				t.Errorf("parsed %d rules, want 2", len(config.Templates["Scale"].rules))
+			}
+		})
+	}
+}
+
+func TestGenerateArgs(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, `{"templates": {"Scale": {"arg_range": [1, 6], "arg_rules": ["n > k", "k >= 2"]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scale := config.Templates["Scale"]
+	params := []TemplateParam{{Name: "n"}, {Name: "k"}}
+	random := mathrand.New(mathrand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		args, err := scale.generateArgs(random, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := scale.checkArgs(params, args); err != nil {
+			t.Error(err)
+		}
+		for _, arg := range args {
+			if n, err := strconv.Atoi(arg); err != nil || n < 1 || n > 6 {
+				t.Errorf("argument %s is not in [1, 6]", arg)
+			}
+		}
+	}
+
+	if err := scale.checkArgs(params, []string{"2", "3"}); err == nil {
+		t.Error("arguments (2, 3) satisfied n > k")
+	}
+
+	impossible, err := LoadConfig(writeConfig(t, `{"templates": {"Scale": {"arg_rules": ["n > k", "k > n"]}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := impossible.Templates["Scale"].generateArgs(random, params); err == nil {
+		t.Error("generated arguments satisfying n > k and k > n")
+	}
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// maxCooccurrenceSignals limits the selection, the matrix grows with its square
+const maxCooccurrenceSignals = 128
+
+// Cooccurrence counts the constraints mentioning each pair of selected signals
+type Cooccurrence struct {
+	Signals []string // Selected signals in declaration order
+	Counts  [][]int  // Counts[i][j] constraints mention both, Counts[i][i] those mentioning signal i
+}
+
+// cooccurrence selects the signals whose name matches pattern, in declaration
+// order, and counts how many constraints mention each pair of them
+func cooccurrence(constraints circuitgraph.Constraints, signals map[int64]string, pattern string) (Cooccurrence, error) {
+	var ids []int64
+	for id, name := range signals {
+		if matchGlob(pattern, name) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > maxCooccurrenceSignals {
+		return Cooccurrence{}, fmt.Errorf("%d signals match %q, select at most %d", len(ids), pattern, maxCooccurrenceSignals)
+	}
+	// circom numbers signals in declaration order, which keeps arrays in index order
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	index := make(map[int64]int, len(ids))
+	result := Cooccurrence{Signals: make([]string, len(ids)), Counts: make([][]int, len(ids))}
+	for i, id := range ids {
+		index[id] = i
+		result.Signals[i] = signals[id]
+		result.Counts[i] = make([]int, len(ids))
+	}
+
+	for _, constraint := range constraints {
+		selected := make(map[int]struct{})
+		for _, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				if i, ok := index[signal]; ok {
+					selected[i] = struct{}{}
+				}
+			}
+		}
+		for i := range selected {
+			for j := range selected {
+				result.Counts[i][j]++
+			}
+		}
+	}
+	return result, nil
+}
+
+// writeCooccurrence exports the matrix as CSV, with the signal names as header row and column
+func writeCooccurrence(dir string, matrix Cooccurrence, templateName string) error {
+	fileName := outputFile(dir, fmt.Sprintf("%s_cooccurrence.csv", templateName))
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	writer.Write(append([]string{"signal"}, matrix.Signals...))
+	for i, row := range matrix.Counts {
+		record := []string{matrix.Signals[i]}
+		for _, count := range row {
+			record = append(record, strconv.Itoa(count))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func visualizeCooccurrence(dir string, matrix Cooccurrence, templateName string) error {
+	heatMap := charts.NewHeatMap()
+	maxCount := 0
+	var data []opts.HeatMapData
+	for i, row := range matrix.Counts {
+		for j, count := range row {
+			data = append(data, opts.HeatMapData{Value: [3]interface{}{j, i, count}})
+			maxCount = max(maxCount, count)
+		}
+	}
+	heatMap.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{ChartID: chartID("cooccurrence", templateName)}),
+		charts.WithTitleOpts(opts.Title{Title: "Signal Co-occurrence: " + templateName}),
+		charts.WithYAxisOpts(opts.YAxis{Type: "category", Data: matrix.Signals}),
+		charts.WithVisualMapOpts(opts.VisualMap{Min: 0, Max: float32(maxCount), InRange: &opts.VisualMapInRange{Color: []string{"#ffffff", "#d94e5d"}}}),
+		charts.WithTooltipOpts(opts.Tooltip{}),
+	)
+	heatMap.SetXAxis(matrix.Signals).AddSeries("constraints", data)
+
+	fileName := outputFile(dir, fmt.Sprintf("%s_cooccurrence.html", templateName))
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return heatMap.Render(f)
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// diagnosticSeverities maps the severity of a finding to the levels editors
+// and their problem matchers know
+var diagnosticSeverities = map[circuitgraph.Severity]string{
+	circuitgraph.SeverityInfo:     "note",
+	circuitgraph.SeverityLow:      "warning",
+	circuitgraph.SeverityMedium:   "warning",
+	circuitgraph.SeverityHigh:     "error",
+	circuitgraph.SeverityCritical: "error",
+}
+
+// WriteDiagnostics prints one path:line:col: severity: message [rule] line
+// per finding, the format of compiler errors that vim, VS Code problem
+// matchers and most other editors parse. A finding on a signal the template
+// declares points at the declaration, any other finding at the template.
+// Failed templates are reported as errors with the rule analysis-failed,
+// skipped files as notes with the rule file-skipped.
+func WriteDiagnostics(w io.Writer, results Results) error {
+	for _, t := range results.Templates {
+		if t.Error != "" {
+			line, column := t.position("")
+			if _, err := fmt.Fprintf(w, "%s:%d:%d: error: %s [analysis-failed]\n", t.File, line, column, oneLine(t.Error)); err != nil {
+				return err
+			}
+			continue
+		}
+		if t.Skipped != "" {
+			if _, err := fmt.Fprintf(w, "%s:1:1: note: skipped, %s [file-skipped]\n", t.File, t.Skipped); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, finding := range t.Findings {
+			line, column := t.position(finding.Signal)
+			severity, ok := diagnosticSeverities[finding.Severity]
+			if !ok {
+				severity = string(finding.Severity)
+			}
+			message := finding.Message
+			if finding.Signal != "" {
+				message = finding.Signal + ": " + message
+			}
+			if _, err := fmt.Fprintf(w, "%s:%d:%d: %s: %s [%s]\n", t.File, line, column, severity, oneLine(message), finding.Category); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// position returns the line and column declaring a signal of the main
+// component, e.g. main.in[2], or the template for other signals. Results
+// loaded from a file know neither and point at the start of it.
+func (t TemplateResult) position(signal string) (int, int) {
+	if t.source == nil || t.source.Line == 0 {
+		return 1, 1
+	}
+	if name, ok := strings.CutPrefix(signal, "main."); ok {
+		if bracket := strings.Index(name, "["); bracket >= 0 {
+			name = name[:bracket]
+		}
+		if line, ok := t.source.Lines[name]; ok && !strings.Contains(name, ".") {
+			return line, t.source.Columns[name]
+		}
+	}
+	return t.source.Line, t.source.Column
+}
+
+// oneLine joins the lines of a message, a diagnostic must fit on one
+func oneLine(message string) string {
+	return strings.Join(strings.Fields(message), " ")
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+func TestWriteDiagnostics(t *testing.T) {
+	declared := TemplateResult{
+		File:     "circuits/square.circom",
+		Template: "Square",
+		Findings: []circuitgraph.Finding{
+			{Category: circuitgraph.CategoryUnderconstrained, Severity: circuitgraph.SeverityHigh, Signal: "main.in[2]", Message: "signal has a single\nneighbor"},
+			{Category: circuitgraph.CategoryUnderconstrained, Severity: circuitgraph.SeverityLow, Signal: "main.sub.x", Message: "unused"},
+			{Category: "custom", Severity: "unknown", Message: "no signal"},
+		},
+		source: &TemplateInfo{
+			Line: 3, Column: 1,
+			Lines:   map[string]int{"in": 4},
+			Columns: map[string]int{"in": 15},
+		},
+	}
+	results := Results{Templates: []TemplateResult{
+		declared,
+		sampleResults().Templates[1],
+		{File: "circuits/broken.circom", Template: "Broken", Error: "error[T3001]: Non quadratic constraints\nare not allowed!"},
+		{File: "circuits/huge.circom", Skipped: "larger than 1 MiB"},
+	}}
+	var out strings.Builder
+	if err := WriteDiagnostics(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	want := `circuits/square.circom:4:15: error: main.in[2]: signal has a single neighbor [underconstrained-signal]
+circuits/square.circom:3:1: warning: main.sub.x: unused [underconstrained-signal]
+circuits/square.circom:3:1: unknown: no signal [custom]
+circuits/cube.circom:1:1: warning: main.y: [underconstrained-signal]
+circuits/broken.circom:1:1: error: error[T3001]: Non quadratic constraints are not allowed! [analysis-failed]
+circuits/huge.circom:1:1: note: skipped, larger than 1 MiB [file-skipped]
+`
+	if out.String() != want {
+		t.Errorf("diagnostics =\n%s\nwant\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// DirectorySummary totals the results of the files below one top-level
+// directory of the input
+type DirectorySummary struct {
+	Directory string  `json:"directory"` // "." for the files right in the input directory
+	Files     int     `json:"files"`
+	Templates int     `json:"templates"`
+	Findings  int     `json:"findings"`
+	Failures  int     `json:"failures"`
+	Seconds   float64 `json:"seconds,omitempty"` // Time spent compiling and analyzing the templates
+}
+
+// DirectorySummaries groups the results by the top-level directory of their
+// path, most findings first, then by name. There are none if the results
+// carry no path relative to the input directory.
+func DirectorySummaries(results Results) []DirectorySummary {
+	index := make(map[string]int)
+	files := make(map[string]bool)
+	var summaries []DirectorySummary
+	for _, t := range results.Templates {
+		if t.Path == "" {
+			continue
+		}
+		directory := "."
+		if top, _, nested := strings.Cut(t.Path, "/"); nested {
+			directory = top
+		}
+		i, ok := index[directory]
+		if !ok {
+			i = len(summaries)
+			index[directory] = i
+			summaries = append(summaries, DirectorySummary{Directory: directory})
+		}
+		summary := &summaries[i]
+		if !files[t.Path] {
+			files[t.Path] = true
+			summary.Files++
+		}
+		if t.Error != "" {
+			summary.Failures++
+		}
+		if t.Template != "" {
+			summary.Templates++
+		}
+		summary.Findings += t.FindingCount()
+		summary.Seconds += t.Seconds
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Findings != summaries[j].Findings {
+			return summaries[i].Findings > summaries[j].Findings
+		}
+		return summaries[i].Directory < summaries[j].Directory
+	})
+	return summaries
+}
+
+// WriteDirectorySummaries prints one aligned row per directory
+func WriteDirectorySummaries(w io.Writer, summaries []DirectorySummary) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DIRECTORY\tFILES\tTEMPLATES\tFINDINGS\tFAILURES\tSECONDS")
+	for _, s := range summaries {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.1f\n", s.Directory, s.Files, s.Templates, s.Findings, s.Failures, s.Seconds)
+	}
+	return tw.Flush()
+}
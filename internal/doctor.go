@@ -0,0 +1,173 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// Circuit compiled by the doctor to exercise the whole pipeline
+const doctorCircuit = `pragma circom 2.0.0;
+
+template Doctor() {
+    signal input a;
+    signal input b;
+    signal output c;
+    c <== a * b;
+}
+`
+
+type doctorCheck struct {
+	name     string
+	required bool
+	hint     string
+	needs    string // Check that must pass for this one to mean anything, skipped otherwise
+	run      func() (string, error)
+}
+
+// Doctor diagnoses the environment the analyzer runs in, including the library
+// directories passed to circom with -l. It prints one line per check and
+// returns false if any required check failed.
+func Doctor(c Circom, outputDir, inputPath string, libraries []string) bool {
+	checks := []doctorCheck{
+		{
+			name:     "circom",
+			required: true,
+			hint:     "install circom (https://docs.circom.io/getting-started/installation/) or point -circom-path at it",
+			run: func() (string, error) {
+				if err := CheckCircomInstallation(c); err != nil {
+					return "", err
+				}
+				version, _ := CircomVersion(c)
+				return fmt.Sprintf("%s (%s)", c, version), nil
+			},
+		},
+		{
+			name:     "output directory",
+			required: true,
+			hint:     "run from a writable directory",
+			run: func() (string, error) {
+				return outputDir, checkWritable(outputDir)
+			},
+		},
+		{
+			name:     "trial compilation",
+			required: true,
+			hint:     "check that circom can write next to its input and produces --json/--sym output",
+			needs:    "circom",
+			run:      func() (string, error) { return trialCompile(c) },
+		},
+	}
+	if inputPath != "" {
+		checks = append(checks, doctorCheck{
+			name:     "input",
+			required: true,
+			hint:     "point -input at a .circom file or a directory containing some",
+			run: func() (string, error) {
+				files, _, err := GetCircomFiles(inputPath, WalkOptions{})
+				if err != nil {
+					return "", err
+				}
+				if len(files) == 0 {
+					return "", fmt.Errorf("no .circom files found in %s", inputPath)
+				}
+				return fmt.Sprintf("%d .circom files in %s", len(files), inputPath), nil
+			},
+		})
+	}
+	for _, library := range libraries {
+		checks = append(checks, doctorCheck{
+			name:     "library " + library,
+			required: true,
+			hint:     "point -l at a directory of included .circom files, e.g. node_modules/circomlib/circuits",
+			run: func() (string, error) {
+				info, err := os.Stat(library)
+				if err != nil {
+					return "", err
+				}
+				if !info.IsDir() {
+					return "", fmt.Errorf("%s is not a directory", library)
+				}
+				if _, err := os.ReadDir(library); err != nil {
+					return "", err
+				}
+				return library, nil
+			},
+		})
+	}
+	for _, tool := range []string{"snarkjs", "docker", "git"} {
+		checks = append(checks, doctorCheck{
+			name: tool,
+			hint: fmt.Sprintf("optional, install %s if you need it", tool),
+			run:  func() (string, error) { return exec.LookPath(tool) },
+		})
+	}
+
+	healthy := true
+	failed := make(map[string]bool)
+	for _, check := range checks {
+		if check.needs != "" && failed[check.needs] {
+			fmt.Printf("[SKIP] %s: the %s check failed\n", check.name, check.needs)
+			continue
+		}
+		detail, err := check.run()
+		switch {
+		case err == nil:
+			fmt.Printf("[PASS] %s: %s\n", check.name, detail)
+		case check.required:
+			healthy = false
+			failed[check.name] = true
+			fmt.Printf("[FAIL] %s: %v\n       hint: %s\n", check.name, err, check.hint)
+		default:
+			fmt.Printf("[WARN] %s: %v\n       hint: %s\n", check.name, err, check.hint)
+		}
+	}
+	return healthy
+}
+
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".circuit-analyzer-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// trialCompile runs the embedded circuit through the same steps as a real analysis
+func trialCompile(c Circom) (string, error) {
+	dir, err := os.MkdirTemp("", "circuit-analyzer-doctor-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	circuit := filepath.Join(dir, "doctor.circom")
+	if err := os.WriteFile(circuit, []byte(doctorCircuit), 0644); err != nil {
+		return "", err
+	}
+
+	tempFile, err := CreateTempCircomFile(circuit)
+	if err != nil {
+		return "", err
+	}
+	if err := AddMainComponent(tempFile, "Doctor", nil); err != nil {
+		return "", err
+	}
+	artifacts, err := CompileCircuit(context.Background(), c, tempFile, CompileOptions{})
+	if err != nil {
+		return "", err
+	}
+	constraints, err := circuitgraph.LoadFromJson(artifacts.ConstraintsFile, circuitgraph.ParseOptions{Strict: true})
+	if err != nil {
+		return "", err
+	}
+	if len(constraints) == 0 {
+		return "", fmt.Errorf("compiled circuit has no constraints")
+	}
+	return fmt.Sprintf("%d constraint(s)", len(constraints)), nil
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DuplicateTemplates returns the files declaring every template name that
+// more than one of the files declares, in the order of the files. Files that
+// cannot be read are left to the analysis to report.
+func DuplicateTemplates(files []string) map[string][]string {
+	declaring := make(map[string][]string)
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		templates, err := extractTemplates(file)
+		file.Close()
+		if err != nil {
+			continue
+		}
+		for _, template := range templates {
+			if list := declaring[template.Name]; len(list) == 0 || list[len(list)-1] != path {
+				declaring[template.Name] = append(list, path)
+			}
+		}
+	}
+	for name, list := range declaring {
+		if len(list) < 2 {
+			delete(declaring, name)
+		}
+	}
+	return declaring
+}
+
+// WriteDuplicateTemplates prints a note per template name declared by
+// several files, whose outputs are told apart by their file
+func WriteDuplicateTemplates(w io.Writer, duplicates map[string][]string) {
+	names := make([]string, 0, len(duplicates))
+	for name := range duplicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "Note: template %s is declared in %d files (%s), its outputs are named %s@<file>. A circuit including more than one of them fails to compile.\n",
+			name, len(duplicates[name]), strings.Join(duplicates[name], ", "), name)
+	}
+}
+
+// qualifiedName identifies a template across files, as the key of a main
+// component or of a result
+func qualifiedName(file, template string) string {
+	return file + ":" + template
+}
+
+// entryPointOutput is the output name of a template compiled from the main
+// component of another file, e.g. Top@circuit for circuit.circom
+func entryPointOutput(result TemplateResult) string {
+	if result.Path != "" {
+		return qualifiedOutput(result)
+	}
+	return result.Template + "@" + strings.TrimSuffix(filepath.Base(result.File), filepath.Ext(result.File))
+}
+
+// qualifiedOutput is the output name of a template sharing its name with a
+// template of another file, e.g. Main@circuits/rollup/main, which writes
+// Main@circuits_rollup_main_circuit_graph.html and so on
+func qualifiedOutput(result TemplateResult) string {
+	path := result.Path
+	if path == "" {
+		path = filepath.ToSlash(result.File)
+	}
+	return result.Template + "@" + strings.TrimSuffix(path, filepath.Ext(path))
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCircomNotFound is returned when no circom binary (or Docker) can be found
+var ErrCircomNotFound = errors.New("circom is not installed or not in PATH")
+
+// ErrAnalyzerBusy is returned when a file is queued while Wait is collecting the previous run
+var ErrAnalyzerBusy = errors.New("analyzer is finishing a run, call AnalyzeFile after Wait returns")
+
+// CompileError is returned when circom fails to compile a generated circuit
+type CompileError struct {
+	Template string   // Template the main component instantiates, if known
+	Args     []string // Arguments of the main component, if known
+	Command  string   // Command line the compiler was run with
+	Stderr   string   // Output of the compiler, with the locations in the work file mapped to the source
+	Err      error
+
+	// The compiler blamed the main component appended to the source for the
+	// analysis, which the user did not write
+	InMain bool
+}
+
+func (e *CompileError) Error() string {
+	var b strings.Builder
+	b.WriteString("compilation failed")
+	if e.Template != "" {
+		fmt.Fprintf(&b, " for %s(%s)", e.Template, strings.Join(e.Args, ", "))
+	}
+	if e.InMain {
+		fmt.Fprintf(&b, ": error in %s with args (%s)", generatedMainLocation, strings.Join(e.Args, ", "))
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	if e.Stderr != "" {
+		fmt.Fprintf(&b, ": %s", e.Stderr)
+	}
+	return b.String()
+}
+
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
+
+// InterruptedError is returned for a template or file whose analysis was
+// stopped by its context, e.g. by Ctrl-C or a time budget
+type InterruptedError struct {
+	Stage string // What was running, e.g. compile
+	Err   error  // The error of the context
+}
+
+func (e *InterruptedError) Error() string {
+	return fmt.Sprintf("%s interrupted: %v", e.Stage, e.Err)
+}
+
+func (e *InterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is returned for a template or file whose analysis panicked,
+// which is a bug of the tool rather than of the circuit
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// exitError is a circom process that exited with a non-zero code
+type exitError struct {
+	code   int
+	stderr string
+}
+
+func (e *exitError) Error() string {
+	if e.stderr == "" {
+		return fmt.Sprintf("exit code %d", e.code)
+	}
+	return fmt.Sprintf("exit code %d: %s", e.code, e.stderr)
+}
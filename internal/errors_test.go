@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// cannedCompiler writes the given outputs next to the source, nil ones
+// falling back to the square fixture
+type cannedCompiler struct {
+	constraints, sym []byte
+}
+
+func (c cannedCompiler) Compile(ctx context.Context, sourcePath string, options CompileOptions) (Artifacts, error) {
+	constraints, sym, _ := circomOutputs(sourcePath)
+	for file, content := range map[string][]byte{constraints: c.constraints, sym: c.sym} {
+		if content == nil {
+			fixture := "square_constraints.json"
+			if file == sym {
+				fixture = "square.sym"
+			}
+			var err error
+			if content, err = os.ReadFile(filepath.Join("testdata", fixture)); err != nil {
+				return Artifacts{}, err
+			}
+		}
+		if err := os.WriteFile(file, content, 0o644); err != nil {
+			return Artifacts{}, err
+		}
+	}
+	return Artifacts{ConstraintsFile: constraints, SymFile: sym}, nil
+}
+
+func TestAnalysisErrors(t *testing.T) {
+	circom := writeFakeCircom(t, t.TempDir())
+	tests := []struct {
+		name     string
+		compiler Compiler
+		strict   bool
+		fail     string // Message of the fake circom failing, if set
+		check    func(err error) bool
+	}{
+		{
+			name:     "circom not found",
+			compiler: LocalCircom{Circom{Path: filepath.Join(t.TempDir(), "circom")}},
+			check:    func(err error) bool { return errors.Is(err, ErrCircomNotFound) },
+		},
+		{
+			name:     "compile error",
+			compiler: LocalCircom{Circom{Path: circom}},
+			fail:     "Non quadratic constraints are not allowed!",
+			check: func(err error) bool {
+				var compileErr *CompileError
+				return errors.As(err, &compileErr) && compileErr.Template == "Square" &&
+					strings.Contains(compileErr.Stderr, "Non quadratic") && compileErr.Command != ""
+			},
+		},
+		{
+			name:     "malformed constraints",
+			compiler: cannedCompiler{constraints: []byte(`{"constraints": [[{"1": "1"}, x]]}`)},
+			check: func(err error) bool {
+				var parseErr *circuitgraph.ParseError
+				return errors.As(err, &parseErr) && strings.HasSuffix(parseErr.File, "_constraints.json") && parseErr.Offset > 0
+			},
+		},
+		{
+			name:     "malformed sym in strict mode",
+			compiler: cannedCompiler{sym: []byte("1,one,0,main.y\n2,2,0,main.x\n")},
+			strict:   true,
+			check: func(err error) bool {
+				var parseErr *circuitgraph.ParseError
+				return errors.As(err, &parseErr) && strings.HasSuffix(parseErr.File, ".sym")
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("FAKE_CIRCOM_FAIL", test.fail)
+			analyzer := NewAnalyzer(Options{Parallelism: 1, Quiet: true, Compiler: test.compiler, Strict: test.strict, WorkDir: t.TempDir(), OutputDir: t.TempDir()})
+			if err := analyzer.AnalyzeFile(filepath.Join("testdata", "square.circom")); err != nil {
+				t.Fatal(err)
+			}
+			results := analyzer.Wait()
+
+			if len(results.Templates) != 1 {
+				t.Fatalf("got %d results, want 1", len(results.Templates))
+			}
+			if err := results.Templates[0].Err(); !test.check(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
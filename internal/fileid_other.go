@@ -0,0 +1,23 @@
+//go:build !unix
+
+package internal
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Without device and inode numbers, directories are identified by their resolved path
+type fileKey struct {
+	path string
+}
+
+func fileKeyOf(path string, info os.FileInfo) fileKey {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return fileKey{path: path}
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package internal
+
+import (
+	"os"
+	"syscall"
+)
+
+type fileKey struct {
+	dev uint64
+	ino uint64
+}
+
+func fileKeyOf(path string, info os.FileInfo) fileKey {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+	}
+	return fileKey{}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// templateGroup holds the findings that are not about a particular signal
+const templateGroup = "(template)"
+
+// FindingGroup is the findings on the signals below one top-level component
+type FindingGroup struct {
+	Prefix   string
+	Findings []circuitgraph.Finding
+}
+
+// signalGroup returns the top-level component of a signal, e.g. main.hasher
+// for main.hasher.out[0]. Signals of the main component itself, and names
+// without a component path, form their own group.
+func signalGroup(signal string) string {
+	if signal == "" {
+		return templateGroup
+	}
+	first := strings.IndexByte(signal, '.')
+	if first < 0 {
+		return signal
+	}
+	second := strings.IndexByte(signal[first+1:], '.')
+	if second < 0 {
+		return signal[:first]
+	}
+	return signal[:first+1+second]
+}
+
+// GroupFindings buckets findings by the top-level component of their signal,
+// largest group first
+func GroupFindings(findings []circuitgraph.Finding) []FindingGroup {
+	index := make(map[string]int)
+	var groups []FindingGroup
+	for _, finding := range findings {
+		prefix := signalGroup(finding.Signal)
+		i, ok := index[prefix]
+		if !ok {
+			i = len(groups)
+			index[prefix] = i
+			groups = append(groups, FindingGroup{Prefix: prefix})
+		}
+		groups[i].Findings = append(groups[i].Findings, finding)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Findings) != len(groups[j].Findings) {
+			return len(groups[i].Findings) > len(groups[j].Findings)
+		}
+		return groups[i].Prefix < groups[j].Prefix
+	})
+	return groups
+}
+
+func printFindingGroups(w io.Writer, findings []circuitgraph.Finding) {
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Findings by component:")
+	for _, group := range GroupFindings(findings) {
+		fmt.Fprintf(w, "  %s: %d finding(s)\n", group.Prefix, len(group.Findings))
+		for _, finding := range group.Findings {
+			fmt.Fprintf(w, "    - [%s] %s", finding.Severity, finding.Category)
+			if finding.Signal != "" {
+				fmt.Fprintf(w, " %s", finding.Signal)
+			}
+			fmt.Fprintf(w, ": %s\n", finding.Message)
+		}
+	}
+}
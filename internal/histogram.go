@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// DegreeBucket counts the signals sharing one degree
+type DegreeBucket struct {
+	Degree int `json:"degree"`
+	Count  int `json:"count"`
+}
+
+// degreeHistogram returns the degree distribution of the signals ordered by degree
+func degreeHistogram(g *circuitgraph.CircuitGraph) []DegreeBucket {
+	counts := make(map[int]int)
+	nodes := g.Nodes()
+	for nodes.Next() {
+		if node := nodes.Node().(*circuitgraph.NamedNode); !node.Synthetic() {
+			counts[g.SignalDegree(node.ID())]++
+		}
+	}
+
+	histogram := make([]DegreeBucket, 0, len(counts))
+	for degree, count := range counts {
+		histogram = append(histogram, DegreeBucket{Degree: degree, Count: count})
+	}
+	sort.Slice(histogram, func(i, j int) bool {
+		return histogram[i].Degree < histogram[j].Degree
+	})
+	return histogram
+}
+
+// writeDegreeHistogram exports the histogram as JSON or CSV
+func writeDegreeHistogram(dir string, histogram []DegreeBucket, templateName, format string) error {
+	fileName := outputFile(dir, fmt.Sprintf("%s_degree_histogram.%s", templateName, format))
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(histogram)
+	case "csv":
+		writer := csv.NewWriter(f)
+		writer.Write([]string{"degree", "count"})
+		for _, bucket := range histogram {
+			writer.Write([]string{strconv.Itoa(bucket.Degree), strconv.Itoa(bucket.Count)})
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unknown histogram format %q", format)
+	}
+}
+
+// SignalDegree is the degree of a signal relative to the other signals of its template
+type SignalDegree struct {
+	Signal     string  `json:"signal"`
+	Degree     int     `json:"degree"`
+	Percentile float64 `json:"percentile"` // Share of signals with a lower degree, counting ties half, in percent
+	ZScore     float64 `json:"z_score"`    // Standard deviations from the mean degree, 0 if all degrees are equal
+}
+
+// signalDegrees returns the degree of every signal ordered by name, placed
+// within the degree distribution of the template
+func signalDegrees(g *circuitgraph.CircuitGraph) []SignalDegree {
+	var degrees []SignalDegree
+	nodes := g.Nodes()
+	for nodes.Next() {
+		if node := nodes.Node().(*circuitgraph.NamedNode); !node.Synthetic() {
+			degrees = append(degrees, SignalDegree{Signal: node.Name, Degree: g.SignalDegree(node.ID())})
+		}
+	}
+	rankDegrees(degrees)
+	sort.Slice(degrees, func(i, j int) bool { return degrees[i].Signal < degrees[j].Signal })
+	return degrees
+}
+
+// rankDegrees fills in the percentile and z-score of every signal
+func rankDegrees(degrees []SignalDegree) {
+	n := float64(len(degrees))
+	counts := make(map[int]int)
+	mean := 0.0
+	for _, d := range degrees {
+		counts[d.Degree]++
+		mean += float64(d.Degree)
+	}
+	mean /= n
+	variance := 0.0
+	for _, d := range degrees {
+		variance += (float64(d.Degree) - mean) * (float64(d.Degree) - mean)
+	}
+	std := math.Sqrt(variance / n)
+
+	// Signals below each degree, from the distribution in ascending order
+	below := make(map[int]int, len(counts))
+	distinct := make([]int, 0, len(counts))
+	for degree := range counts {
+		distinct = append(distinct, degree)
+	}
+	sort.Ints(distinct)
+	seen := 0
+	for _, degree := range distinct {
+		below[degree] = seen
+		seen += counts[degree]
+	}
+
+	for i := range degrees {
+		d := &degrees[i]
+		d.Percentile = 100 * (float64(below[d.Degree]) + 0.5*float64(counts[d.Degree])) / n
+		if std > 0 {
+			d.ZScore = (float64(d.Degree) - mean) / std
+		}
+	}
+}
+
+// writeSignalDegrees exports the per-signal degrees as JSON or CSV
+func writeSignalDegrees(dir string, degrees []SignalDegree, templateName, format string) error {
+	fileName := outputFile(dir, fmt.Sprintf("%s_signal_degrees.%s", templateName, format))
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(degrees)
+	case "csv":
+		writer := csv.NewWriter(f)
+		writer.Write([]string{"signal", "degree", "percentile", "z_score"})
+		for _, d := range degrees {
+			writer.Write([]string{d.Signal, strconv.Itoa(d.Degree),
+				strconv.FormatFloat(d.Percentile, 'f', 2, 64), strconv.FormatFloat(d.ZScore, 'f', 3, 64)})
+		}
+		writer.Flush()
+		return writer.Error()
+	default:
+		return fmt.Errorf("unknown signal degree format %q", format)
+	}
+}
+
+func visualizeDegreeHistogram(dir string, histogram []DegreeBucket, templateName string) error {
+	bar := charts.NewBar()
+	bar.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{ChartID: chartID("degree-histogram", templateName)}),
+		charts.WithTitleOpts(opts.Title{Title: "Degree Distribution: " + templateName}),
+		charts.WithXAxisOpts(opts.XAxis{Name: "degree"}),
+		charts.WithYAxisOpts(opts.YAxis{Name: "signals"}),
+	)
+
+	degrees := make([]string, len(histogram))
+	counts := make([]opts.BarData, len(histogram))
+	for i, bucket := range histogram {
+		degrees[i] = strconv.Itoa(bucket.Degree)
+		counts[i] = opts.BarData{Value: bucket.Count}
+	}
+	bar.SetXAxis(degrees).AddSeries("signals", counts)
+
+	fileName := outputFile(dir, fmt.Sprintf("%s_degree_histogram.html", templateName))
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return bar.Render(f)
+}
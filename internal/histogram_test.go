@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRankDegrees(t *testing.T) {
+	tests := []struct {
+		name        string
+		degrees     []int
+		percentiles []float64
+		zScores     []float64
+	}{
+		{
+			// Mean 5 and standard deviation 2
+			name:        "textbook distribution",
+			degrees:     []int{2, 4, 4, 4, 5, 5, 7, 9},
+			percentiles: []float64{6.25, 31.25, 31.25, 31.25, 62.5, 62.5, 81.25, 93.75},
+			zScores:     []float64{-1.5, -0.5, -0.5, -0.5, 0, 0, 1, 2},
+		},
+		{
+			name:        "equal degrees",
+			degrees:     []int{3, 3, 3},
+			percentiles: []float64{50, 50, 50},
+			zScores:     []float64{0, 0, 0},
+		},
+		{
+			name:        "single signal",
+			degrees:     []int{1},
+			percentiles: []float64{50},
+			zScores:     []float64{0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			degrees := make([]SignalDegree, len(test.degrees))
+			for i, degree := range test.degrees {
+				degrees[i].Degree = degree
+			}
+			rankDegrees(degrees)
+			for i, d := range degrees {
+				if math.Abs(d.Percentile-test.percentiles[i]) > 1e-9 || math.Abs(d.ZScore-test.zScores[i]) > 1e-9 {
+					t.Errorf("degree %d: percentile %g and z-score %g, want %g and %g", d.Degree, d.Percentile, d.ZScore, test.percentiles[i], test.zScores[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWriteSignalDegreesCSV(t *testing.T) {
+	dir := t.TempDir()
+	degrees := []SignalDegree{{Signal: "main.x", Degree: 2, Percentile: 6.25, ZScore: -1.5}, {Signal: "main.y", Degree: 9, Percentile: 93.75, ZScore: 2}}
+	if err := writeSignalDegrees(dir, degrees, "Square", "csv"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "Square_signal_degrees.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "signal,degree,percentile,z_score\nmain.x,2,6.25,-1.500\nmain.y,9,93.75,2.000\n"
+	if string(content) != want {
+		t.Errorf("got\n%s\nwant\n%s", content, want)
+	}
+	if err := writeSignalDegrees(dir, degrees, "Square", "xml"); err == nil || !strings.Contains(err.Error(), "xml") {
+		t.Errorf("error = %v for an unknown format", err)
+	}
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// postProcess pipes the result of a template as JSON to the hook command, a
+// program and its arguments separated by spaces. If the hook prints JSON, it
+// replaces the result. A hook exiting non-zero is recorded in HookExit, a hook
+// that cannot run or prints something else than a result leaves it unchanged.
+func postProcess(ctx context.Context, hook string, result *TemplateResult) error {
+	args := strings.Fields(hook)
+	if len(args) == 0 {
+		return errors.New("the hook command is empty")
+	}
+	input, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return err
+	}
+
+	if output := bytes.TrimSpace(stdout.Bytes()); len(output) > 0 {
+		// Fields the hook leaves out are dropped, e.g. findings it removed all of
+		var transformed TemplateResult
+		if err := json.Unmarshal(output, &transformed); err != nil {
+			return fmt.Errorf("hook output is not a template result: %v", err)
+		}
+		if transformed.Error != "" {
+			transformed.err = result.err
+		}
+		transformed.graph, transformed.families, transformed.signals = result.graph, result.families, result.signals
+		transformed.random, transformed.source = result.random, result.source
+		*result = transformed
+	}
+	if exitErr != nil {
+		result.HookExit = exitErr.ExitCode()
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("hook exited with code %d: %s", result.HookExit, message)
+		}
+		return fmt.Errorf("hook exited with code %d", result.HookExit)
+	}
+	return nil
+}
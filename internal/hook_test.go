@@ -0,0 +1,89 @@
+package internal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestPostProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks are shell scripts")
+	}
+	dir := t.TempDir()
+	hook := func(name, script string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("#!/bin/sh\ncat >/dev/null\n"+script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	original := sampleResults().Templates[0]
+	// The hook drops the finding by leaving out the findings
+	replaced := TemplateResult{File: original.File, Template: "Square", Stats: original.Stats}
+
+	tests := []struct {
+		name     string
+		hook     string
+		want     TemplateResult
+		wantErr  string // Part of the error, none if empty
+		hookExit int
+	}{
+		{
+			name: "replaced result",
+			hook: hook("replace", `echo '{"file": "circuits/square.circom", "template": "Square", "stats": {"constraints": 1, "signals": 2}}'`),
+			want: replaced,
+		},
+		{
+			name: "silent hook",
+			hook: hook("silent", ""),
+			want: original,
+		},
+		{
+			name:     "non-zero exit",
+			hook:     hook("fail", "echo 'policy violated' >&2\nexit 3"),
+			want:     original,
+			wantErr:  "hook exited with code 3: policy violated",
+			hookExit: 3,
+		},
+		{
+			name:    "non-JSON output",
+			hook:    hook("chatty", "echo done"),
+			want:    original,
+			wantErr: "hook output is not a template result",
+		},
+		{
+			name:    "blank command",
+			hook:    " ",
+			want:    original,
+			wantErr: "the hook command is empty",
+		},
+		{
+			name:    "missing program",
+			hook:    filepath.Join(dir, "missing"),
+			want:    original,
+			wantErr: "no such file",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := sampleResults().Templates[0]
+			err := postProcess(context.Background(), test.hook, &result)
+			if test.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if test.wantErr != "" && (err == nil || !strings.Contains(err.Error(), test.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, test.wantErr)
+			}
+			want := test.want
+			want.HookExit = test.hookExit
+			if !reflect.DeepEqual(result, want) {
+				t.Errorf("result = %+v, want %+v", result, want)
+			}
+		})
+	}
+}
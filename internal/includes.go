@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// vendoredDirs are the directory names holding copies of third-party circuits
+var vendoredDirs = map[string]bool{"node_modules": true, "vendor": true, "third_party": true}
+
+var includeRegexp = regexp.MustCompile(`^\s*include\s+"([^"]+)"\s*;`)
+
+// ScopeOptions widens the files ScopeFiles keeps
+type ScopeOptions struct {
+	Includes  bool     // Keep the files only ever included by other files
+	Vendored  bool     // Keep the files below vendored directories, e.g. node_modules
+	Libraries []string // Directories includes are also resolved against, as with circom -l
+	Root      string   // Input directory, whose own path does not count as vendored
+}
+
+// Scope is the files of an input worth analyzing on their own
+type Scope struct {
+	Files       []string // Files to analyze, in the order they were found
+	IncludeOnly []string // Files left out as another of the files includes them
+	Vendored    []string // Files left out as they are below a vendored directory
+	Templates   int      // Templates declared by the files left out
+}
+
+// ScopeFiles leaves out of files those below a vendored directory and those
+// another of the files includes, whose templates are analyzed as part of the
+// circuits including them, unless options keep them. Files that cannot be read
+// are kept, for the analysis to report.
+func ScopeFiles(files []string, options ScopeOptions) Scope {
+	known := make(map[string]bool, len(files))
+	for _, file := range files {
+		if abs, err := filepath.Abs(file); err == nil {
+			known[abs] = true
+		}
+	}
+	included := make(map[string]bool)
+	for _, file := range files {
+		includes, err := readIncludes(file)
+		if err != nil {
+			continue
+		}
+		for _, include := range includes {
+			if target := resolveInclude(file, include, options.Libraries, known); target != "" {
+				included[target] = true
+			}
+		}
+	}
+
+	var scope Scope
+	for _, file := range files {
+		abs, _ := filepath.Abs(file)
+		switch {
+		case !options.Vendored && vendored(file, options.Root):
+			scope.Vendored = append(scope.Vendored, file)
+		case !options.Includes && included[abs]:
+			scope.IncludeOnly = append(scope.IncludeOnly, file)
+		default:
+			scope.Files = append(scope.Files, file)
+			continue
+		}
+		scope.Templates += countTemplates(file)
+	}
+	return scope
+}
+
+// readIncludes returns the paths a circom file includes, as written
+func readIncludes(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var includes []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if match := includeRegexp.FindStringSubmatch(scanner.Text()); match != nil {
+			includes = append(includes, match[1])
+		}
+	}
+	return includes, scanner.Err()
+}
+
+// resolveInclude returns the absolute path of one of the known files an
+// include of file refers to, looked up next to file and then in the
+// libraries like circom does, empty if it is none of them
+func resolveInclude(file, include string, libraries []string, known map[string]bool) string {
+	dirs := append([]string{filepath.Dir(file)}, libraries...)
+	for _, dir := range dirs {
+		target, err := filepath.Abs(filepath.Join(dir, include))
+		if err == nil && known[target] {
+			return target
+		}
+	}
+	return ""
+}
+
+// vendored reports whether a file is below a vendored directory of root
+func vendored(file, root string) bool {
+	if root != "" {
+		if rel, err := filepath.Rel(root, file); err == nil {
+			file = rel
+		}
+	}
+	for _, dir := range strings.Split(filepath.ToSlash(filepath.Dir(file)), "/") {
+		if vendoredDirs[dir] {
+			return true
+		}
+	}
+	return false
+}
+
+// countTemplates returns the number of templates a file declares, 0 if it
+// cannot be read
+func countTemplates(path string) int {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	templates, _ := extractTemplates(file)
+	return len(templates)
+}
+
+// WriteScope notes the files the scope left out, if any
+func WriteScope(w io.Writer, scope Scope) {
+	var files, flags []string
+	if len(scope.IncludeOnly) > 0 {
+		files = append(files, fmt.Sprintf("%d file(s) only included by other files", len(scope.IncludeOnly)))
+		flags = append(flags, "-analyze-includes")
+	}
+	if len(scope.Vendored) > 0 {
+		files = append(files, fmt.Sprintf("%d vendored file(s)", len(scope.Vendored)))
+		flags = append(flags, "-analyze-vendored")
+	}
+	if len(files) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Skipped %d template(s) of %s, analyzed as part of the circuits including them (%s to analyze them on their own)\n",
+		scope.Templates, strings.Join(files, " and "), strings.Join(flags, " and "))
+}
@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeScopeTree writes a project with an entry point including a file next
+// to it and one of a library directory, a file nothing includes and a
+// vendored copy of a library, and returns its root
+func writeScopeTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"circuits/main.circom":             "include \"square.circom\";\ninclude \"double.circom\";\n\ntemplate Main() {}\n\ncomponent main = Main();\n",
+		"circuits/square.circom":           "template Square() {}\n",
+		"circuits/standalone.circom":       "template Standalone() {}\ntemplate Helper() {}\n",
+		"shared/double.circom":             "template Double() {}\n",
+		"vendor/circomlib/poseidon.circom": "template Poseidon() {}\ntemplate Ark() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestScopeFiles(t *testing.T) {
+	root := writeScopeTree(t)
+	path := func(name string) string { return filepath.Join(root, filepath.FromSlash(name)) }
+	files := []string{
+		path("circuits/main.circom"),
+		path("circuits/square.circom"),
+		path("circuits/standalone.circom"),
+		path("shared/double.circom"),
+		path("vendor/circomlib/poseidon.circom"),
+		path("circuits/missing.circom"),
+	}
+	libraries := []string{path("shared")}
+
+	tests := []struct {
+		name    string
+		options ScopeOptions
+		want    Scope
+	}{
+		{
+			name:    "default",
+			options: ScopeOptions{Libraries: libraries, Root: root},
+			want: Scope{
+				Files:       []string{files[0], files[2], files[5]},
+				IncludeOnly: []string{files[1], files[3]},
+				Vendored:    []string{files[4]},
+				Templates:   4,
+			},
+		},
+		{
+			name:    "without the library",
+			options: ScopeOptions{Root: root},
+			want: Scope{
+				Files:       []string{files[0], files[2], files[3], files[5]},
+				IncludeOnly: []string{files[1]},
+				Vendored:    []string{files[4]},
+				Templates:   3,
+			},
+		},
+		{
+			name:    "includes",
+			options: ScopeOptions{Includes: true, Libraries: libraries, Root: root},
+			want: Scope{
+				Files:     []string{files[0], files[1], files[2], files[3], files[5]},
+				Vendored:  []string{files[4]},
+				Templates: 2,
+			},
+		},
+		{
+			name:    "vendored",
+			options: ScopeOptions{Vendored: true, Libraries: libraries, Root: root},
+			want: Scope{
+				Files:       []string{files[0], files[2], files[4], files[5]},
+				IncludeOnly: []string{files[1], files[3]},
+				Templates:   2,
+			},
+		},
+		{
+			name:    "input inside a vendored directory",
+			options: ScopeOptions{Root: path("vendor/circomlib")},
+			want:    Scope{Files: []string{files[4]}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			input := files
+			if test.options.Root != root {
+				input = files[4:5]
+			}
+			if got := ScopeFiles(input, test.options); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("scope = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteScope(t *testing.T) {
+	var out strings.Builder
+	WriteScope(&out, Scope{Files: []string{"main.circom"}, IncludeOnly: []string{"a.circom", "b.circom"}, Vendored: []string{"vendor/c.circom"}, Templates: 4})
+	want := "Skipped 4 template(s) of 2 file(s) only included by other files and 1 vendored file(s), analyzed as part of the circuits including them (-analyze-includes and -analyze-vendored to analyze them on their own)\n"
+	if out.String() != want {
+		t.Errorf("scope line = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	WriteScope(&out, Scope{IncludeOnly: []string{"a.circom"}, Templates: 1})
+	want = "Skipped 1 template(s) of 1 file(s) only included by other files, analyzed as part of the circuits including them (-analyze-includes to analyze them on their own)\n"
+	if out.String() != want {
+		t.Errorf("scope line = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	WriteScope(&out, Scope{Files: []string{"main.circom"}})
+	if out.Len() != 0 {
+		t.Errorf("wrote %q for a scope leaving nothing out", out.String())
+	}
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// MergedFinding is a finding reported by one or more runs, with the runs that
+// reported it
+type MergedFinding struct {
+	File     string               `json:"file"`
+	Template string               `json:"template"`
+	Finding  circuitgraph.Finding `json:"finding"`
+	Runs     []string             `json:"runs"` // Names of the runs reporting the finding, in the order they were given
+}
+
+// mergeKey identifies a finding across runs: its template, told apart by its
+// file as in qualifiedName, its category and its signal
+type mergeKey struct {
+	template, category, signal string
+}
+
+// MergeFindings returns the union of the findings of several runs of the
+// analysis, e.g. with other arguments or circom versions, reporting a finding
+// once per template, category and signal. Templates are matched by their path
+// relative to the input, or by their file if it is not known, and name. A
+// finding keeps the most severe severity any run gave it, with its message.
+// Findings are in the order of the run, template and finding first reporting
+// them. names and runs are parallel.
+func MergeFindings(names []string, runs []Results) []MergedFinding {
+	var merged []MergedFinding
+	index := make(map[mergeKey]int)
+	for i, run := range runs {
+		for _, template := range run.Templates {
+			file := template.Path
+			if file == "" {
+				file = template.File
+			}
+			for _, finding := range template.Findings {
+				key := mergeKey{qualifiedName(file, template.Template), finding.Category, finding.Signal}
+				at, seen := index[key]
+				if !seen {
+					index[key] = len(merged)
+					merged = append(merged, MergedFinding{File: template.File, Template: template.Template, Finding: finding, Runs: []string{names[i]}})
+					continue
+				}
+				if finding.Severity.Rank() > merged[at].Finding.Severity.Rank() {
+					merged[at].Finding = finding
+				}
+				if runs := merged[at].Runs; runs[len(runs)-1] != names[i] {
+					merged[at].Runs = append(runs, names[i])
+				}
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compileBuckets are the upper bounds, in seconds, of the compile duration
+// histogram, from small templates to large circuits hitting a timeout
+var compileBuckets = []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// metrics counts what the analyzer of the API did, as an Observer, and writes
+// the counts in the Prometheus text format
+type metrics struct {
+	mu             sync.Mutex
+	started        int64
+	completed      int64
+	failed         int64   // Templates whose analysis failed after it started
+	filesFailed    int64   // Files that failed before any of their templates started, e.g. unreadable ones
+	compiles       []int64 // Compilations per bucket of compileBuckets, not cumulative, the last one above them all
+	compileSeconds float64
+	compileErrors  int64
+	findings       map[string]int64 // Findings reported so far by category
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		compiles: make([]int64, len(compileBuckets)+1),
+		findings: make(map[string]int64),
+	}
+}
+
+func (m *metrics) TemplateStarted(file, template string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started++
+}
+
+func (m *metrics) CompileFinished(template string, elapsed time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seconds := elapsed.Seconds()
+	m.compiles[sort.SearchFloat64s(compileBuckets, seconds)]++
+	m.compileSeconds += seconds
+	if err != nil {
+		m.compileErrors++
+	}
+}
+
+func (m *metrics) TemplateFinished(result TemplateResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if result.Error != "" {
+		if result.Template == "" {
+			m.filesFailed++
+		} else {
+			m.failed++
+		}
+		return
+	}
+	if result.Skipped != "" {
+		return
+	}
+	m.completed++
+	for _, finding := range result.Findings {
+		m.findings[finding.Category]++
+	}
+}
+
+// write prints the metrics, along with the number of busy and available workers
+func (m *metrics) write(w io.Writer, busyWorkers, workers int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var b strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+	metric("circuit_analyzer_templates_started_total", "counter", "Templates whose analysis started.")
+	fmt.Fprintf(&b, "circuit_analyzer_templates_started_total %d\n", m.started)
+	metric("circuit_analyzer_templates_completed_total", "counter", "Templates analyzed successfully.")
+	fmt.Fprintf(&b, "circuit_analyzer_templates_completed_total %d\n", m.completed)
+	metric("circuit_analyzer_templates_failed_total", "counter", "Templates whose analysis failed.")
+	fmt.Fprintf(&b, "circuit_analyzer_templates_failed_total %d\n", m.failed)
+	metric("circuit_analyzer_files_failed_total", "counter", "Files that failed before any of their templates was analyzed.")
+	fmt.Fprintf(&b, "circuit_analyzer_files_failed_total %d\n", m.filesFailed)
+
+	metric("circuit_analyzer_compile_duration_seconds", "histogram", "Time spent in the circom compiler per template.")
+	var cumulative, total int64
+	for _, count := range m.compiles {
+		total += count
+	}
+	for i, bound := range compileBuckets {
+		cumulative += m.compiles[i]
+		fmt.Fprintf(&b, "circuit_analyzer_compile_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(&b, "circuit_analyzer_compile_duration_seconds_bucket{le=\"+Inf\"} %d\n", total)
+	fmt.Fprintf(&b, "circuit_analyzer_compile_duration_seconds_sum %s\n", strconv.FormatFloat(m.compileSeconds, 'g', -1, 64))
+	fmt.Fprintf(&b, "circuit_analyzer_compile_duration_seconds_count %d\n", total)
+	metric("circuit_analyzer_compile_errors_total", "counter", "Compilations that failed or timed out.")
+	fmt.Fprintf(&b, "circuit_analyzer_compile_errors_total %d\n", m.compileErrors)
+
+	metric("circuit_analyzer_workers_in_flight", "gauge", "Workers currently analyzing a request.")
+	fmt.Fprintf(&b, "circuit_analyzer_workers_in_flight %d\n", busyWorkers)
+	metric("circuit_analyzer_workers", "gauge", "Workers available to analyze requests.")
+	fmt.Fprintf(&b, "circuit_analyzer_workers %d\n", workers)
+
+	metric("circuit_analyzer_findings_total", "counter", "Findings of successfully analyzed templates by rule.")
+	rules := make([]string, 0, len(m.findings))
+	for rule := range m.findings {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "circuit_analyzer_findings_total{rule=%q} %d\n", rule, m.findings[rule])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
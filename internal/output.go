@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WriteResults stores the results as a single JSON document, as JSON Lines,
+// one template per line, or as the CodeClimate issues of all findings
+func WriteResults(results Results, format, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	case "jsonl":
+		encoder := json.NewEncoder(f)
+		for _, template := range results.Templates {
+			if err := encoder.Encode(template); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "codeclimate":
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(codeClimateIssues(results))
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// WriteTemplateResults stores every template result as its own JSON
+// document in dir, which is created if needed, and returns the paths written.
+// Files are named <file>_<template>.json after the path of the source
+// relative to the input, e.g. circuits_rollup_main_Main.json, and names two
+// results would still share get a -2, -3... suffix.
+func WriteTemplateResults(results Results, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	used := make(map[string]bool, len(results.Templates))
+	paths := make([]string, 0, len(results.Templates))
+	for _, template := range results.Templates {
+		base := templateResultName(template)
+		name := base + ".json"
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s-%d.json", base, i)
+		}
+		used[strings.ToLower(name)] = true
+
+		path := outputFile(dir, name)
+		if err := writeJSONFile(path, template); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// templateResultName is the name of the file of WriteTemplateResults without
+// the extension, the source path with its directories joined by underscores,
+// then the template, or the file alone for a file that failed as a whole
+func templateResultName(result TemplateResult) string {
+	path := result.Path
+	if path == "" {
+		path = filepath.ToSlash(result.File)
+	}
+	path = strings.TrimSuffix(strings.TrimLeft(path, "/"), filepath.Ext(path))
+	name := strings.ReplaceAll(path, "/", "_")
+	if result.Template != "" {
+		name += "_" + result.Template
+	}
+	return sanitizeFileName(name)
+}
+
+// writeJSONFile writes a value as an indented JSON document to path
+func writeJSONFile(path string, value any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteHashes prints the topology hash of every analyzed template as template: hash
+func WriteHashes(w io.Writer, results Results) error {
+	for _, t := range results.Templates {
+		if t.Hash == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", t.OutputName(), t.Hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnalysisVersion is the version of the schema of <template>_analysis.json
+// files, raised whenever a change to TemplateResult breaks older readers
+const AnalysisVersion = 1
+
+// analysisFile is the content of a <template>_analysis.json file
+type analysisFile struct {
+	Version int             `json:"analysis_version"`
+	Result  *TemplateResult `json:"result"`
+}
+
+// writeAnalysis stores the result of a template in <template>_analysis.json
+// in dir, next to its visualization
+func writeAnalysis(dir string, result TemplateResult) error {
+	f, err := os.Create(outputFile(dir, fmt.Sprintf("%s_analysis.json", result.OutputName())))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(analysisFile{Version: AnalysisVersion, Result: &result})
+}
+
+// LoadResults reads results written by WriteResults in either format, or the
+// result of a single template from a <template>_analysis.json file
+func LoadResults(path string) (Results, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Results{}, err
+	}
+
+	// A JSON lines file of a single template is a JSON object too, so only an
+	// object with the keys of a document is read as one
+	var document struct {
+		Results
+		analysisFile
+	}
+	var keys map[string]json.RawMessage
+	if json.Unmarshal(data, &keys) == nil && json.Unmarshal(data, &document) == nil && (keys["templates"] != nil || document.Version != 0) {
+		if document.Version == 0 {
+			return document.Results, nil
+		}
+		if document.Version > AnalysisVersion || document.Result == nil {
+			return Results{}, fmt.Errorf("%s: unsupported analysis version %d, expected at most %d", path, document.Version, AnalysisVersion)
+		}
+		return Results{Templates: []TemplateResult{*document.Result}}, nil
+	}
+
+	var results Results
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var template TemplateResult
+		if err := json.Unmarshal(scanner.Bytes(), &template); err != nil {
+			return Results{}, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		results.Templates = append(results.Templates, template)
+	}
+	return results, scanner.Err()
+}
+
+// IsStreamTarget reports whether results written to path should be streamed
+// as they complete rather than written at the end: path is an inherited file
+// descriptor, given as fd:N, or a named pipe
+func IsStreamTarget(path string) bool {
+	if strings.HasPrefix(path, "fd:") {
+		return true
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&os.ModeNamedPipe != 0
+}
+
+// OpenStream opens a stream target for writing. Opening a named pipe blocks
+// until a reader opens it.
+func OpenStream(path string) (*os.File, error) {
+	if fd, ok := strings.CutPrefix(path, "fd:"); ok {
+		n, err := strconv.Atoi(fd)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid file descriptor %q", fd)
+		}
+		f := os.NewFile(uintptr(n), path)
+		if f == nil {
+			return nil, fmt.Errorf("file descriptor %d is not open", n)
+		}
+		if _, err := f.Stat(); err != nil {
+			return nil, fmt.Errorf("file descriptor %d is not open: %w", n, err)
+		}
+		return f, nil
+	}
+	return os.OpenFile(path, os.O_WRONLY, 0)
+}
+
+// ResultStream writes every result as a JSON line as soon as its template is
+// done, in order of completion. As an Observer, it is safe for concurrent use.
+type ResultStream struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error // First write error, later results are dropped
+}
+
+func NewResultStream(w io.Writer) *ResultStream {
+	return &ResultStream{w: w}
+}
+
+func (s *ResultStream) TemplateStarted(file, template string) {}
+
+func (s *ResultStream) CompileFinished(template string, elapsed time.Duration, err error) {}
+
+func (s *ResultStream) TemplateFinished(result TemplateResult) {
+	line, err := json.Marshal(result)
+	if err != nil {
+		printWarning(fmt.Sprintf("streaming the result of %s: %v", result.Template, err))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	// A single write per line, so a reader never sees half a result
+	if _, err := s.w.Write(append(line, '\n')); err != nil {
+		s.err = err
+	}
+}
+
+// Err returns the error that stopped the stream, if any
+func (s *ResultStream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
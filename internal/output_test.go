@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// sampleResults returns results of two templates with a finding each
+func sampleResults() Results {
+	return Results{Templates: []TemplateResult{
+		{
+			File:     "circuits/square.circom",
+			Template: "Square",
+			Stats:    circuitgraph.Stats{Constraints: 1, Signals: 2},
+			Findings: []circuitgraph.Finding{{Category: circuitgraph.CategoryUnderconstrained, Signal: "main.x", Severity: circuitgraph.SeverityHigh}},
+		},
+		{
+			File:     "circuits/cube.circom",
+			Template: "Cube",
+			Stats:    circuitgraph.Stats{Constraints: 2, Signals: 3},
+			Findings: []circuitgraph.Finding{{Category: circuitgraph.CategoryUnderconstrained, Signal: "main.y", Severity: circuitgraph.SeverityLow}},
+		},
+	}}
+}
+
+func TestLoadResults(t *testing.T) {
+	results := sampleResults()
+	tests := []struct {
+		name   string
+		format string
+		want   Results
+	}{
+		{"json", "json", results},
+		{"jsonl", "jsonl", results},
+		{"one line jsonl", "jsonl", Results{Templates: results.Templates[:1]}},
+		{"empty json", "json", Results{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "results")
+			if err := WriteResults(test.want, test.format, path); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadResults(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Templates) != len(test.want.Templates) {
+				t.Fatalf("loaded %d templates, want %d", len(got.Templates), len(test.want.Templates))
+			}
+			for i := range got.Templates {
+				if !reflect.DeepEqual(got.Templates[i], test.want.Templates[i]) {
+					t.Errorf("template %d = %+v, want %+v", i, got.Templates[i], test.want.Templates[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadResultsAnalysisFile(t *testing.T) {
+	dir := t.TempDir()
+	result := sampleResults().Templates[0]
+	if err := writeAnalysis(dir, result); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadResults(filepath.Join(dir, "Square_analysis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Templates) != 1 || !reflect.DeepEqual(got.Templates[0], result) {
+		t.Errorf("loaded %+v, want %+v", got.Templates, result)
+	}
+}
+
+func TestLoadResultsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"newer analysis version", `{"analysis_version": 99, "result": {"file": "a.circom"}}`},
+		{"analysis without result", `{"analysis_version": 1}`},
+		{"invalid line", "{\"file\": \"a.circom\"}\nnot json\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "results")
+			if err := os.WriteFile(path, []byte(test.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadResults(path); err == nil {
+				t.Error("loaded without error")
+			}
+		})
+	}
+}
+
+// fullResult returns a result of a template with most fields of the
+// <template>_analysis.json schema set
+func fullResult() TemplateResult {
+	return TemplateResult{
+		File:          "circuits/square.circom",
+		Path:          "square.circom",
+		Template:      "Square",
+		Args:          []string{"3", "5"},
+		MainComponent: "component main = Square(3, 5);",
+		Command:       "circom --json --sym --O0 square.circom",
+		Stats: circuitgraph.Stats{
+			Constraints: 1, Signals: 3, Edges: 3, Components: 1, Connected: true, LargestComponent: 2,
+			Fingerprint: "0123456789abcdef",
+		},
+		Findings: []circuitgraph.Finding{
+			{Category: circuitgraph.CategoryUnderconstrained, Severity: circuitgraph.SeverityHigh, Signal: "main.x", Message: "signal has a single neighbor"},
+		},
+		Subgraphs:      [][]string{{"main.x", "main.y"}},
+		Connectivity:   circuitgraph.Connectivity{Fiedler: 2, ComponentSize: 2},
+		Seconds:        1.5,
+		CompileSeconds: 1.25,
+	}
+}
+
+func TestAnalysisFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	result := fullResult()
+	if err := writeAnalysis(dir, result); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "Square_analysis.json")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document analysisFile
+	if err := json.Unmarshal(content, &document); err != nil {
+		t.Fatal(err)
+	}
+	if document.Version != AnalysisVersion {
+		t.Errorf("analysis_version = %d, want %d", document.Version, AnalysisVersion)
+	}
+
+	loaded, err := LoadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Templates) != 1 || !reflect.DeepEqual(loaded.Templates[0], result) {
+		t.Errorf("loaded %+v, want %+v", loaded.Templates, result)
+	}
+}
+
+// TestAnalysisFileVersion1 keeps files written by older releases readable:
+// testdata/Square_analysis.json is frozen at analysis_version 1
+func TestAnalysisFileVersion1(t *testing.T) {
+	loaded, err := LoadResults(filepath.Join("testdata", "Square_analysis.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Templates) != 1 || !reflect.DeepEqual(loaded.Templates[0], fullResult()) {
+		t.Errorf("loaded %+v, want %+v", loaded.Templates, fullResult())
+	}
+}
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+func signalFamilies(signals map[int64]string) map[string]bool {
+	families := make(map[string]bool)
+	for _, name := range signals {
+		families[circuitgraph.SignalFamily(name)] = true
+	}
+	return families
+}
+
+// ParamRun is the analysis of a template with one set of arguments
+type ParamRun struct {
+	Args   string // Arguments of the main component, e.g. "4, 2"
+	Result TemplateResult
+}
+
+// ParamDependent is a finding or signal family that only shows up with some of the arguments
+type ParamDependent struct {
+	What  string   // Finding, as [severity] category family, or signal family
+	Where []string // Arguments it shows up with
+}
+
+// ParamComparison is what changes between the runs of a template
+type ParamComparison struct {
+	Findings []ParamDependent // Findings, by category and signal family, missing from some runs
+	Families []ParamDependent // Signal families missing from some runs
+}
+
+// CompareParams finds the findings and signal families of a template that
+// only show up with some of its arguments. Runs that failed are left out.
+func CompareParams(runs []ParamRun) ParamComparison {
+	findings := make(map[string][]string)
+	families := make(map[string][]string)
+	analyzed := 0
+	for _, run := range runs {
+		if run.Result.Error != "" {
+			continue
+		}
+		analyzed++
+		seen := make(map[string]bool)
+		for _, finding := range run.Result.Findings {
+			key := findingKey(finding)
+			if !seen[key] {
+				seen[key] = true
+				findings[key] = append(findings[key], run.Args)
+			}
+		}
+		for family := range run.Result.families {
+			families[family] = append(families[family], run.Args)
+		}
+	}
+	return ParamComparison{
+		Findings: partial(findings, analyzed),
+		Families: partial(families, analyzed),
+	}
+}
+
+// partial returns the entries seen in fewer than all runs, sorted
+func partial(seen map[string][]string, runs int) []ParamDependent {
+	var dependent []ParamDependent
+	for what, where := range seen {
+		if len(where) < runs {
+			dependent = append(dependent, ParamDependent{What: what, Where: where})
+		}
+	}
+	sort.Slice(dependent, func(i, j int) bool { return dependent[i].What < dependent[j].What })
+	return dependent
+}
+
+// WriteParamComparison prints the structure of every run side by side,
+// followed by what only shows up with some of the arguments
+func WriteParamComparison(w io.Writer, runs []ParamRun, comparison ParamComparison) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprint(tw, "ARGS\tSIGNALS\tCONSTRAINTS\tCOMPONENTS\tUNDERCONSTRAINED\tFINDINGS\n")
+	for _, run := range runs {
+		if run.Result.Error != "" {
+			fmt.Fprintf(tw, "(%s)\tfailed: %s\n", run.Args, run.Result.Error)
+			continue
+		}
+		stats := run.Result.Stats
+		fmt.Fprintf(tw, "(%s)\t%d\t%d\t%d\t%d\t%d\n", run.Args, stats.Signals, stats.Constraints, stats.Components,
+			countCategory(run.Result.Findings, circuitgraph.CategoryUnderconstrained), len(run.Result.Findings))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(comparison.Findings) > 0 {
+		fmt.Fprintln(w, "\nFindings that only occur with some arguments, parameter-dependent underconstraints are easy to miss:")
+		printParamDependent(w, comparison.Findings)
+	}
+	if len(comparison.Families) > 0 {
+		fmt.Fprintln(w, "\nSignal families that only exist with some arguments:")
+		printParamDependent(w, comparison.Families)
+	}
+	return nil
+}
+
+func printParamDependent(w io.Writer, dependent []ParamDependent) {
+	for _, d := range dependent {
+		fmt.Fprintf(w, "  %s: only with (%s)\n", d.What, strings.Join(d.Where, "), ("))
+	}
+}
+
+func countCategory(findings []circuitgraph.Finding, category string) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.Category == category {
+			count++
+		}
+	}
+	return count
+}
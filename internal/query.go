@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// Query selects findings from stored results. Empty fields match everything.
+type Query struct {
+	Template    string                // Glob on the template name
+	Signal      string                // Glob on the signal name
+	Category    string                // Exact category
+	MinSeverity circuitgraph.Severity // Lowest severity to include
+}
+
+// QueryMatch is a finding together with the template it was reported for
+type QueryMatch struct {
+	File     string               `json:"file"`
+	Template string               `json:"template"`
+	Finding  circuitgraph.Finding `json:"finding"`
+}
+
+func QueryFindings(results Results, query Query) []QueryMatch {
+	var matches []QueryMatch
+	for _, template := range results.Templates {
+		if query.Template != "" && !matchGlob(query.Template, template.Template) {
+			continue
+		}
+		for _, finding := range template.Findings {
+			if query.Signal != "" && !matchGlob(query.Signal, finding.Signal) {
+				continue
+			}
+			if query.Category != "" && finding.Category != query.Category {
+				continue
+			}
+			if query.MinSeverity != "" && finding.Severity.Rank() < query.MinSeverity.Rank() {
+				continue
+			}
+			matches = append(matches, QueryMatch{File: template.File, Template: template.Template, Finding: finding})
+		}
+	}
+	return matches
+}
+
+// matchGlob matches name against a pattern in which * and ? are the only
+// wildcards, so brackets in signal names like main.in[0] are taken literally.
+func matchGlob(pattern, name string) bool {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	matched, _ := regexp.MatchString("^"+expr+"$", name)
+	return matched
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"html/template"
+	"os"
+)
+
+// maxReportGraphNodes is the size above which graphs are left out of the
+// combined report, browsers struggle to lay out larger ones
+const maxReportGraphNodes = 500
+
+// echartsScript is the echarts build go-echarts renders its charts with
+const echartsScript = "https://go-echarts.github.io/go-echarts-assets/assets/echarts.min.js"
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Circuit graph analysis</title>
+<script src="{{.Script}}"></script>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+.failed { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Circuit graph analysis</h1>
+<p>{{len .Templates}} template(s), {{.Findings}} finding(s), {{.Failures}} failure(s)</p>
+<table>
+<tr><th>File</th><th>Template</th><th>Constraints</th><th>Nodes</th><th>Edges</th><th>Compile</th><th>Findings</th><th>Health</th></tr>
+{{- range $i, $t := .Templates}}
+<tr><td>{{$t.File}}</td><td><a href="#template-{{$i}}">{{$t.Template}}</a></td>
+{{- if $t.Error}}<td colspan="6" class="failed">failed</td>
+{{- else if $t.Skipped}}<td colspan="6">skipped</td>
+{{- else}}<td>{{$t.Stats.Constraints}}</td><td>{{$t.Stats.Signals}}</td><td>{{$t.Stats.Edges}}</td><td>{{if $t.CompileSeconds}}{{printf "%.1fs" $t.CompileSeconds}}{{else}}-{{end}}</td><td>{{$t.FindingCount}}</td><td>{{$t.Health}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{range $i, $t := .Templates}}
+<h2 id="template-{{$i}}">{{$t.Template}} <small>{{$t.File}}</small></h2>
+{{- if $t.MainComponent}}<p><code>{{$t.MainComponent}}</code></p>{{end}}
+{{- if $t.Error}}<p class="failed">{{$t.Error}}</p>{{else if $t.Skipped}}<p>Skipped, {{$t.Skipped}}.</p>{{else}}
+<table>
+<tr><th>Constraints</th><td>{{$t.Stats.Constraints}}</td></tr>
+<tr><th>Signals</th><td>{{$t.Stats.Signals}}</td></tr>
+<tr><th>Edges</th><td>{{$t.Stats.Edges}}</td></tr>
+{{if $t.Inputs}}<tr><th>Public / private inputs</th><td>{{$t.Stats.PublicInputs}} / {{$t.Stats.PrivateInputs}}</td></tr>{{end}}
+<tr><th>Connected</th><td>{{if $t.Stats.Connected}}yes{{else}}no{{end}}</td></tr>
+<tr><th>Components</th><td>{{$t.Stats.Components}}</td></tr>
+<tr><th>Largest component</th><td>{{$t.Stats.LargestComponent}}</td></tr>
+<tr><th>Algebraic connectivity</th><td>{{printf "%.4g" $t.Connectivity.Fiedler}}</td></tr>
+</table>{{end}}
+{{- if $t.Findings}}
+<table>
+<tr><th>Severity</th><th>Category</th><th>Signal</th><th>Message</th></tr>
+{{- range $t.Findings}}
+<tr><td>{{.Severity}}</td><td>{{.Category}}</td><td>{{.Signal}}</td><td>{{.Message}}</td></tr>
+{{- end}}
+</table>
+{{- else if not (or $t.Error $t.Skipped)}}<p>No findings.</p>{{end}}
+{{- if $t.Graph}}
+{{$t.Graph.Element}}
+{{$t.Graph.Script}}
+{{- else if not (or $t.Error $t.Skipped)}}<p>Graph not shown, it has more than {{$.MaxGraphNodes}} nodes.</p>{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// reportTemplateData is a template result as shown in the report
+type reportTemplateData struct {
+	TemplateResult
+	Health int
+	Graph  *reportGraph
+}
+
+// reportGraph holds the parts of a rendered chart, trusted as produced by go-echarts
+type reportGraph struct {
+	Element template.HTML
+	Script  template.HTML
+}
+
+// WriteReport writes a single HTML page with an index of all templates, their
+// stats and findings, and the charts of their graphs if the analyzer ran
+// with Report enabled
+func WriteReport(results Results, path string) error {
+	data := struct {
+		ReportData
+		Script        string
+		MaxGraphNodes int
+	}{
+		ReportData:    newReportData(results),
+		Script:        echartsScript,
+		MaxGraphNodes: maxReportGraphNodes,
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return reportTemplate.Execute(f, data)
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// builtinReportTemplates are the report templates selected by name rather
+// than by path, written against the same data as user templates
+var builtinReportTemplates = map[string]string{
+	// The summary printed at the end of a run, with the findings of every template
+	"summary": `Analyzed {{len .Templates}} template(s) with {{.Findings}} finding(s), {{.Failures}} failure(s)
+{{- range .Templates}}
+{{.File}}: {{.Template}}
+{{- if .Error}} failed: {{.Error}}
+{{- else if .Skipped}} skipped, {{.Skipped}}
+{{- else}} {{.Stats.Signals}} signals, {{.Stats.Edges}} edges, health {{.Health}}
+{{- range bySeverity .Findings}}
+  {{.Severity}} {{.Category}}{{if .Signal}} {{.Signal}}{{end}}: {{.Message}}
+{{- end}}
+{{- end}}
+{{- end}}
+`,
+	// A Markdown page for merge request comments and wikis
+	"markdown": `# Circuit graph analysis
+
+{{len .Templates}} template(s), {{.Findings}} finding(s), {{.Failures}} failure(s).
+{{- if .Directories}}
+
+| Directory | Files | Templates | Findings | Failures |
+|---|---|---|---|---|
+{{- range .Directories}}
+| {{.Directory}} | {{.Files}} | {{.Templates}} | {{.Findings}} | {{.Failures}} |
+{{- end}}
+{{- end}}
+
+| File | Template | Nodes | Edges | Findings | Health |
+|---|---|---|---|---|---|
+{{- range byFindings .Templates}}
+{{- if .Error}}
+| {{.File}} | {{.Template}} | - | - | - | failed |
+{{- else if .Skipped}}
+| {{.File}} | {{.Template}} | - | - | - | skipped |
+{{- else}}
+| {{.File}} | {{.Template}} | {{.Stats.Signals}} | {{.Stats.Edges}} | {{.FindingCount}} | {{.Health}} |
+{{- end}}
+{{- end}}
+{{range byFindings .Templates}}{{if or .Findings .Error}}
+## {{.Template}} ({{.File}})
+
+{{if .Error}}Failed: {{.Error}}
+{{else}}{{percent .Stats.LargestComponent .Stats.Signals}} of the signals are in the largest component.
+
+| Severity | Category | Signal | Message |
+|---|---|---|---|
+{{- range bySeverity .Findings}}
+| {{severityEmoji .Severity}} {{.Severity}} | {{.Category}} | {{.Signal}} | {{.Message}} |
+{{- end}}
+{{end}}{{end}}{{end}}`,
+}
+
+// severityColors are the colors of the severities in HTML, as CSS colors
+var severityColors = map[circuitgraph.Severity]string{
+	circuitgraph.SeverityInfo:     "#607d8b",
+	circuitgraph.SeverityLow:      "#2e7d32",
+	circuitgraph.SeverityMedium:   "#f9a825",
+	circuitgraph.SeverityHigh:     "#ef6c00",
+	circuitgraph.SeverityCritical: "#c62828",
+}
+
+// severityEmojis mark the severities in Markdown, which has no colors
+var severityEmojis = map[circuitgraph.Severity]string{
+	circuitgraph.SeverityInfo:     "⚪",
+	circuitgraph.SeverityLow:      "🟢",
+	circuitgraph.SeverityMedium:   "🟡",
+	circuitgraph.SeverityHigh:     "🟠",
+	circuitgraph.SeverityCritical: "🔴",
+}
+
+// reportFuncs are the helpers available to report templates
+var reportFuncs = map[string]any{
+	"bySeverity": bySeverity,
+	// byFindings returns the templates with the most findings first, then by file and name
+	"byFindings": func(templates []reportTemplateData) []reportTemplateData {
+		sorted := append([]reportTemplateData(nil), templates...)
+		sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].FindingCount() > sorted[j].FindingCount() })
+		return sorted
+	},
+	// percent formats part of total as a percentage with one decimal
+	"percent": func(part, total int) string {
+		if total == 0 {
+			return "0.0%"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(part)/float64(total))
+	},
+	"severityColor": func(severity circuitgraph.Severity) string { return severityColors[severity] },
+	"severityEmoji": func(severity circuitgraph.Severity) string { return severityEmojis[severity] },
+	// ansi wraps text in the terminal color of a severity
+	"ansi": func(severity circuitgraph.Severity, text string) string {
+		codes := map[circuitgraph.Severity]string{
+			circuitgraph.SeverityLow:      "32",
+			circuitgraph.SeverityMedium:   "33",
+			circuitgraph.SeverityHigh:     "91",
+			circuitgraph.SeverityCritical: "31;1",
+		}
+		if code, ok := codes[severity]; ok {
+			return "\x1b[" + code + "m" + text + "\x1b[0m"
+		}
+		return text
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// bySeverity returns the findings most severe first, then by category and signal
+func bySeverity(findings []circuitgraph.Finding) []circuitgraph.Finding {
+	sorted := append([]circuitgraph.Finding(nil), findings...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Severity.Rank() != sorted[j].Severity.Rank() {
+			return sorted[i].Severity.Rank() > sorted[j].Severity.Rank()
+		}
+		if sorted[i].Category != sorted[j].Category {
+			return sorted[i].Category < sorted[j].Category
+		}
+		return sorted[i].Signal < sorted[j].Signal
+	})
+	return sorted
+}
+
+// ReportData is what report templates see of a run
+type ReportData struct {
+	Templates   []reportTemplateData
+	Directories []DirectorySummary
+	Findings    int
+	Failures    int
+	Seconds     float64 // Time spent on all templates
+}
+
+// newReportData prepares the results of a run for a report template
+func newReportData(results Results) ReportData {
+	data := ReportData{
+		Directories: results.Directories,
+		Findings:    results.Findings(),
+		Failures:    results.Failures(),
+	}
+	for _, t := range results.Templates {
+		entry := reportTemplateData{TemplateResult: t, Health: templateHealth(t)}
+		if t.graph != nil {
+			entry.Graph = &reportGraph{Element: htmltemplate.HTML(t.graph.Element), Script: htmltemplate.HTML(t.graph.Script)}
+		}
+		data.Templates = append(data.Templates, entry)
+		data.Seconds += t.Seconds
+	}
+	return data
+}
+
+// IsBuiltinReportTemplate reports whether name selects a built-in report template
+func IsBuiltinReportTemplate(name string) bool {
+	_, ok := builtinReportTemplates[name]
+	return ok
+}
+
+// WriteTemplateReport renders the results through a report template, either
+// the name of a built-in one or the path of a Go template file, parsed with
+// html/template if it ends in .html and text/template otherwise. Errors name
+// the template file and the line. Nothing is written if rendering fails.
+func WriteTemplateReport(w io.Writer, results Results, name string) error {
+	type executor interface {
+		Execute(io.Writer, any) error
+	}
+	source, ok := builtinReportTemplates[name]
+	if !ok {
+		content, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		source = string(content)
+	}
+
+	var tmpl executor
+	var err error
+	templateName := filepath.Base(name)
+	if ext := strings.ToLower(filepath.Ext(name)); ext == ".html" || ext == ".htm" {
+		tmpl, err = htmltemplate.New(templateName).Funcs(reportFuncs).Parse(source)
+	} else {
+		tmpl, err = texttemplate.New(templateName).Funcs(reportFuncs).Parse(source)
+	}
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, newReportData(results)); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,192 @@
+package internal
+
+import (
+	"errors"
+	mathrand "math/rand"
+	"sort"
+	"sync"
+
+	"github.com/go-echarts/go-echarts/v2/render"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// TemplateResult is the outcome of analyzing a single template
+type TemplateResult struct {
+	File            string                             `json:"file"`
+	Path            string                             `json:"path,omitempty"` // File relative to the input directory, with forward slashes
+	Template        string                             `json:"template,omitempty"`
+	Output          string                             `json:"output,omitempty"` // Name of the files written for the template if other files declare the same name
+	Args            []string                           `json:"args,omitempty"`
+	MainComponent   string                             `json:"main_component,omitempty"` // Main component the template was compiled with
+	Command         string                             `json:"command,omitempty"`        // Compiler command line, for reproduction
+	Stats           circuitgraph.Stats                 `json:"stats"`
+	Findings        []circuitgraph.Finding             `json:"findings"`
+	Severities      map[circuitgraph.Severity]int      `json:"severities,omitempty"`       // Finding counts kept in place of the findings, with -low-memory
+	Subgraphs       [][]string                         `json:"subgraphs,omitempty"`        // Signals of every independent subgraph, if there is more than one
+	Blocks          []circuitgraph.Block               `json:"blocks,omitempty"`           // Biconnected components, for rendering the block-cut tree
+	Connectivity    circuitgraph.Connectivity          `json:"connectivity"`               // Robustness of the largest component, for trending
+	HotSpots        []circuitgraph.HotSpot             `json:"hot_spots,omitempty"`        // Edges with the highest betweenness
+	Hubs            []circuitgraph.Hub                 `json:"hubs,omitempty"`             // Signals connected to a large share of the graph
+	Patterns        []circuitgraph.Pattern             `json:"patterns,omitempty"`         // Repeated local structures of array signal families
+	Coloring        *circuitgraph.Coloring             `json:"coloring,omitempty"`         // Greedy coloring of the signals, with -coloring
+	Twins           []circuitgraph.TwinGroup           `json:"twins,omitempty"`            // Signals appearing in exactly the same constraints
+	Inputs          []circuitgraph.InputSignal         `json:"inputs,omitempty"`           // Inputs of the main component, public or private, if circom wrote an r1cs file
+	Prefixes        *circuitgraph.PrefixStats          `json:"prefixes,omitempty"`         // Statistics per signal name prefix, with -prefix-stats
+	Removal         *circuitgraph.Removal              `json:"removal,omitempty"`          // Components before and after the -remove-signals what-if
+	Samples         []ArgSample                        `json:"samples,omitempty"`          // Arguments and finding counts of every sample, with -arg-samples
+	ComponentTotals *circuitgraph.ComponentConstraints `json:"component_totals,omitempty"` // Constraints attributed to the components of the circuit
+	Seconds         float64                            `json:"seconds,omitempty"`          // Time spent compiling and analyzing the template
+	CompileSeconds  float64                            `json:"compile_seconds,omitempty"`  // Wall-clock time of the compiler alone, failed compilations included
+	Hash            string                             `json:"hash,omitempty"`             // Topology hash of the graph, with -hash-only
+	HookExit        int                                `json:"hook_exit,omitempty"`        // Non-zero exit code of the -post-process hook
+	Error           string                             `json:"error,omitempty"`
+	Skipped         string                             `json:"skipped,omitempty"` // Why the file was not analyzed, e.g. it exceeds -max-file-size
+
+	err      error                // Original error, for errors.As
+	graph    *render.ChartSnippet // Chart of the constraint graph for the combined report, if requested and small enough
+	families map[string]bool      // Signal names without array indices, if requested
+	signals  map[string]bool      // Signals of the graph, if requested
+	random   *mathrand.Rand       // Source of the generated arguments, the global one if nil
+	source   *TemplateInfo        // Declaration of the template, locating it and its signals in the file
+	noFiles  bool                 // Skip the files written per template, for the argument samples after the kept one
+}
+
+// OutputName is the name the files written for the template start with, its
+// name unless other files declare a template of the same name
+func (t TemplateResult) OutputName() string {
+	if t.Output != "" {
+		return t.Output
+	}
+	return t.Template
+}
+
+// FindingCount returns the number of findings of the template, also when
+// only their counts were kept
+func (t TemplateResult) FindingCount() int {
+	if t.Severities == nil {
+		return len(t.Findings)
+	}
+	count := 0
+	for _, n := range t.Severities {
+		count += n
+	}
+	return count
+}
+
+// severities counts the findings of the template by severity
+func (t TemplateResult) severities() map[circuitgraph.Severity]int {
+	if t.Severities != nil {
+		return t.Severities
+	}
+	counts := make(map[circuitgraph.Severity]int)
+	for _, finding := range t.Findings {
+		counts[finding.Severity]++
+	}
+	return counts
+}
+
+// summary strips the result down to its stats and finding counts, what
+// -low-memory keeps of a template once it has been streamed
+func (t TemplateResult) summary() TemplateResult {
+	return TemplateResult{
+		File:           t.File,
+		Path:           t.Path,
+		Template:       t.Template,
+		Output:         t.Output,
+		Args:           t.Args,
+		MainComponent:  t.MainComponent,
+		Command:        t.Command,
+		Stats:          t.Stats,
+		Severities:     t.severities(),
+		Connectivity:   t.Connectivity,
+		Seconds:        t.Seconds,
+		CompileSeconds: t.CompileSeconds,
+		Hash:           t.Hash,
+		HookExit:       t.HookExit,
+		Error:          t.Error,
+		Skipped:        t.Skipped,
+		err:            t.err,
+	}
+}
+
+// Err returns the error that stopped the analysis, nil if it succeeded or was loaded from a file
+func (t TemplateResult) Err() error {
+	return t.err
+}
+
+// Results aggregates the template results of a run
+type Results struct {
+	Templates   []TemplateResult   `json:"templates"`
+	Directories []DirectorySummary `json:"directories,omitempty"` // Totals per top-level directory of the input
+}
+
+// Findings returns the total number of findings across all templates
+func (r Results) Findings() int {
+	count := 0
+	for _, t := range r.Templates {
+		count += t.FindingCount()
+	}
+	return count
+}
+
+// HookFailures returns the number of templates whose -post-process hook exited non-zero
+func (r Results) HookFailures() int {
+	count := 0
+	for _, t := range r.Templates {
+		if t.HookExit != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Failures returns the number of files and templates that could not be analyzed
+func (r Results) Failures() int {
+	count := 0
+	for _, t := range r.Templates {
+		if t.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// As finds the first template error matching target, as errors.As does
+func (r Results) As(target any) bool {
+	for _, t := range r.Templates {
+		if t.err != nil && errors.As(t.err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// collector gathers template results from concurrent workers
+type collector struct {
+	mu      sync.Mutex
+	results []TemplateResult
+}
+
+func (c *collector) add(result TemplateResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results = append(c.results, result)
+}
+
+// drain returns the collected results ordered by file and template, independent
+// of worker scheduling, and empties the collector
+func (c *collector) drain() Results {
+	c.mu.Lock()
+	templates := c.results
+	c.results = nil
+	c.mu.Unlock()
+
+	sort.SliceStable(templates, func(i, j int) bool {
+		if templates[i].File != templates[j].File {
+			return templates[i].File < templates[j].File
+		}
+		return templates[i].Template < templates[j].Template
+	})
+	return Results{Templates: templates}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// ArgAggregate is how the findings of several argument samples of a template are combined
+type ArgAggregate string
+
+const (
+	AggregateIntersect ArgAggregate = "intersect" // Findings of every sample
+	AggregateUnion     ArgAggregate = "union"     // Findings of any sample
+	AggregateMajority  ArgAggregate = "majority"  // Findings of more than half the samples
+)
+
+// ArgSample is one analysis of a template with randomly generated arguments
+type ArgSample struct {
+	Args     []string `json:"args"`
+	Findings int      `json:"findings"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// findingKey identifies a finding across arguments, by its severity, its
+// category and the family of its signal, e.g. main.in[*] for main.in[3]
+func findingKey(finding circuitgraph.Finding) string {
+	key := fmt.Sprintf("[%s] %s", finding.Severity, finding.Category)
+	if finding.Signal != "" {
+		key += " " + circuitgraph.SignalFamily(finding.Signal)
+	}
+	return key
+}
+
+// analyzeSamples analyzes a template once per argument sample and keeps the
+// result of the first successful sample, with the findings of all samples
+// combined by the aggregation policy. Only the kept sample writes the files
+// of the template, the later ones would overwrite them. Failed samples are
+// left out of it, the first error is returned when they all fail.
+func (a *Analyzer) analyzeSamples(ctx context.Context, filePath string, template TemplateInfo, result *TemplateResult) error {
+	var samples []TemplateResult
+	var failed TemplateResult // First failed sample, reported if all of them fail
+	var firstErr error
+	for i := 0; i < a.options.ArgSamples; i++ {
+		sample := TemplateResult{File: filePath, Path: result.Path, Template: template.Name, Output: result.Output, random: result.random, source: result.source, noFiles: len(samples) > 0}
+		fmt.Fprintf(a.report, "\nSample %d/%d of template %s\n", i+1, a.options.ArgSamples, template.Name)
+		err := a.analyzeTemplate(ctx, filePath, template, &sample)
+		result.Samples = append(result.Samples, ArgSample{Args: sample.Args, Findings: len(sample.Findings)})
+		if err != nil {
+			result.Samples[i].Error = err.Error()
+			if ctx.Err() != nil {
+				return err
+			}
+			if firstErr == nil {
+				failed, firstErr = sample, err
+			}
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	if len(samples) == 0 {
+		failed.Samples = result.Samples
+		*result = failed
+		return firstErr
+	}
+
+	findings := make([][]circuitgraph.Finding, len(samples))
+	for i, sample := range samples {
+		findings[i] = sample.Findings
+	}
+	first := samples[0]
+	first.Samples = result.Samples
+	first.Findings = a.options.ArgAggregate.aggregate(findings)
+	*result = first
+
+	args := make([]string, len(result.Samples))
+	for i, sample := range result.Samples {
+		args[i] = "(" + strings.Join(sample.Args, ", ") + ")"
+	}
+	fmt.Fprintf(a.report, "\nTemplate %s: %d finding(s) kept by %s over samples %s\n", template.Name, len(result.Findings), a.options.ArgAggregate, strings.Join(args, " "))
+	return nil
+}
+
+// aggregate combines the findings of several samples. Every sample counts a
+// kind of finding once, however many signals it has. The first sample keeps
+// all its findings of a kept kind, the other samples contribute one example
+// of each kind it lacks.
+func (p ArgAggregate) aggregate(samples [][]circuitgraph.Finding) []circuitgraph.Finding {
+	if len(samples) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	examples := make(map[string]circuitgraph.Finding)
+	var keys []string
+	for _, findings := range samples {
+		seen := make(map[string]bool)
+		for _, finding := range findings {
+			key := findingKey(finding)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			if counts[key] == 0 {
+				keys = append(keys, key)
+				examples[key] = finding
+			}
+			counts[key]++
+		}
+	}
+
+	var kept []circuitgraph.Finding
+	inFirst := make(map[string]bool)
+	for _, finding := range samples[0] {
+		key := findingKey(finding)
+		inFirst[key] = true
+		if p.keeps(counts[key], len(samples)) {
+			kept = append(kept, finding)
+		}
+	}
+	for _, key := range keys {
+		if !inFirst[key] && p.keeps(counts[key], len(samples)) {
+			kept = append(kept, examples[key])
+		}
+	}
+	return kept
+}
+
+// keeps reports whether a finding seen in found of the samples is kept
+func (p ArgAggregate) keeps(found, samples int) bool {
+	switch p {
+	case AggregateUnion:
+		return found > 0
+	case AggregateMajority:
+		return 2*found > samples
+	default:
+		return found == samples
+	}
+}
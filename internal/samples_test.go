@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+func TestAggregateFindings(t *testing.T) {
+	finding := func(category, signal string) circuitgraph.Finding {
+		return circuitgraph.Finding{Category: category, Severity: circuitgraph.SeverityMedium, Signal: signal}
+	}
+	in0 := finding(circuitgraph.CategoryUnderconstrained, "main.in[0]")
+	in1 := finding(circuitgraph.CategoryUnderconstrained, "main.in[1]")
+	in2 := finding(circuitgraph.CategoryUnderconstrained, "main.in[2]")
+	twin := finding(circuitgraph.CategoryTwinSignals, "main.a")
+	isolated := finding(circuitgraph.CategoryIsolatedInput, "main.x")
+	// The underconstrained inputs are in every sample, whatever their index,
+	// the isolated input in two samples and the twins in one
+	samples := [][]circuitgraph.Finding{
+		{in0, in1, twin},
+		{in2, isolated},
+		{in0, isolated},
+	}
+
+	tests := []struct {
+		policy ArgAggregate
+		want   []circuitgraph.Finding
+	}{
+		{"", []circuitgraph.Finding{in0, in1}},
+		{AggregateIntersect, []circuitgraph.Finding{in0, in1}},
+		{AggregateMajority, []circuitgraph.Finding{in0, in1, isolated}},
+		{AggregateUnion, []circuitgraph.Finding{in0, in1, twin, isolated}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.policy), func(t *testing.T) {
+			if got := test.policy.aggregate(samples); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("aggregate() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+	if got := AggregateUnion.aggregate(nil); got != nil {
+		t.Errorf("aggregate(nil) = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// SignalMetrics gathers the per-signal metrics of a template for -format signals-csv
+type SignalMetrics struct {
+	SignalDegree
+	ID             int64
+	Kind           circuitgraph.SignalKind
+	WeightedDegree int                     // Constraints behind the edges of the signal, summed over its neighbors
+	Slots          circuitgraph.Slots      // Terms of the constraints the signal appears in
+	TwinGroup      int                     // ID of the twin group of the signal, 0 if it has no twin
+	Visibility     circuitgraph.Visibility // Visibility of an input of the main component, empty for other signals or if unknown
+}
+
+// signalMetrics returns the metrics of every signal ordered by name
+func signalMetrics(g *circuitgraph.CircuitGraph, kinds map[string]circuitgraph.SignalKind, slots map[int64]circuitgraph.SlotUse, twins []circuitgraph.TwinGroup, inputs []circuitgraph.InputSignal) []SignalMetrics {
+	twinGroups := make(map[string]int)
+	for _, group := range twins {
+		for _, signal := range group.Signals {
+			twinGroups[signal] = group.ID
+		}
+	}
+	visibilities := make(map[string]circuitgraph.Visibility, len(inputs))
+	for _, input := range inputs {
+		visibilities[input.Signal] = input.Visibility
+	}
+	ids := make(map[string]int64)
+	nodes := g.Nodes()
+	for nodes.Next() {
+		if node := nodes.Node().(*circuitgraph.NamedNode); !node.Synthetic() {
+			ids[node.Name] = node.ID()
+		}
+	}
+
+	degrees := signalDegrees(g)
+	metrics := make([]SignalMetrics, len(degrees))
+	for i, degree := range degrees {
+		id := ids[degree.Signal]
+		weighted := 0
+		neighbors := g.From(id)
+		for neighbors.Next() {
+			weighted += len(g.Provenance(id, neighbors.Node().ID()))
+		}
+		metrics[i] = SignalMetrics{
+			SignalDegree:   degree,
+			ID:             id,
+			Kind:           circuitgraph.SignalRole(degree.Signal, kinds),
+			WeightedDegree: weighted,
+			Slots:          slots[id].Slots,
+			TwinGroup:      twinGroups[degree.Signal],
+			Visibility:     visibilities[degree.Signal],
+		}
+	}
+	return metrics
+}
+
+// SignalColumns tells which of the optional metrics of SignalMetrics were
+// computed for a template, the others are left out of the CSV
+type SignalColumns struct {
+	Slots      bool // Skipped in quick mode
+	Twins      bool // Skipped in quick mode or without the twins check
+	Visibility bool // Only known from an r1cs file
+}
+
+// writeSignalMetrics writes one row per signal to <template>_signals.csv,
+// with the optional columns of the metrics that were computed
+func writeSignalMetrics(dir string, metrics []SignalMetrics, columns SignalColumns, templateName string) error {
+	fileName := outputFile(dir, fmt.Sprintf("%s_signals.csv", templateName))
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	writer := csv.NewWriter(f)
+	header := []string{"id", "signal", "kind", "degree", "weighted_degree", "degree_percentile", "degree_z_score"}
+	if columns.Slots {
+		header = append(header, "slots")
+	}
+	if columns.Twins {
+		header = append(header, "twin_group")
+	}
+	if columns.Visibility {
+		header = append(header, "visibility")
+	}
+	writer.Write(header)
+	for _, m := range metrics {
+		row := []string{
+			strconv.FormatInt(m.ID, 10),
+			m.Signal,
+			string(m.Kind),
+			strconv.Itoa(m.Degree),
+			strconv.Itoa(m.WeightedDegree),
+			strconv.FormatFloat(m.Percentile, 'f', 2, 64),
+			strconv.FormatFloat(m.ZScore, 'f', 3, 64),
+		}
+		if columns.Slots {
+			row = append(row, m.Slots.String())
+		}
+		if columns.Twins {
+			row = append(row, twinGroup(m.TwinGroup))
+		}
+		if columns.Visibility {
+			row = append(row, string(m.Visibility))
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// twinGroup formats the twin group ID of a signal, empty if it has no twin
+func twinGroup(id int) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.Itoa(id)
+}
@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// squareMetrics returns the metrics of x * z = y, where x and z are twins and
+// x is a public input
+func squareMetrics(t *testing.T) []SignalMetrics {
+	t.Helper()
+	constraints := circuitgraph.Constraints{{{2}, {3}, {1}}}
+	signals := map[int64]string{1: "main.y", 2: "main.x", 3: "main.z"}
+	g, err := circuitgraph.BuildGraph(constraints, signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[string]circuitgraph.SignalKind{"x": circuitgraph.KindInput, "z": circuitgraph.KindInput, "y": circuitgraph.KindOutput}
+	twins := []circuitgraph.TwinGroup{{ID: 1, Signals: []string{"main.x", "main.z"}, Constraints: 1}}
+	inputs := []circuitgraph.InputSignal{{Signal: "main.x", Visibility: circuitgraph.VisibilityPublic}, {Signal: "main.z", Visibility: circuitgraph.VisibilityPrivate}}
+	return signalMetrics(g, kinds, circuitgraph.SignalSlots(constraints), twins, inputs)
+}
+
+func TestSignalMetrics(t *testing.T) {
+	metrics := squareMetrics(t)
+	// Every signal shares the constraint with the two others
+	degree := SignalDegree{Degree: 2, Percentile: 50}
+	want := []SignalMetrics{
+		{SignalDegree: signalDegree("main.x", degree), ID: 2, Kind: circuitgraph.KindInput, WeightedDegree: 2, Slots: circuitgraph.SlotA, TwinGroup: 1, Visibility: circuitgraph.VisibilityPublic},
+		{SignalDegree: signalDegree("main.y", degree), ID: 1, Kind: circuitgraph.KindOutput, WeightedDegree: 2, Slots: circuitgraph.SlotC},
+		{SignalDegree: signalDegree("main.z", degree), ID: 3, Kind: circuitgraph.KindInput, WeightedDegree: 2, Slots: circuitgraph.SlotB, TwinGroup: 1, Visibility: circuitgraph.VisibilityPrivate},
+	}
+	if !reflect.DeepEqual(metrics, want) {
+		t.Errorf("signalMetrics() = %+v, want %+v", metrics, want)
+	}
+}
+
+// signalDegree returns degree for the given signal
+func signalDegree(signal string, degree SignalDegree) SignalDegree {
+	degree.Signal = signal
+	return degree
+}
+
+func TestWriteSignalMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns SignalColumns
+		want    string
+	}{
+		{
+			name:    "all columns",
+			columns: SignalColumns{Slots: true, Twins: true, Visibility: true},
+			want: "id,signal,kind,degree,weighted_degree,degree_percentile,degree_z_score,slots,twin_group,visibility\n" +
+				"2,main.x,input,2,2,50.00,0.000,A,1,public\n" +
+				"1,main.y,output,2,2,50.00,0.000,C,,\n" +
+				"3,main.z,input,2,2,50.00,0.000,B,1,private\n",
+		},
+		{
+			name:    "without twins and visibility",
+			columns: SignalColumns{Slots: true},
+			want: "id,signal,kind,degree,weighted_degree,degree_percentile,degree_z_score,slots\n" +
+				"2,main.x,input,2,2,50.00,0.000,A\n" +
+				"1,main.y,output,2,2,50.00,0.000,C\n" +
+				"3,main.z,input,2,2,50.00,0.000,B\n",
+		},
+		{
+			name: "quick mode",
+			want: "id,signal,kind,degree,weighted_degree,degree_percentile,degree_z_score\n" +
+				"2,main.x,input,2,2,50.00,0.000\n" +
+				"1,main.y,output,2,2,50.00,0.000\n" +
+				"3,main.z,input,2,2,50.00,0.000\n",
+		},
+	}
+	metrics := squareMetrics(t)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := writeSignalMetrics(dir, metrics, test.columns, "Square"); err != nil {
+				t.Fatal(err)
+			}
+			content, err := os.ReadFile(filepath.Join(dir, "Square_signals.csv"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != test.want {
+				t.Errorf("Square_signals.csv =\n%s\nwant\n%s", content, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// SimilarityGroups returns the templates sharing a structural fingerprint,
+// groups of at least two analyzed templates, largest first. Members have the
+// same size and degree sequence, which makes them near-identical or copies.
+func SimilarityGroups(results Results) [][]TemplateResult {
+	index := make(map[string]int)
+	var groups [][]TemplateResult
+	for _, t := range results.Templates {
+		if t.Error != "" || t.Stats.Fingerprint == "" {
+			continue
+		}
+		i, ok := index[t.Stats.Fingerprint]
+		if !ok {
+			i = len(groups)
+			index[t.Stats.Fingerprint] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], t)
+	}
+
+	similar := groups[:0]
+	for _, group := range groups {
+		if len(group) > 1 {
+			similar = append(similar, group)
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool { return len(similar[i]) > len(similar[j]) })
+	return similar
+}
+
+// WriteSimilarityGroups lists the groups of structurally near-identical templates
+func WriteSimilarityGroups(w io.Writer, results Results) {
+	groups := SimilarityGroups(results)
+	if len(groups) == 0 {
+		fmt.Fprintln(w, "No structurally near-identical templates found.")
+		return
+	}
+	fmt.Fprintf(w, "Found %d group(s) of structurally near-identical templates:\n", len(groups))
+	for _, group := range groups {
+		fmt.Fprintf(w, "  %d signals, %d constraints:\n", group[0].Stats.Signals, group[0].Stats.Constraints)
+		for _, t := range group {
+			fmt.Fprintf(w, "    - %s in %s\n", t.Template, t.File)
+		}
+	}
+}
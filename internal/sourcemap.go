@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// generatedMainLocation replaces the locations of compiler errors in the main
+// component appended to a work file, which has no line in the source
+const generatedMainLocation = "generated main component"
+
+// workFileLocationRegexp matches the path of a work file as circom reports
+// it, quoted or not, with the line and column that may follow, e.g.
+// "/src/circuit-analyzer_Main_123456.circom":87:5
+var workFileLocationRegexp = regexp.MustCompile(`("?)([^\s"'()\[\]]*` + regexp.QuoteMeta(workFilePrefix) + `(?:[\w$]+_)?\d+\.circom)("?)(?::(\d+)(?::(\d+))?)?`)
+
+// mapWorkFileLocations rewrites the locations circom reports in a work file,
+// e.g. "/src/circuit-analyzer_Main_123456.circom":87:5, to the source file the
+// work file copies. The work file keeps the lines of the source where they
+// are, see CreateTempCircomFile, and any line past them belongs to the main
+// component appended for the analysis, whose locations are replaced by a
+// mention of it. It also reports whether any location is in that main
+// component. Other work files, e.g. of a concurrent analysis, are left as
+// they are.
+func mapWorkFileLocations(output, workFile, sourcePath string) (string, bool) {
+	if output == "" {
+		return output, false
+	}
+	sourceLines, err := countLines(sourcePath)
+	if err != nil {
+		return output, false
+	}
+	workFileName := filepath.Base(workFile)
+	inMain := false
+	mapped := workFileLocationRegexp.ReplaceAllStringFunc(output, func(match string) string {
+		groups := workFileLocationRegexp.FindStringSubmatch(match)
+		if filepath.Base(groups[2]) != workFileName {
+			return match
+		}
+		quote := groups[1]
+		if groups[3] == "" {
+			quote = ""
+		}
+		line, err := strconv.Atoi(groups[4])
+		if err == nil && line > sourceLines {
+			inMain = true
+			return generatedMainLocation
+		}
+		rewritten := quote + sourcePath + quote
+		if groups[4] != "" {
+			rewritten += ":" + groups[4]
+		}
+		if groups[5] != "" {
+			rewritten += ":" + groups[5]
+		}
+		return rewritten
+	})
+	return mapped, inMain
+}
+
+// countLines returns the number of lines of a file, the last one counting
+// even without a line break
+func countLines(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	lines := bytes.Count(content, []byte("\n"))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		lines++
+	}
+	return lines, nil
+}
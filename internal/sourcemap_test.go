@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMapWorkFileLocations(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "main.circom")
+	// Five lines, the work file appends the main component on line 7
+	if err := os.WriteFile(source, []byte("pragma circom 2.0.0;\n\ntemplate Main() {\n    signal input x;\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	workFile := filepath.Join(dir, "circuit-analyzer_Main_123456.circom")
+	other := filepath.Join(dir, "circuit-analyzer_Main_654321.circom")
+
+	tests := []struct {
+		name   string
+		output string
+		want   string
+		inMain bool
+	}{
+		{
+			name:   "quoted path with line and column",
+			output: "error[P1012]: illegal expression\n   ┌─ \"" + workFile + "\":4:5\n  │\n4 │     signal input x\n",
+			want:   "error[P1012]: illegal expression\n   ┌─ \"" + source + "\":4:5\n  │\n4 │     signal input x\n",
+		},
+		{
+			name:   "bare path",
+			output: "previous errors were found in " + workFile,
+			want:   "previous errors were found in " + source,
+		},
+		{
+			name:   "bare path with line",
+			output: "--> " + workFile + ":3",
+			want:   "--> " + source + ":3",
+		},
+		{
+			name:   "line past the source",
+			output: "error[T2021]: Calling symbol\n   ┌─ \"" + workFile + "\":7:16\n",
+			want:   "error[T2021]: Calling symbol\n   ┌─ " + generatedMainLocation + "\n",
+			inMain: true,
+		},
+		{
+			name:   "included file",
+			output: "   ┌─ \"" + filepath.Join(dir, "lib.circom") + "\":9:1\n",
+			want:   "   ┌─ \"" + filepath.Join(dir, "lib.circom") + "\":9:1\n",
+		},
+		{
+			name:   "another work file",
+			output: "   ┌─ \"" + other + "\":7:1\n",
+			want:   "   ┌─ \"" + other + "\":7:1\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, inMain := mapWorkFileLocations(test.output, workFile, source)
+			if got != test.want || inMain != test.inMain {
+				t.Errorf("mapWorkFileLocations() = %q, %v, want %q, %v", got, inMain, test.want, test.inMain)
+			}
+		})
+	}
+}
+
+func TestCreateTempCircomFileBlanksMain(t *testing.T) {
+	tests := []struct {
+		name, source string
+	}{
+		{"one line", "template Main() {\n}\n\ncomponent main = Main();\n"},
+		{"public signals", "template Main() {\n}\n\ncomponent main {public [a, b]} = Main(3);\n"},
+		{"multi-line public signals", "template Main() {\n}\n\ncomponent main {\n    public [\n        a,\n        b\n    ]\n} = Main(3);\n"},
+		{"multi-line arguments", "template Main() {\n}\n\ncomponent main = Main(\n    3,\n    [1, 2]\n);\n"},
+		{"trailing comment", "template Main() {\n}\ncomponent main = Main(); // entry point\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := filepath.Join(t.TempDir(), "main.circom")
+			if err := os.WriteFile(source, []byte(test.source), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			workFile, err := createTempCircomFile(source, "", workFilePrefix+"*.circom")
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := os.ReadFile(workFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Contains(content, []byte("component main")) || bytes.Contains(content, []byte("Main(")) && !bytes.Contains(content, []byte("template Main(")) {
+				t.Errorf("the main component was not blanked:\n%s", content)
+			}
+			if got, want := bytes.Count(content, []byte("\n")), strings.Count(test.source, "\n"); got != want {
+				t.Errorf("work file has %d lines, want %d:\n%s", got, want, content)
+			}
+			if !bytes.HasPrefix(content, []byte("template Main() {\n}\n")) {
+				t.Errorf("the template moved:\n%s", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+func printStats(w io.Writer, stats circuitgraph.Stats) {
+	fmt.Fprintf(w, "There are %d nodes (signals) in this graph.\n", stats.Signals)
+	fmt.Fprintf(w, "%d constraints reference signals %d times (%.2f references per signal).\n",
+		stats.Constraints, stats.SignalReferences, stats.ReuseRatio)
+	if stats.PublicInputs+stats.PrivateInputs > 0 {
+		fmt.Fprintf(w, "The main component has %d public and %d private input signals.\n", stats.PublicInputs, stats.PrivateInputs)
+	}
+	fmt.Fprintf(w, "As a constraint×signal matrix, %d entries are nonzero (density %.4f%%, sparsity %.4f%%), %.2f per constraint and %.2f per signal.\n",
+		stats.Nonzeros, 100*stats.Density, 100*(1-stats.Density), stats.NonzerosPerConstraint, stats.NonzerosPerSignal)
+	if stats.Components > 1 {
+		fmt.Fprintf(w, "Largest components: %d and %d signals, %.1f%% of signals are outside the largest component.\n",
+			stats.LargestComponent, stats.SecondLargestComponent, 100*stats.OutsideLargestFraction)
+	}
+	fmt.Fprintf(w, "The \"1\" signal shares constraints with %d signals (%.1f%%) and touches %.1f%% of the edges, %d constraints pin a single signal to constants.\n",
+		stats.ConstantDegree, 100*stats.ConstantCoverage, 100*stats.ConstantEdgeShare, stats.ConstantAssertions)
+	fmt.Fprintf(w, "Degree assortativity: %.3f.\n", stats.Assortativity)
+	if stats.Bipartite {
+		fmt.Fprintf(w, "The graph is bipartite, with %d and %d signals on either side.\n", len(stats.Partition[0]), len(stats.Partition[1]))
+	} else {
+		fmt.Fprintf(w, "The graph contains %d triangles.\n", stats.Triangles)
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// healthPenalties is what a finding of each severity takes off the health score of 100
+var healthPenalties = map[circuitgraph.Severity]int{
+	circuitgraph.SeverityInfo:     0,
+	circuitgraph.SeverityLow:      2,
+	circuitgraph.SeverityMedium:   5,
+	circuitgraph.SeverityHigh:     15,
+	circuitgraph.SeverityCritical: 40,
+}
+
+// healthScore rates a template from 100 (no findings) down to 0
+func healthScore(findings []circuitgraph.Finding) int {
+	score := 100
+	for _, finding := range findings {
+		score -= healthPenalties[finding.Severity]
+	}
+	return max(score, 0)
+}
+
+// templateHealth is the health score of a template, also when only the
+// counts of its findings were kept
+func templateHealth(t TemplateResult) int {
+	score := 100
+	for severity, count := range t.severities() {
+		score -= healthPenalties[severity] * count
+	}
+	return max(score, 0)
+}
+
+// compileTime formats the compilation time of a template, - if it was not
+// measured, e.g. for artifacts compiled elsewhere or with -deterministic
+func compileTime(t TemplateResult) string {
+	if t.CompileSeconds == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fs", t.CompileSeconds)
+}
+
+// WriteTable prints one aligned row per template
+func WriteTable(w io.Writer, results Results) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tTEMPLATE\tCONSTRAINTS\tNODES\tEDGES\tCOMPILE\tFINDINGS\tHEALTH")
+	for _, t := range results.Templates {
+		if t.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t%s\t-\tfailed\n", t.File, t.Template, compileTime(t))
+			continue
+		}
+		if t.Skipped != "" {
+			fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\t-\tskipped\n", t.File, t.Template)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%d\t%d\n", t.File, t.Template, t.Stats.Constraints, t.Stats.Signals, t.Stats.Edges,
+			compileTime(t), t.FindingCount(), templateHealth(t))
+	}
+	return tw.Flush()
+}
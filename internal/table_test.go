@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+func TestHealthScore(t *testing.T) {
+	tests := []struct {
+		severities []circuitgraph.Severity
+		want       int
+	}{
+		{nil, 100},
+		{[]circuitgraph.Severity{circuitgraph.SeverityInfo}, 100},
+		{[]circuitgraph.Severity{circuitgraph.SeverityLow, circuitgraph.SeverityMedium, circuitgraph.SeverityHigh}, 78},
+		{[]circuitgraph.Severity{circuitgraph.SeverityCritical, circuitgraph.SeverityCritical, circuitgraph.SeverityCritical}, 0},
+	}
+	for _, test := range tests {
+		var findings []circuitgraph.Finding
+		for _, severity := range test.severities {
+			findings = append(findings, circuitgraph.Finding{Severity: severity})
+		}
+		if got := healthScore(findings); got != test.want {
+			t.Errorf("healthScore(%v) = %d, want %d", test.severities, got, test.want)
+		}
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	results := sampleResults()
+	results.Templates[0].CompileSeconds = 1.25
+	results.Templates = append(results.Templates,
+		TemplateResult{File: "circuits/broken.circom", Template: "Broken", Error: "compile error", CompileSeconds: 0.5},
+		TemplateResult{File: "circuits/huge.circom", Skipped: "larger than 1 MiB"},
+	)
+	var out strings.Builder
+	if err := WriteTable(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	want := `FILE                    TEMPLATE  CONSTRAINTS  NODES  EDGES  COMPILE  FINDINGS  HEALTH
+circuits/square.circom  Square    1            2      0      1.2s     1         85
+circuits/cube.circom    Cube      2            3      0      -        1         98
+circuits/broken.circom  Broken    -            -      -      0.5s     -         failed
+circuits/huge.circom              -            -      -      -        -         skipped
+`
+	if out.String() != want {
+		t.Errorf("table =\n%s\nwant\n%s", out.String(), want)
+	}
+}
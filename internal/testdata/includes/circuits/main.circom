@@ -0,0 +1,14 @@
+pragma circom 2.0.0;
+
+include "square.circom";
+include "double.circom";
+
+template Main() {
+    signal input x;
+    signal output y;
+    component square = Square();
+    component double = Double();
+    square.x <== x;
+    double.x <== square.y;
+    y <== double.y;
+}
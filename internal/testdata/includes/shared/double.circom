@@ -0,0 +1,7 @@
+pragma circom 2.0.0;
+
+template Double() {
+    signal input x;
+    signal output y;
+    y <== 2 * x;
+}
@@ -0,0 +1,15 @@
+pragma circom 2.0.0;
+
+template Square() {
+    signal input x;
+    signal output y;
+    y <== x * x;
+}
+
+template Cube() {
+    signal input x;
+    signal output y;
+    signal x2;
+    x2 <== x * x;
+    y <== x2 * x;
+}
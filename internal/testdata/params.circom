@@ -0,0 +1,7 @@
+pragma circom 2.0.0;
+
+template Scale(n, k) {
+    signal input x;
+    signal output y;
+    y <== x * x * n + k;
+}
@@ -0,0 +1,437 @@
+package internal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// tuiSeverities are the minimum severities the severity filter cycles through, -1 for all
+var tuiSeverities = []int{-1, 1, 2, 3, 4}
+
+// TUI shows the templates of a run as they complete and, once the run is
+// done, lets the user browse their findings and metrics from the keyboard.
+// It only learns about templates as an Observer, safe for concurrent use, so
+// it sees the results the other frontends see. It draws with ANSI escape
+// sequences and reads keys with the terminal in raw mode, so browsing needs
+// a terminal that understands them, as those of Windows 10 and later do.
+type TUI struct {
+	mu      sync.Mutex
+	out     io.Writer
+	dir     string // Directory of the visualizations
+	rows    []tuiRow
+	index   map[string]int // Row of every file and template
+	started time.Time
+	height  int // Lines of the terminal
+	width   int // Columns of the terminal
+
+	// Browsing state, only used by Browse
+	cursor   int
+	scroll   int // First finding shown in the details
+	detail   bool
+	severity int    // Index in tuiSeverities
+	rule     string // Only templates and findings of this category, all if empty
+	rules    []string
+	status   string // Outcome of the last action
+}
+
+// tuiRow is a template of the run, running until its result arrives
+type tuiRow struct {
+	file     string
+	template string
+	done     bool
+	result   TemplateResult
+}
+
+// NewTUI creates a TUI drawing to out, a terminal, and opening the
+// visualizations written to dir
+func NewTUI(out io.Writer, dir string) *TUI {
+	height, width := terminalSize(out)
+	return &TUI{out: out, dir: dir, index: make(map[string]int), started: time.Now(), height: height, width: width}
+}
+
+func (t *TUI) TemplateStarted(file, template string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.index[file+"\x00"+template] = len(t.rows)
+	t.rows = append(t.rows, tuiRow{file: file, template: template})
+	t.drawProgress()
+}
+
+func (t *TUI) CompileFinished(template string, elapsed time.Duration, err error) {}
+
+func (t *TUI) TemplateFinished(result TemplateResult) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i, ok := t.index[result.File+"\x00"+result.Template]
+	if !ok {
+		// A file failing before its templates are read
+		i = len(t.rows)
+		t.rows = append(t.rows, tuiRow{file: result.File, template: result.Template})
+	}
+	t.rows[i].done = true
+	t.rows[i].result = result
+	t.drawProgress()
+}
+
+// drawProgress shows the latest templates started and how far the run is
+func (t *TUI) drawProgress() {
+	running, failed := 0, 0
+	for _, row := range t.rows {
+		if !row.done {
+			running++
+		} else if row.result.Error != "" {
+			failed++
+		}
+	}
+	lines := []string{fmt.Sprintf("Analyzing: %d running, %d done, %d failed, %s elapsed",
+		running, len(t.rows)-running, failed, time.Since(t.started).Round(time.Second)), ""}
+	first := max(0, len(t.rows)-(t.height-3))
+	for _, row := range t.rows[first:] {
+		lines = append(lines, row.line())
+	}
+	t.draw(lines)
+}
+
+// name is the file and template of the row, the file alone if it failed before its templates were read
+func (r tuiRow) name() string {
+	if r.template == "" {
+		return r.file
+	}
+	return r.file + ":" + r.template
+}
+
+// line describes a row in the template list
+func (r tuiRow) line() string {
+	name := r.name()
+	switch {
+	case !r.done:
+		return "  ...     " + name
+	case r.result.Error != "":
+		return "  failed  " + name
+	case r.result.Skipped != "":
+		return "  skipped " + name
+	default:
+		return fmt.Sprintf("  %-7d %s (health %d)", r.result.FindingCount(), name, templateHealth(r.result))
+	}
+}
+
+// draw replaces the screen with the lines, cut to the size of the terminal
+func (t *TUI) draw(lines []string) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for i, line := range lines {
+		if i >= t.height {
+			break
+		}
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		if runes := []rune(line); len(runes) > t.width {
+			line = string(runes[:t.width])
+		}
+		b.WriteString(line)
+	}
+	io.WriteString(t.out, b.String())
+}
+
+// Browse lets the user browse the results from the keyboard read from in
+// until q is pressed. It fails if in is a terminal that cannot be put in
+// raw mode.
+func (t *TUI) Browse(in io.Reader) error {
+	restore, err := rawTerminal(in)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	io.WriteString(t.out, "\x1b[?25l") // Hide the cursor
+	defer io.WriteString(t.out, "\x1b[?25h\x1b[H\x1b[2J")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sort.SliceStable(t.rows, func(i, j int) bool {
+		if t.rows[i].file != t.rows[j].file {
+			return t.rows[i].file < t.rows[j].file
+		}
+		return t.rows[i].template < t.rows[j].template
+	})
+	seen := make(map[string]bool)
+	for _, row := range t.rows {
+		for _, finding := range row.result.Findings {
+			if !seen[finding.Category] {
+				seen[finding.Category] = true
+				t.rules = append(t.rules, finding.Category)
+			}
+		}
+	}
+	sort.Strings(t.rules)
+
+	keys := bufio.NewReader(in)
+	for {
+		t.drawBrowser()
+		key, err := readKey(keys)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if !t.handle(key) {
+			return nil
+		}
+	}
+}
+
+// handle applies a key and reports whether to keep browsing
+func (t *TUI) handle(key string) bool {
+	visible := t.visible()
+	t.status = ""
+	switch key {
+	case "q", "ctrl-c":
+		return false
+	case "up", "k":
+		if t.detail {
+			t.scroll = max(0, t.scroll-1)
+		} else {
+			t.cursor = max(0, t.cursor-1)
+		}
+	case "down", "j":
+		if t.detail {
+			t.scroll++
+		} else {
+			t.cursor = min(len(visible)-1, t.cursor+1)
+		}
+	case "enter", "right", "l":
+		if len(visible) > 0 {
+			t.detail, t.scroll = true, 0
+		}
+	case "left", "h", "esc", "b", "backspace":
+		t.detail = false
+	case "s":
+		t.severity = (t.severity + 1) % len(tuiSeverities)
+		t.cursor, t.detail = 0, false
+	case "r":
+		t.rule = nextRule(t.rules, t.rule)
+		t.cursor, t.detail = 0, false
+	case "o":
+		if len(visible) > 0 {
+			t.status = t.open(visible[t.cursor])
+		}
+	}
+	return true
+}
+
+// nextRule returns the rule after current in the cycle of the rule filter,
+// empty for all rules
+func nextRule(rules []string, current string) string {
+	if current == "" {
+		if len(rules) == 0 {
+			return ""
+		}
+		return rules[0]
+	}
+	for i, rule := range rules {
+		if rule == current && i+1 < len(rules) {
+			return rules[i+1]
+		}
+	}
+	return ""
+}
+
+// shown reports whether a finding passes the severity and rule filters
+func (t *TUI) shown(severity int, category string) bool {
+	return severity >= tuiSeverities[t.severity] && (t.rule == "" || category == t.rule)
+}
+
+// visible returns the rows passing the filters, all rows if there are none
+func (t *TUI) visible() []tuiRow {
+	if t.severity == 0 && t.rule == "" {
+		return t.rows
+	}
+	var rows []tuiRow
+	for _, row := range t.rows {
+		for _, finding := range row.result.Findings {
+			if t.shown(finding.Severity.Rank(), finding.Category) {
+				rows = append(rows, row)
+				break
+			}
+		}
+	}
+	return rows
+}
+
+// drawBrowser shows the template list or the details of the selected template
+func (t *TUI) drawBrowser() {
+	visible := t.visible()
+	t.cursor = max(0, min(t.cursor, len(visible)-1))
+	severity, rule := "all", "all"
+	if minimum := tuiSeverities[t.severity]; minimum >= 0 {
+		severity = severityNames[minimum] + "+"
+	}
+	if t.rule != "" {
+		rule = t.rule
+	}
+	footer := fmt.Sprintf("up/down move  enter details  left back  s severity: %s  r rule: %s  o open graph  q quit", severity, rule)
+
+	var lines []string
+	if t.detail && len(visible) > 0 {
+		lines = t.details(visible[t.cursor])
+	} else {
+		lines = append(lines, fmt.Sprintf("%d of %d template(s)", len(visible), len(t.rows)), "")
+		room := max(1, t.height-4)
+		first := max(0, min(t.cursor-room/2, len(visible)-room))
+		for i := first; i < len(visible) && i < first+room; i++ {
+			marker := " "
+			if i == t.cursor {
+				marker = ">"
+			}
+			lines = append(lines, marker+visible[i].line())
+		}
+	}
+	for len(lines) < t.height-2 {
+		lines = append(lines, "")
+	}
+	lines = append(lines[:t.height-2], t.status, footer)
+	t.draw(lines)
+}
+
+// severityNames name the severities by rank
+var severityNames = []string{"info", "low", "medium", "high", "critical"}
+
+// details lists the metrics and the findings passing the filters of a
+// template, scrolled down to the first finding shown
+func (t *TUI) details(row tuiRow) []string {
+	r := row.result
+	lines := []string{row.name()}
+	if r.MainComponent != "" {
+		lines = append(lines, r.MainComponent)
+	}
+	if r.Error != "" {
+		return append(lines, "", "Failed: "+oneLine(r.Error))
+	}
+	if r.Skipped != "" {
+		return append(lines, "", "Skipped: "+r.Skipped)
+	}
+	s := r.Stats
+	lines = append(lines, "",
+		fmt.Sprintf("Constraints %d, signals %d, edges %d, density %.4f%%", s.Constraints, s.Signals, s.Edges, 100*s.Density),
+		fmt.Sprintf("Components %d, largest %d, algebraic connectivity %.4g, health %d", s.Components, s.LargestComponent, r.Connectivity.Fiedler, templateHealth(r)),
+		"")
+
+	var findings []string
+	for _, finding := range bySeverity(r.Findings) {
+		if t.shown(finding.Severity.Rank(), finding.Category) {
+			line := fmt.Sprintf("%-8s %s", finding.Severity, finding.Category)
+			if finding.Signal != "" {
+				line += " " + finding.Signal
+			}
+			findings = append(findings, line+": "+oneLine(finding.Message))
+		}
+	}
+	if len(findings) == 0 {
+		return append(lines, "No findings shown.")
+	}
+	t.scroll = min(t.scroll, len(findings)-1)
+	lines = append(lines, strconv.Itoa(len(findings))+" finding(s):")
+	return append(lines, findings[t.scroll:]...)
+}
+
+// open opens the visualization of a template in the browser and returns the
+// status to show
+func (t *TUI) open(row tuiRow) string {
+	path := outputFile(t.dir, row.result.OutputName()+"_circuit_graph.html")
+	if _, err := os.Stat(path); err != nil {
+		if _, err := os.Stat(outputFile(t.dir, row.result.OutputName()+"_circuit_graph.dot")); err == nil {
+			return "The graph of " + row.template + " was too large to render, it was written as DOT"
+		}
+		return "No visualization of " + row.template + ", run with -visualize"
+	}
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", path)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", path)
+	default:
+		cmd = exec.Command("xdg-open", path)
+	}
+	if err := cmd.Start(); err != nil {
+		return "Opening " + path + ": " + err.Error()
+	}
+	go cmd.Wait()
+	return "Opened " + path
+}
+
+// readKey reads a key press of the raw terminal, naming arrows and control keys
+func readKey(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	switch b {
+	case 3:
+		return "ctrl-c", nil
+	case '\r', '\n':
+		return "enter", nil
+	case 127, 8:
+		return "backspace", nil
+	case 0x1b:
+		// Arrows arrive as ESC [ A to D in a single read, a lone ESC is the key itself
+		if r.Buffered() < 2 {
+			return "esc", nil
+		}
+		if next, _ := r.ReadByte(); next != '[' && next != 'O' {
+			return "esc", nil
+		}
+		arrow, _ := r.ReadByte()
+		switch arrow {
+		case 'A':
+			return "up", nil
+		case 'B':
+			return "down", nil
+		case 'C':
+			return "right", nil
+		case 'D':
+			return "left", nil
+		}
+		return "esc", nil
+	}
+	return string(rune(b)), nil
+}
+
+// rawTerminal puts in in raw mode without echo if it is a terminal and
+// returns the function restoring its previous mode, doing nothing for other
+// readers such as scripted keys
+func rawTerminal(in io.Reader) (func(), error) {
+	f, ok := in.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return func() {}, nil
+	}
+	saved, err := term.MakeRaw(int(f.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("switching the terminal to raw mode: %w", err)
+	}
+	return func() { term.Restore(int(f.Fd()), saved) }, nil
+}
+
+// terminalSize returns the lines and columns of out, 24 by 80 if it is not a terminal
+func terminalSize(out io.Writer) (int, int) {
+	if f, ok := out.(*os.File); ok {
+		width, height, err := term.GetSize(int(f.Fd()))
+		if err == nil && height > 4 && width > 0 {
+			return height, width
+		}
+	}
+	return 24, 80
+}
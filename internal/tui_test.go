@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// newBrowsedTUI returns a TUI holding three finished templates: A with a high
+// underconstrained finding, B with an info hub finding and C with none
+func newBrowsedTUI(t *testing.T, out *bytes.Buffer) *TUI {
+	t.Helper()
+	tui := NewTUI(out, t.TempDir())
+	results := []TemplateResult{
+		{File: "c.circom", Template: "C"},
+		{File: "a.circom", Template: "A", Findings: []circuitgraph.Finding{
+			{Severity: circuitgraph.SeverityHigh, Category: circuitgraph.CategoryUnderconstrained, Signal: "main.x"},
+		}},
+		{File: "b.circom", Template: "B", Findings: []circuitgraph.Finding{
+			{Severity: circuitgraph.SeverityInfo, Category: circuitgraph.CategoryHub, Signal: "main.h"},
+		}},
+	}
+	for _, result := range results {
+		tui.TemplateStarted(result.File, result.Template)
+		tui.TemplateFinished(result)
+	}
+	return tui
+}
+
+// templates names the templates of rows
+func templates(rows []tuiRow) string {
+	var names []string
+	for _, row := range rows {
+		names = append(names, row.template)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTUIRows(t *testing.T) {
+	var out bytes.Buffer
+	tui := NewTUI(&out, t.TempDir())
+
+	tui.TemplateStarted("a.circom", "A")
+	if screen := out.String(); !strings.Contains(screen, "1 running, 0 done") || !strings.Contains(screen, "  ...     a.circom:A") {
+		t.Errorf("started screen = %q", screen)
+	}
+
+	out.Reset()
+	tui.TemplateFinished(TemplateResult{File: "a.circom", Template: "A", Findings: []circuitgraph.Finding{
+		{Severity: circuitgraph.SeverityHigh}, {Severity: circuitgraph.SeverityLow},
+	}})
+	if screen := out.String(); !strings.Contains(screen, "0 running, 1 done") || !strings.Contains(screen, "  2       a.circom:A (health 83)") {
+		t.Errorf("finished screen = %q", screen)
+	}
+
+	// A file failing before its templates are read gets a row of its own
+	out.Reset()
+	tui.TemplateFinished(TemplateResult{File: "b.circom", Error: "parse error"})
+	if screen := out.String(); !strings.Contains(screen, "1 failed") || !strings.Contains(screen, "  failed  b.circom") {
+		t.Errorf("failed screen = %q", screen)
+	}
+	if len(tui.rows) != 2 {
+		t.Errorf("got %d rows, want 2", len(tui.rows))
+	}
+}
+
+func TestTUIFilters(t *testing.T) {
+	tests := []struct {
+		keys string
+		want string
+	}{
+		{"", "A,B,C"},
+		{"s", "A"},         // low and above
+		{"ssss", ""},       // critical only
+		{"sssss", "A,B,C"}, // back to all
+		{"r", "B"},         // hub-signal, the first rule in order
+		{"rr", "A"},
+		{"rrr", "A,B,C"},
+		{"sr", ""}, // no hub finding of low severity or above
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		tui := newBrowsedTUI(t, &out)
+		if err := tui.Browse(strings.NewReader(test.keys + "q")); err != nil {
+			t.Fatalf("keys %q: %v", test.keys, err)
+		}
+		if got := templates(tui.visible()); got != test.want {
+			t.Errorf("keys %q show %q, want %q", test.keys, got, test.want)
+		}
+	}
+}
+
+func TestTUIKeys(t *testing.T) {
+	tests := []struct {
+		keys   string
+		cursor int
+		detail bool
+	}{
+		{"", 0, false},
+		{"jj", 2, false},
+		{"jjjj", 2, false}, // stops at the last template
+		{"\x1b[B\x1b[Bk", 1, false},
+		{"kk", 0, false},
+		{"j\r", 1, true},
+		{"j\x1b[C", 1, true},
+		{"j\r\x1b", 1, false}, // a lone escape goes back
+		{"j\rh", 1, false},
+		{"s\r", 0, true},
+	}
+	for _, test := range tests {
+		var out bytes.Buffer
+		tui := newBrowsedTUI(t, &out)
+		if err := tui.Browse(strings.NewReader(test.keys + "q")); err != nil {
+			t.Fatalf("keys %q: %v", test.keys, err)
+		}
+		if tui.cursor != test.cursor || tui.detail != test.detail {
+			t.Errorf("keys %q: cursor %d, detail %v, want %d and %v", test.keys, tui.cursor, tui.detail, test.cursor, test.detail)
+		}
+	}
+}
+
+func TestTUIBrowseDetails(t *testing.T) {
+	var out bytes.Buffer
+	tui := newBrowsedTUI(t, &out)
+	// The input ends without q, as when the terminal is closed
+	if err := tui.Browse(strings.NewReader("j\r")); err != nil {
+		t.Fatal(err)
+	}
+	screen := out.String()
+	for _, want := range []string{"> ", "b.circom:B", "1 finding(s):", "info     hub-signal main.h"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("screen does not show %q: %q", want, screen)
+		}
+	}
+}
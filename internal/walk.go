@@ -0,0 +1,201 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type WalkOptions struct {
+	FollowSymlinks bool // Descend into symlinked directories
+	MaxDepth       int  // Maximum directory depth below the input path, 0 for no limit
+}
+
+// WalkStats makes the directories and files left out of a walk visible
+type WalkStats struct {
+	DirsVisited     int
+	SkippedDepth    int // Directories beyond MaxDepth
+	SkippedSymlinks int // Symlinked directories not followed
+	SkippedCycles   int // Directories reached a second time through a symlink
+	SkippedFiles    int // .circom files below the directories beyond MaxDepth or not followed, not found through another path
+	DanglingLinks   int // Symlinks whose target does not exist
+}
+
+func (s WalkStats) String() string {
+	return fmt.Sprintf("visited %d directories, skipped %d beyond the depth limit, %d symlinked and %d already visited, "+
+		"left out %d .circom files below them and %d dangling links",
+		s.DirsVisited, s.SkippedDepth, s.SkippedSymlinks, s.SkippedCycles, s.SkippedFiles, s.DanglingLinks)
+}
+
+// walkCircomFiles collects the .circom files below root, leaving out the
+// temporary sources of the analysis. Directories are identified by device and
+// inode where available, so symlink cycles are visited only once.
+func walkCircomFiles(root string, options WalkOptions) ([]string, WalkStats, error) {
+	var files []string
+	var stats WalkStats
+	var skipped []string // Directories beyond MaxDepth or not followed
+	visited := make(map[fileKey]bool)
+
+	var walk func(dir string, depth int) error
+	walk = func(dir string, depth int) error {
+		info, err := os.Stat(dir)
+		if err != nil {
+			return err
+		}
+		key := fileKeyOf(dir, info)
+		if visited[key] {
+			stats.SkippedCycles++
+			return nil
+		}
+		visited[key] = true
+		stats.DirsVisited++
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			isDir := entry.IsDir()
+			if entry.Type()&os.ModeSymlink != 0 {
+				target, err := os.Stat(path)
+				if err != nil {
+					stats.DanglingLinks++
+					continue
+				}
+				if target.IsDir() {
+					if !options.FollowSymlinks {
+						stats.SkippedSymlinks++
+						skipped = append(skipped, path)
+						continue
+					}
+					isDir = true
+				}
+			}
+
+			if isDir {
+				if options.MaxDepth > 0 && depth >= options.MaxDepth {
+					stats.SkippedDepth++
+					skipped = append(skipped, path)
+					continue
+				}
+				if err := walk(path, depth+1); err != nil {
+					return err
+				}
+			} else if strings.HasSuffix(entry.Name(), ".circom") && !IsWorkFile(entry.Name()) {
+				files = append(files, path)
+			}
+		}
+		return nil
+	}
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, stats, err
+	}
+	if !info.IsDir() {
+		if strings.HasSuffix(info.Name(), ".circom") {
+			files = append(files, root)
+		}
+		return files, stats, nil
+	}
+
+	if err := walk(root, 0); err != nil {
+		return files, stats, err
+	}
+	stats.SkippedFiles = countSkippedFiles(skipped, files)
+	return files, stats, nil
+}
+
+// countSkippedFiles counts the .circom files below the given directories,
+// without following symlinks, that are not among the files found
+func countSkippedFiles(dirs, found []string) int {
+	seen := make(map[string]bool)
+	for _, file := range found {
+		if resolved, err := filepath.EvalSymlinks(file); err == nil {
+			seen[resolved] = true
+		}
+	}
+	count := 0
+	for _, dir := range dirs {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		filepath.WalkDir(resolved, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".circom") || IsWorkFile(entry.Name()) || seen[path] {
+				return nil
+			}
+			if entry.Type()&os.ModeSymlink != 0 {
+				if _, err := os.Stat(path); err != nil {
+					return nil // Dangling link
+				}
+			}
+			seen[path] = true
+			count++
+			return nil
+		})
+	}
+	return count
+}
+
+// StagedFiles returns the files staged for the next git commit, added or
+// modified, among the given ones. Each file is matched by its absolute path
+// against the staged paths of the repository containing it.
+func StagedFiles(files []string) ([]string, error) {
+	staged := make(map[string]bool)
+	roots := make(map[string]string) // Repository of every directory seen
+	listed := make(map[string]bool)  // Repositories whose staged files are known
+	var kept []string
+	for _, file := range files {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		root, ok := roots[filepath.Dir(abs)]
+		if !ok {
+			if root, err = gitOutput(filepath.Dir(abs), "rev-parse", "--show-toplevel"); err != nil {
+				return nil, fmt.Errorf("%s is not in a git repository: %w", file, err)
+			}
+			roots[filepath.Dir(abs)] = root
+		}
+		if !listed[root] {
+			listed[root] = true
+			names, err := gitOutput(root, "diff", "--cached", "--name-only", "-z", "--diff-filter=ACMR")
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range strings.Split(names, "\x00") { // Unquoted with -z
+				if name != "" {
+					staged[filepath.Join(root, filepath.FromSlash(name))] = true
+				}
+			}
+		}
+		if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = resolved
+		}
+		if staged[abs] {
+			kept = append(kept, file)
+		}
+	}
+	return kept, nil
+}
+
+// gitOutput runs git in dir and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeWalkTree writes a tree three levels deep, with a symlink back to its
+// root, a dangling link and a work file, and returns its root
+func writeWalkTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"a.circom", "sub/b.circom", "sub/deep/c.circom", "sub/notes.txt", "sub/" + workFilePrefix + "Main_123.circom"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("template T() {}\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(root, filepath.Join(root, "sub", "loop")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "missing.circom"), filepath.Join(root, "dangling.circom")); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestWalkCircomFiles(t *testing.T) {
+	root := writeWalkTree(t)
+	tests := []struct {
+		name    string
+		options WalkOptions
+		files   []string
+		stats   WalkStats
+	}{
+		{
+			name:  "default",
+			files: []string{"a.circom", "sub/b.circom", "sub/deep/c.circom"},
+			stats: WalkStats{DirsVisited: 3, SkippedSymlinks: 1, DanglingLinks: 1},
+		},
+		{
+			// The cycle comes back to the root, which is visited once
+			name:    "following symlinks",
+			options: WalkOptions{FollowSymlinks: true},
+			files:   []string{"a.circom", "sub/b.circom", "sub/deep/c.circom"},
+			stats:   WalkStats{DirsVisited: 3, SkippedCycles: 1, DanglingLinks: 1},
+		},
+		{
+			name:    "depth limit",
+			options: WalkOptions{MaxDepth: 1},
+			files:   []string{"a.circom", "sub/b.circom"},
+			stats:   WalkStats{DirsVisited: 2, SkippedDepth: 1, SkippedSymlinks: 1, SkippedFiles: 1, DanglingLinks: 1},
+		},
+		{
+			name:    "depth limit following symlinks",
+			options: WalkOptions{MaxDepth: 1, FollowSymlinks: true},
+			files:   []string{"a.circom", "sub/b.circom"},
+			stats:   WalkStats{DirsVisited: 2, SkippedDepth: 2, SkippedFiles: 1, DanglingLinks: 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			files, stats, err := walkCircomFiles(root, test.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, file := range files {
+				rel, err := filepath.Rel(root, file)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, test.files) {
+				t.Errorf("files = %v, want %v", got, test.files)
+			}
+			if stats != test.stats {
+				t.Errorf("stats = %+v, want %+v", stats, test.stats)
+			}
+		})
+	}
+}
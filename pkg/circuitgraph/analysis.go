@@ -0,0 +1,143 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// Finding categories reported by RunChecks and CheckOutputs
+const (
+	CategoryUnderconstrained = "underconstrained-signal"
+	CategorySubgraphs        = "independent-subgraphs"
+	CategoryNoOutputs        = "no-output-signals"
+)
+
+// Analysis holds the outcome of the checks run on a constraint graph
+type Analysis struct {
+	Underconstrained []string     `json:"underconstrained"`    // Signals with one or no connections
+	Subgraphs        [][]string   `json:"subgraphs,omitempty"` // Independent subgraphs after removing the "1" signal, if there is more than one
+	Blocks           []Block      `json:"blocks,omitempty"`    // Biconnected components after removing the "1" signal, largest first
+	Connectivity     Connectivity `json:"connectivity"`        // Algebraic connectivity of the largest component
+	Findings         []Finding    `json:"findings"`
+}
+
+// Analyze checks a graph built by BuildGraph and returns its findings
+func Analyze(g *CircuitGraph) []Finding {
+	return RunChecks(g).Findings
+}
+
+// RunChecks checks a graph built by BuildGraph for potentially underconstrained
+// signals and for independent subgraphs once the constant signal is removed,
+// decomposes the graph into its biconnected blocks and looks for a bottleneck
+// and for one or two edges holding together its largest component.
+func RunChecks(g *CircuitGraph) Analysis {
+	return runChecks(g, nil, false)
+}
+
+// RunQuickChecks only runs the cheap checks of RunChecks, for potentially
+// underconstrained signals and independent subgraphs, and leaves the blocks
+// and the connectivity of the analysis empty
+func RunQuickChecks(g *CircuitGraph) Analysis {
+	return runChecks(g, nil, true)
+}
+
+// runChecks runs the checks of RunChecks selected by checks, or only those
+// of RunQuickChecks in quick mode
+func runChecks(g *CircuitGraph, checks CheckSet, quick bool) Analysis {
+	var analysis Analysis
+
+	// Check for signals with one or no connections
+	if checks.Has(checkUnderconstrained) {
+		analysis.Underconstrained = FindUnderconstrainedSignals(g)
+		for _, signal := range analysis.Underconstrained {
+			analysis.Findings = append(analysis.Findings, Finding{
+				Category: CategoryUnderconstrained,
+				Severity: SeverityMedium,
+				Signal:   signal,
+				Message:  "signal has one or no connections",
+			})
+		}
+	}
+
+	// Check for independent subgraphs once the "1" signal is removed
+	if checks.Has(checkSubgraphs) {
+		if subgraphs := signalComponents(g); len(subgraphs) > 1 {
+			analysis.Findings = append(analysis.Findings, Finding{
+				Category: CategorySubgraphs,
+				Severity: SeverityMedium,
+				Message:  fmt.Sprintf("found %d independent subgraphs after removing the \"1\" signal", len(subgraphs)),
+			})
+			for _, subgraph := range subgraphs {
+				names := make([]string, 0, len(subgraph))
+				for _, node := range subgraph {
+					names = append(names, node.Name)
+				}
+				analysis.Subgraphs = append(analysis.Subgraphs, names)
+			}
+		}
+	}
+	if quick {
+		return analysis
+	}
+
+	if checks.Has(checkBlocks) {
+		analysis.Blocks = BiconnectedComponents(g)
+	}
+
+	// Check how close the largest component is to falling apart
+	if checks.Has(checkConnectivity) {
+		analysis.Connectivity = AlgebraicConnectivity(g)
+		analysis.Findings = append(analysis.Findings, checkBottleneck(analysis.Connectivity)...)
+		analysis.Findings = append(analysis.Findings, checkSplit(analysis.Connectivity.Split)...)
+	}
+
+	return analysis
+}
+
+// withoutConstant returns a copy of the graph without node 0, which is the "1" signal
+func withoutConstant(g *CircuitGraph) *simple.UndirectedGraph {
+	gc := simple.NewUndirectedGraph()
+	graph.Copy(gc, g.UndirectedGraph)
+	gc.RemoveNode(int64(0))
+	return gc
+}
+
+// signalComponents returns the connected components of the graph without the
+// constant signal. Synthetic nodes connect their members but are not listed.
+// Components and their signals are ordered by ID.
+func signalComponents(g *CircuitGraph) [][]*NamedNode {
+	gc := withoutConstant(g)
+
+	var components [][]*NamedNode
+	for _, component := range topo.ConnectedComponents(gc) {
+		var signals []*NamedNode
+		for _, node := range sortedNodes(component) {
+			if namedNode := node.(*NamedNode); !namedNode.Synthetic() {
+				signals = append(signals, namedNode)
+			}
+		}
+		if len(signals) > 0 {
+			components = append(components, signals)
+		}
+	}
+	sort.Slice(components, func(i, j int) bool { return components[i][0].ID() < components[j][0].ID() })
+	return components
+}
+
+// FindUnderconstrainedSignals returns the signals with one or no connections
+func FindUnderconstrainedSignals(graph *CircuitGraph) []string {
+	underconstrained := []string{}
+	nodes := graph.Nodes()
+	for nodes.Next() {
+		n := nodes.Node().(*NamedNode)
+		if !n.Synthetic() && graph.SignalDegree(n.ID()) <= 1 {
+			underconstrained = append(underconstrained, n.Name)
+		}
+	}
+	sort.Strings(underconstrained)
+	return underconstrained
+}
@@ -0,0 +1,138 @@
+package circuitgraph
+
+import "errors"
+
+// AnalyzeOptions selects the checks AnalyzeGraph runs besides the ones of
+// RunChecks and the constraint checks, which run unless Checks leaves them out
+type AnalyzeOptions struct {
+	Kinds           map[string]SignalKind  // Signals declared by the template by name, nil if its source is unknown
+	Lines           map[string]int         // Lines declaring the signals of Kinds, quoted in findings, may be nil
+	HubThreshold    float64                // Report hubs covering more than this percentage of the signals, 0 to skip
+	PinnedThreshold float64                // Report signals with at least this share of their constraints mentioning "1", 0 to skip
+	HotSpots        int                    // Number of edges with the highest betweenness to return, 0 to skip
+	PatternMembers  int                    // Smallest array family compared for repeated patterns, 0 to skip
+	Coloring        bool                   // Color the signals greedily, costly on dense graphs
+	Remove          func(name string) bool // Signals removed for a what-if component analysis, nil to skip
+	Quick           bool                   // Only run RunQuickChecks and the checks on inputs, ignoring the other options
+	Checks          CheckSet               // Analyses to run, among those the other options enable, all of them if nil
+
+	// Header of the r1cs file circom wrote along the constraints, telling the
+	// public and private inputs of the main component, nil if unknown
+	Header *R1CSHeader
+}
+
+// AnalysisResult is everything AnalyzeGraph learned about a graph
+type AnalysisResult struct {
+	Analysis
+	Stats    Stats             `json:"stats"`
+	HotSpots []HotSpot         `json:"hot_spots,omitempty"`
+	Hubs     []Hub             `json:"hubs,omitempty"`
+	Patterns []Pattern         `json:"patterns,omitempty"`
+	Coloring *Coloring         `json:"coloring,omitempty"`
+	Twins    []TwinGroup       `json:"twins,omitempty"`
+	Removal  *Removal          `json:"removal,omitempty"`
+	Slots    map[int64]SlotUse `json:"-"` // Slots every signal appears in, keyed by signal ID
+
+	// Inputs of the main component with their visibility, if options.Header is known
+	Inputs []InputSignal `json:"inputs,omitempty"`
+}
+
+// AnalyzeGraph runs every enabled check on a graph built by BuildGraph from
+// the constraints and signal names and returns the results without printing
+// anything. Findings come in a fixed order: those of RunChecks, then the
+// declared outputs and inputs, the slot usage, the pinned signals, the linear
+// outputs, the twin signals, the hubs and the possibly vacuous constraints.
+// The checks needing the declared signals of the template are skipped if
+// options.Kinds is nil, and options of 0 or less skip their check, as do the
+// analyses options.Checks leaves out. Without the stats analysis, only the
+// constraint, signal, edge and component counts of the statistics are filled
+// in. It fails if there is no graph.
+//
+// In quick mode, only the findings of RunQuickChecks and of the inputs are
+// returned, along with the statistics.
+//
+// With options.Header, the inputs are listed with their visibility, counted
+// in the statistics, and the findings leaving a public input unconstrained
+// are more severe than those on a private one, see MarkInputVisibility.
+func AnalyzeGraph(g *CircuitGraph, constraints Constraints, signals map[int64]string, options AnalyzeOptions) (AnalysisResult, error) {
+	result, err := analyzeGraph(g, constraints, signals, options)
+	if err != nil || options.Header == nil {
+		return result, err
+	}
+	result.Inputs = InputVisibilities(signals, *options.Header)
+	for _, input := range result.Inputs {
+		if input.Visibility == VisibilityPublic {
+			result.Stats.PublicInputs++
+		} else {
+			result.Stats.PrivateInputs++
+		}
+	}
+	MarkInputVisibility(result.Findings, result.Inputs)
+	return result, nil
+}
+
+// analyzeGraph is AnalyzeGraph before the visibility of the inputs is applied
+func analyzeGraph(g *CircuitGraph, constraints Constraints, signals map[int64]string, options AnalyzeOptions) (AnalysisResult, error) {
+	var result AnalysisResult
+	if g == nil {
+		return result, errors.New("no graph to analyze")
+	}
+	checks := options.Checks
+
+	if checks.Has(checkStats) {
+		result.Stats = ComputeStats(constraints, g)
+	} else {
+		result.Stats = Stats{Constraints: len(constraints), Signals: g.SignalCount(), Edges: g.Edges().Len()}
+		result.Stats.countComponents(g)
+	}
+	result.Analysis = runChecks(g, checks, options.Quick)
+	if options.Quick {
+		if checks.Has(checkInputs) {
+			result.Findings = append(result.Findings, CheckInputs(g, signals, options.Kinds, options.Lines)...)
+		}
+		return result, nil
+	}
+	result.Slots = SignalSlots(constraints)
+	if options.Remove != nil {
+		removal := RemoveSignals(g, options.Remove)
+		result.Removal = &removal
+	}
+	if options.HotSpots > 0 && checks.Has(checkHotSpots) {
+		result.HotSpots = EdgeHotSpots(g, options.HotSpots)
+	}
+	if options.PatternMembers > 0 && checks.Has(checkPatterns) {
+		result.Patterns = RepeatedPatterns(g, options.PatternMembers)
+	}
+	if options.Coloring && checks.Has(checkColoring) {
+		coloring := GreedyColoring(g)
+		result.Coloring = &coloring
+	}
+
+	if options.Kinds != nil && checks.Has(checkOutputs) {
+		result.Findings = append(result.Findings, CheckOutputs(options.Kinds)...)
+	}
+	if checks.Has(checkInputs) {
+		result.Findings = append(result.Findings, CheckInputs(g, signals, options.Kinds, options.Lines)...)
+	}
+	if checks.Has(checkSlots) {
+		result.Findings = append(result.Findings, CheckSlotUsage(result.Slots, signals, options.Kinds)...)
+	}
+	if options.PinnedThreshold > 0 && checks.Has(checkPinned) {
+		result.Findings = append(result.Findings, CheckPinnedSignals(result.Slots, signals, options.PinnedThreshold)...)
+	}
+	if checks.Has(checkLinearOutputs) {
+		result.Findings = append(result.Findings, CheckLinearOutputs(constraints, signals, options.Kinds)...)
+	}
+	if checks.Has(checkTwins) {
+		result.Twins = TwinGroups(constraints, signals)
+		result.Findings = append(result.Findings, CheckTwins(result.Twins)...)
+	}
+	if options.HubThreshold > 0 && checks.Has(checkHubs) {
+		result.Hubs = FindHubs(g, options.Kinds, options.HubThreshold)
+		result.Findings = append(result.Findings, CheckHubs(result.Hubs)...)
+	}
+	if checks.Has(checkVacuous) {
+		result.Findings = append(result.Findings, CheckVacuousConstraints(constraints)...)
+	}
+	return result, nil
+}
@@ -0,0 +1,44 @@
+package circuitgraph
+
+import "testing"
+
+// twoIslands has two constraints sharing no signal: 1*a = b and 1*c = d
+var twoIslands = Constraints{
+	{{1}, {1}, {2}},
+	{{3}, {3}, {4}},
+}
+
+var twoIslandsSignals = map[int64]string{0: "one", 1: "main.a", 2: "main.b", 3: "main.c", 4: "main.d"}
+
+func TestAnalyzeGraphComponents(t *testing.T) {
+	g, err := BuildGraph(twoIslands, twoIslandsSignals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		checks string
+	}{
+		{"all checks", ""},
+		{"with stats", "stats"},
+		{"without stats", "underconstrained,vacuous"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checks, err := ParseChecks(test.checks)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := AnalyzeGraph(g, twoIslands, twoIslandsSignals, AnalyzeOptions{Checks: checks})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Stats.Components != 2 || result.Stats.Connected {
+				t.Errorf("components = %d, connected = %v, want 2 and false", result.Stats.Components, result.Stats.Connected)
+			}
+			if result.Stats.LargestComponent != 2 {
+				t.Errorf("largest component = %d, want 2", result.Stats.LargestComponent)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package circuitgraph
+
+import (
+	"math/rand"
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// Graphs with more nodes than exactBetweennessLimit get their edge
+// betweenness estimated from betweennessSamples source nodes
+const (
+	exactBetweennessLimit = 2000
+	betweennessSamples    = 500
+)
+
+// HotSpot is an edge that many shortest paths between signals run through
+type HotSpot struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Betweenness float64 `json:"betweenness"`           // Shortest paths through the edge, each pair of nodes counted once
+	Approximate bool    `json:"approximate,omitempty"` // Estimated from a sample of source nodes
+	Constraints []int   `json:"constraints"`           // Indices of the constraints that induced the edge
+}
+
+// EdgeHotSpots returns the n edges of the graph without the constant signal
+// with the highest betweenness, highest first. Large graphs get an estimate
+// from a fixed sample of source nodes, marked as approximate.
+func EdgeHotSpots(g *CircuitGraph, n int) []HotSpot {
+	gc := withoutConstant(g)
+	nodes := sortedNodes(graph.NodesOf(gc.Nodes()))
+	if n <= 0 || len(nodes) < 2 {
+		return nil
+	}
+
+	sources := nodes
+	approximate := len(nodes) > exactBetweennessLimit
+	if approximate {
+		// A fixed seed keeps results reproducible between runs
+		random := rand.New(rand.NewSource(1))
+		sources = make([]graph.Node, betweennessSamples)
+		for i, j := range random.Perm(len(nodes))[:betweennessSamples] {
+			sources[i] = nodes[j]
+		}
+	}
+
+	betweenness := make(map[edgeKey]float64)
+	for _, source := range sources {
+		accumulateEdgeBetweenness(gc, source, betweenness)
+	}
+	// Every pair is seen from both ends, and a sample covers a share of the sources
+	factor := 0.5 * float64(len(nodes)) / float64(len(sources))
+
+	keys := make([]edgeKey, 0, len(betweenness))
+	for key := range betweenness {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if betweenness[keys[i]] != betweenness[keys[j]] {
+			return betweenness[keys[i]] > betweenness[keys[j]]
+		}
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+
+	hotSpots := make([]HotSpot, 0, len(keys))
+	for _, key := range keys {
+		hotSpots = append(hotSpots, HotSpot{
+			From:        gc.Node(key[0]).(*NamedNode).Name,
+			To:          gc.Node(key[1]).(*NamedNode).Name,
+			Betweenness: betweenness[key] * factor,
+			Approximate: approximate,
+			Constraints: g.Provenance(key[0], key[1]),
+		})
+	}
+	return hotSpots
+}
+
+// accumulateEdgeBetweenness adds the dependencies of source on every edge,
+// following Brandes' algorithm on the unweighted graph
+func accumulateEdgeBetweenness(g graph.Undirected, source graph.Node, betweenness map[edgeKey]float64) {
+	distance := map[int64]int{source.ID(): 0}
+	paths := map[int64]float64{source.ID(): 1}
+	predecessors := make(map[int64][]int64)
+	order := []int64{source.ID()}
+
+	for i := 0; i < len(order); i++ {
+		v := order[i]
+		neighbors := g.From(v)
+		for neighbors.Next() {
+			w := neighbors.Node().ID()
+			if _, seen := distance[w]; !seen {
+				distance[w] = distance[v] + 1
+				order = append(order, w)
+			}
+			if distance[w] == distance[v]+1 {
+				paths[w] += paths[v]
+				predecessors[w] = append(predecessors[w], v)
+			}
+		}
+	}
+
+	dependency := make(map[int64]float64, len(order))
+	for i := len(order) - 1; i > 0; i-- {
+		w := order[i]
+		for _, v := range predecessors[w] {
+			share := paths[v] / paths[w] * (1 + dependency[w])
+			betweenness[newEdgeKey(v, w)] += share
+			dependency[v] += share
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package circuitgraph
+
+import (
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// maxBlockPrefixes is the number of representative prefixes listed per block
+const maxBlockPrefixes = 3
+
+// Block is a biconnected component of the constraint graph: removing any
+// single one of its signals leaves the rest of the block connected.
+type Block struct {
+	Signals    []string `json:"signals"`              // Members of the block, sorted by name
+	Separators []string `json:"separators,omitempty"` // Members shared with other blocks (cut vertices), sorted by name
+	Prefixes   []string `json:"prefixes,omitempty"`   // Most common component paths of the members, e.g. main.hasher
+}
+
+// BiconnectedComponents returns the blocks of the graph without the constant
+// signal, largest first. Blocks sharing a separator are adjacent in the
+// block-cut tree. Under the star projection, constraint nodes are members
+// like any other node.
+func BiconnectedComponents(g *CircuitGraph) []Block {
+	gc := withoutConstant(g)
+
+	var components [][]graph.Node
+	visited := make(map[int64]bool)
+	nodes := sortedNodes(graph.NodesOf(gc.Nodes()))
+	for _, root := range nodes {
+		if !visited[root.ID()] {
+			components = append(components, biconnectedFrom(gc, root, visited)...)
+		}
+	}
+
+	// A node in several blocks separates them
+	membership := make(map[int64]int)
+	for _, component := range components {
+		for _, node := range component {
+			membership[node.ID()]++
+		}
+	}
+
+	blocks := make([]Block, 0, len(components))
+	for _, component := range components {
+		var block Block
+		for _, node := range component {
+			name := node.(*NamedNode).Name
+			block.Signals = append(block.Signals, name)
+			if membership[node.ID()] > 1 {
+				block.Separators = append(block.Separators, name)
+			}
+		}
+		sort.Strings(block.Signals)
+		sort.Strings(block.Separators)
+		block.Prefixes = CommonPrefixes(block.Signals, maxBlockPrefixes)
+		blocks = append(blocks, block)
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if len(blocks[i].Signals) != len(blocks[j].Signals) {
+			return len(blocks[i].Signals) > len(blocks[j].Signals)
+		}
+		return blocks[i].Signals[0] < blocks[j].Signals[0]
+	})
+	return blocks
+}
+
+// biconnectedFrom runs Tarjan's algorithm on the connected component of root.
+// The depth-first search keeps its own stack, as circuits can be deep enough
+// to make recursion costly.
+func biconnectedFrom(g graph.Undirected, root graph.Node, visited map[int64]bool) [][]graph.Node {
+	type frame struct {
+		node      int64
+		parent    int64
+		hasParent bool
+		neighbors []graph.Node
+		next      int
+	}
+	type edge [2]int64
+
+	discovery := make(map[int64]int)
+	low := make(map[int64]int)
+	var edges []edge
+	var components [][]graph.Node
+
+	visit := func(id, parent int64, hasParent bool) frame {
+		visited[id] = true
+		discovery[id] = len(discovery)
+		low[id] = discovery[id]
+		return frame{node: id, parent: parent, hasParent: hasParent, neighbors: sortedNodes(graph.NodesOf(g.From(id)))}
+	}
+
+	stack := []frame{visit(root.ID(), 0, false)}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(top.neighbors) {
+			w := top.neighbors[top.next].ID()
+			top.next++
+			switch {
+			case top.hasParent && w == top.parent:
+				// The tree edge back to the parent
+			case !visited[w]:
+				edges = append(edges, edge{top.node, w})
+				stack = append(stack, visit(w, top.node, true))
+			case discovery[w] < discovery[top.node]:
+				edges = append(edges, edge{top.node, w})
+				low[top.node] = min(low[top.node], discovery[w])
+			}
+			continue
+		}
+
+		done := *top
+		stack = stack[:len(stack)-1]
+		if !done.hasParent {
+			continue
+		}
+		parent := done.parent
+		low[parent] = min(low[parent], low[done.node])
+		if low[done.node] < discovery[parent] {
+			continue
+		}
+
+		// parent separates the subtree of done.node, which closes a block
+		members := make(map[int64]struct{})
+		for {
+			e := edges[len(edges)-1]
+			edges = edges[:len(edges)-1]
+			members[e[0]] = struct{}{}
+			members[e[1]] = struct{}{}
+			if e == (edge{parent, done.node}) {
+				break
+			}
+		}
+		component := make([]graph.Node, 0, len(members))
+		for id := range members {
+			component = append(component, g.Node(id))
+		}
+		components = append(components, component)
+	}
+	return components
+}
+
+// sortedNodes orders nodes by ID, which keeps the traversal deterministic
+func sortedNodes(nodes []graph.Node) []graph.Node {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID() < nodes[j].ID() })
+	return nodes
+}
+
+// CommonPrefixes returns up to n component paths (the name up to its last
+// dot) shared by most of the names, most frequent first. It summarizes sets
+// of signals too large to list.
+func CommonPrefixes(names []string, n int) []string {
+	counts := make(map[string]int)
+	for _, name := range names {
+		if dot := strings.LastIndex(name, "."); dot > 0 {
+			counts[name[:dot]]++
+		}
+	}
+	prefixes := make([]string, 0, len(counts))
+	for prefix := range counts {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool {
+		if counts[prefixes[i]] != counts[prefixes[j]] {
+			return counts[prefixes[i]] > counts[prefixes[j]]
+		}
+		return prefixes[i] < prefixes[j]
+	})
+	if len(prefixes) > n {
+		prefixes = prefixes[:n]
+	}
+	return prefixes
+}
@@ -0,0 +1,94 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Names of the analyses of AnalyzeGraph, to select with ParseChecks
+const (
+	checkStats            = "stats"            // The statistics beyond the constraint, signal, edge and component counts
+	checkUnderconstrained = "underconstrained" // Signals with one or no connections
+	checkSubgraphs        = "subgraphs"        // Independent subgraphs
+	checkBlocks           = "blocks"           // Biconnected blocks
+	checkConnectivity     = "connectivity"     // Algebraic connectivity, bottleneck and near-disconnection
+	checkOutputs          = "outputs"          // Templates declaring no outputs
+	checkInputs           = "inputs"           // Inputs constraining nothing or only other inputs
+	checkSlots            = "slots"            // Signals always in the same term of their constraints
+	checkPinned           = "pinned"           // Signals pinned to constants
+	checkLinearOutputs    = "linear-outputs"   // Outputs only linearly constrained
+	checkTwins            = "twins"            // Signals appearing in exactly the same constraints
+	checkHubs             = "hubs"             // Signals connected to a large share of the graph
+	checkHotSpots         = "hot-spots"        // Edges of the highest betweenness
+	checkPatterns         = "patterns"         // Repeated local structures of array signal families
+	checkColoring         = "coloring"         // Greedy coloring of the signals
+	checkVacuous          = "vacuous"          // Possibly vacuous constraints
+)
+
+// CheckNames lists the analyses ParseChecks accepts, in the order AnalyzeGraph
+// runs them
+var CheckNames = []string{
+	checkStats, checkUnderconstrained, checkSubgraphs, checkBlocks, checkConnectivity,
+	checkOutputs, checkInputs, checkSlots, checkPinned, checkLinearOutputs, checkTwins,
+	checkHubs, checkHotSpots, checkPatterns, checkColoring, checkVacuous,
+}
+
+// checkAll selects every analysis in a list given to ParseChecks
+const checkAll = "all"
+
+// expensiveChecks are the analyses whose cost grows fastest with the size of
+// the graph, left out unless a list given to ParseChecks names them
+var expensiveChecks = map[string]bool{
+	checkBlocks:       true,
+	checkConnectivity: true,
+	checkHotSpots:     true,
+}
+
+// CheckSet is the analyses AnalyzeGraph runs, all of them if nil
+type CheckSet map[string]bool
+
+// DefaultChecks returns the analyses ParseChecks selects for an empty list,
+// all but the expensive blocks, connectivity and hot-spots
+func DefaultChecks() CheckSet {
+	checks := make(CheckSet, len(CheckNames))
+	for _, name := range CheckNames {
+		if !expensiveChecks[name] {
+			checks[name] = true
+		}
+	}
+	return checks
+}
+
+// Has reports whether the analysis of the given name is selected
+func (s CheckSet) Has(name string) bool {
+	return s == nil || s[name]
+}
+
+// ParseChecks parses a comma-separated list of analyses from CheckNames, or
+// "all" for every one of them. An empty list selects DefaultChecks.
+func ParseChecks(list string) (CheckSet, error) {
+	if strings.TrimSpace(list) == "" {
+		return DefaultChecks(), nil
+	}
+	known := make(map[string]bool, len(CheckNames))
+	for _, name := range CheckNames {
+		known[name] = true
+	}
+	checks := make(CheckSet)
+	all := false
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == checkAll {
+			all = true
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown check %q, expected %s or some of %s", name, checkAll, strings.Join(CheckNames, ", "))
+		}
+		checks[name] = true
+	}
+	if all {
+		return nil, nil
+	}
+	return checks, nil
+}
@@ -0,0 +1,44 @@
+package circuitgraph
+
+import "testing"
+
+func TestParseChecks(t *testing.T) {
+	tests := []struct {
+		list    string
+		has     []string
+		hasNot  []string
+		wantErr bool
+	}{
+		{list: "", has: []string{checkStats, checkUnderconstrained, checkVacuous}, hasNot: []string{checkBlocks, checkConnectivity, checkHotSpots}},
+		{list: " ", has: []string{checkStats}, hasNot: []string{checkConnectivity}},
+		{list: "all", has: CheckNames},
+		{list: "stats,all", has: CheckNames},
+		{list: "connectivity", has: []string{checkConnectivity}, hasNot: []string{checkStats, checkBlocks}},
+		{list: " stats , hot-spots ", has: []string{checkStats, checkHotSpots}, hasNot: []string{checkTwins}},
+		{list: "stats,unknown", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.list, func(t *testing.T) {
+			checks, err := ParseChecks(test.list)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("parsed without error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range test.has {
+				if !checks.Has(name) {
+					t.Errorf("%s not selected", name)
+				}
+			}
+			for _, name := range test.hasNot {
+				if checks.Has(name) {
+					t.Errorf("%s selected", name)
+				}
+			}
+		})
+	}
+}
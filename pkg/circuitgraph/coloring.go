@@ -0,0 +1,64 @@
+package circuitgraph
+
+import "sort"
+
+// Coloring summarizes a greedy coloring of the signals, where signals sharing
+// a constraint get different colors. Every color class is a group of signals
+// no constraint relates, so the number of colors bounds from above how few
+// such groups the signals split into.
+type Coloring struct {
+	Signals      int `json:"signals"`       // Signals colored, all but the "1" signal
+	Colors       int `json:"colors"`        // Colors used, at least the chromatic number of the graph
+	LargestClass int `json:"largest_class"` // Signals of the most used color
+}
+
+// GreedyColoring colors the signals of the graph other than "1" in the
+// Welsh-Powell order, highest degree first and then by ID, giving each the
+// lowest color none of its neighbors has. Neighbors are looked up through
+// synthetic star nodes, so the result does not depend on the projection.
+// Finding the neighbors of every signal is costly on dense graphs.
+func GreedyColoring(g *CircuitGraph) Coloring {
+	type signal struct {
+		id        int64
+		neighbors map[int64]struct{}
+	}
+	var signals []signal
+	nodes := g.Nodes()
+	for nodes.Next() {
+		if node := nodes.Node().(*NamedNode); !node.Synthetic() && node.ID() != 0 {
+			signals = append(signals, signal{node.ID(), g.signalNeighbors(node.ID())})
+		}
+	}
+	sort.Slice(signals, func(i, j int) bool {
+		if len(signals[i].neighbors) != len(signals[j].neighbors) {
+			return len(signals[i].neighbors) > len(signals[j].neighbors)
+		}
+		return signals[i].id < signals[j].id
+	})
+
+	colors := make(map[int64]int, len(signals))
+	var classes []int
+	for _, s := range signals {
+		taken := make(map[int]bool)
+		for neighbor := range s.neighbors {
+			if color, ok := colors[neighbor]; ok {
+				taken[color] = true
+			}
+		}
+		color := 0
+		for taken[color] {
+			color++
+		}
+		colors[s.id] = color
+		if color == len(classes) {
+			classes = append(classes, 0)
+		}
+		classes[color]++
+	}
+
+	coloring := Coloring{Signals: len(signals), Colors: len(classes)}
+	for _, size := range classes {
+		coloring.LargestClass = max(coloring.LargestClass, size)
+	}
+	return coloring
+}
@@ -0,0 +1,332 @@
+package circuitgraph
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Each constraint is an array of three linear expressions. Each expression contains the signals used.
+type Constraints [][3][]int64
+
+// ParseOptions controls how malformed compiler output is handled
+type ParseOptions struct {
+	Strict bool             // Return an error instead of a warning
+	Warn   func(msg string) // Receives warnings in lenient mode, may be nil
+}
+
+// anomaly reports a malformed artifact. It is a ParseError in strict mode and a warning otherwise.
+func (o ParseOptions) anomaly(file string, offset int64, format string, args ...any) error {
+	err := &ParseError{File: file, Offset: offset, Reason: fmt.Sprintf(format, args...)}
+	if o.Strict {
+		return err
+	}
+	if o.Warn != nil {
+		o.Warn(err.Error())
+	}
+	return nil
+}
+
+// LoadFromJson reads the constraints of a circom --json output file, which
+// may be gzip-compressed
+func LoadFromJson(constraintsFile string, options ParseOptions) (Constraints, error) {
+	return LoadFromJsonContext(context.Background(), constraintsFile, options)
+}
+
+// LoadFromJsonContext is LoadFromJson, stopping with ctx.Err() once ctx is
+// done, even in the middle of decoding the file
+func LoadFromJsonContext(ctx context.Context, constraintsFile string, options ParseOptions) (Constraints, error) {
+	// Variable to hold the unmarshaled data
+	var constraints Constraints
+
+	file, err := openArtifact(constraintsFile)
+	if err != nil {
+		return constraints, err
+	}
+	defer file.Close()
+
+	// Temp variable to hold the unmarshaled data
+	var tempData struct {
+		Constraints [][3]map[string]string `json:"constraints"`
+	}
+	err = json.NewDecoder(contextReader{ctx, file}).Decode(&tempData)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		parseErr := &ParseError{File: constraintsFile, Offset: -1, Reason: err.Error()}
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) {
+			parseErr.Offset = syntaxErr.Offset
+		} else if errors.As(err, &typeErr) {
+			parseErr.Offset = typeErr.Offset
+		}
+		return constraints, parseErr
+	}
+
+	// Convert keys from string to integers
+	for c, tempConstraint := range tempData.Constraints {
+		if c%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		var intConstraints [3][]int64
+		for i, linearExpression := range tempConstraint {
+			for key := range linearExpression {
+				intKey, err := stringToInt(key)
+				if err != nil {
+					if err := options.anomaly(constraintsFile, -1, "constraint %d references unparseable signal %q", c, key); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				intConstraints[i] = append(intConstraints[i], intKey)
+			}
+		}
+		constraints = append(constraints, intConstraints)
+	}
+
+	return constraints, nil
+}
+
+// LoadFromSym reads the signal names of a circom --sym output file, keyed by
+// signal ID. The constant signal "1" has ID 0. Every line lists the signal,
+// witness and component indices and the name of a signal. Constraints
+// reference witness indices, so a signal is keyed by its witness index, and
+// signals the simplification removed, with witness index -1, are left out.
+// Lines without a valid witness index fall back to their position.
+func LoadFromSym(symFile string, options ParseOptions) (map[int64]string, error) {
+	table, err := LoadSymTable(symFile, options)
+	return table.Signals, err
+}
+
+// SymTable is the content of a circom --sym output file
+type SymTable struct {
+	Signals map[int64]string // Names by witness index, as returned by LoadFromSym
+	Removed []string         // Names of the signals the simplification removed, in file order
+}
+
+// LoadSymTable reads a circom --sym output file like LoadFromSym, and also
+// keeps the names of the signals the simplification removed. They have no
+// witness index, so no constraint can reference them.
+func LoadSymTable(symFile string, options ParseOptions) (SymTable, error) {
+	return LoadSymTableContext(context.Background(), symFile, options)
+}
+
+// LoadSymTableContext is LoadSymTable, stopping with ctx.Err() once ctx is
+// done
+func LoadSymTableContext(ctx context.Context, symFile string, options ParseOptions) (SymTable, error) {
+	signals := make(map[int64]string)
+	var removed []string
+
+	// Open the file
+	file, err := openArtifact(symFile)
+	if err != nil {
+		return SymTable{Signals: signals, Removed: removed}, err
+	}
+	defer file.Close()
+
+	// Create a new CSV reader
+	reader := csv.NewReader(contextReader{ctx, file})
+	reader.Comma = ','          // Set the delimiter to a comma (default)
+	reader.FieldsPerRecord = -1 // Short rows are reported below instead of failing the whole file
+
+	// Ensure index 0 has "1"
+	signals[0] = "1"
+
+	// Loop through each record and extract the witness index (2nd column) and name (4th column)
+	for i := 0; ; i++ {
+		offset := reader.InputOffset()
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return SymTable{}, ctxErr
+		}
+		if err != nil {
+			return SymTable{}, &ParseError{File: symFile, Offset: offset, Reason: err.Error()}
+		}
+		if len(record) < 4 {
+			if err := options.anomaly(symFile, offset, "line %d has %d columns, expected 4", i+1, len(record)); err != nil {
+				return SymTable{}, err
+			}
+			// Keep the positions of the following signals intact
+			signals[int64(i+1)] = fmt.Sprintf("signal_%d", i+1)
+			continue
+		}
+		name := record[3] // The 'name' field is the 4th column (index 3)
+		witness, err := stringToInt(strings.TrimSpace(record[1]))
+		if err != nil {
+			if err := options.anomaly(symFile, offset, "line %d has witness index %q, expected an integer", i+1, record[1]); err != nil {
+				return SymTable{}, err
+			}
+			witness = int64(i + 1)
+		}
+		if witness < 0 {
+			// Removed by the simplification, no constraint can reference it
+			removed = append(removed, name)
+			continue
+		}
+		if previous, ok := signals[witness]; ok {
+			if err := options.anomaly(symFile, offset, "line %d maps %s to witness %d, already taken by %s", i+1, name, witness, previous); err != nil {
+				return SymTable{}, err
+			}
+		}
+		signals[witness] = name
+	}
+
+	return SymTable{Signals: signals, Removed: removed}, nil
+}
+
+// gzipMagic starts every gzip stream
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// openArtifact opens a compiler output, decompressing it on the fly if it is
+// gzipped, as recognized by its magic bytes rather than its extension
+func openArtifact(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	buffered := bufio.NewReader(file)
+	if magic, _ := buffered.Peek(len(gzipMagic)); !bytes.Equal(magic, gzipMagic) {
+		return readCloser{buffered, file}, nil
+	}
+	decompressed, err := gzip.NewReader(buffered)
+	if err != nil {
+		file.Close()
+		return nil, &ParseError{File: path, Offset: 0, Reason: err.Error()}
+	}
+	return readCloser{decompressed, file}, nil
+}
+
+// contextReader reads from r until ctx is done, then fails with ctx.Err(), so
+// that decoders reading from it stop in the middle of a large file
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
+
+// readCloser reads from a wrapper of a file and closes the file
+type readCloser struct {
+	io.Reader
+	file *os.File
+}
+
+func (r readCloser) Close() error {
+	return r.file.Close()
+}
+
+func stringToInt(s string) (int64, error) {
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// maxInconsistentIDs limits the signal IDs named in an artifact inconsistency
+const maxInconsistentIDs = 10
+
+// CheckSignalIDs verifies that every signal referenced by a constraint has a
+// name in the sym file. It is CheckArtifacts without the removed signals.
+func CheckSignalIDs(constraints Constraints, signals map[int64]string, options ParseOptions) error {
+	return CheckArtifacts(constraints, SymTable{Signals: signals}, options)
+}
+
+// CheckArtifacts verifies that the constraints and the sym file come from the
+// same compilation. Signal IDs referenced by constraints that no signal of the
+// sym file has as its witness index are reported together as one artifact
+// inconsistency naming them, the sign of artifacts mixed up between
+// compilations. If the sym file marks signals as removed by the
+// simplification, the report names a few of them, as the constraints may come
+// from a compilation at another simplification level. Gaps in the witness
+// indices of the sym file are only warned about.
+func CheckArtifacts(constraints Constraints, table SymTable, options ParseOptions) error {
+	missing := make(map[int64]bool)
+	for _, constraint := range constraints {
+		for _, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				if _, ok := table.Signals[signal]; !ok {
+					missing[signal] = true
+				}
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		problem := fmt.Sprintf("artifact inconsistency: constraints reference signal ID(s) %s missing from the sym file", listIDs(missing))
+		if len(table.Removed) > 0 {
+			problem += fmt.Sprintf(", which marks %d signal(s) as removed by the simplification (witness index -1), e.g. %s",
+				len(table.Removed), strings.Join(table.Removed[:min(len(table.Removed), 3)], ", "))
+		}
+		if err := options.anomaly("", -1, "%s", problem); err != nil {
+			return err
+		}
+	}
+
+	if gaps := witnessGaps(table.Signals); len(gaps) > 0 && options.Warn != nil {
+		options.Warn(fmt.Sprintf("the sym file has no signal with witness index %s", strings.Join(gaps, ", ")))
+	}
+	return nil
+}
+
+// listIDs lists the sorted IDs, up to maxInconsistentIDs
+func listIDs(ids map[int64]bool) string {
+	sorted := make([]int64, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var listed []string
+	for _, id := range sorted[:min(len(sorted), maxInconsistentIDs)] {
+		listed = append(listed, strconv.FormatInt(id, 10))
+	}
+	if len(sorted) > maxInconsistentIDs {
+		listed = append(listed, fmt.Sprintf("... (%d in total)", len(sorted)))
+	}
+	return strings.Join(listed, ", ")
+}
+
+// witnessGaps summarizes the witness indices below the highest one that no
+// signal has, as ranges such as "5-9", up to maxInconsistentIDs of them
+func witnessGaps(signals map[int64]string) []string {
+	ids := make([]int64, 0, len(signals))
+	for id := range signals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var gaps []string
+	next := int64(0)
+	for _, id := range ids {
+		if id > next {
+			if len(gaps) == maxInconsistentIDs {
+				gaps = append(gaps, "...")
+				break
+			}
+			if id == next+1 {
+				gaps = append(gaps, strconv.FormatInt(next, 10))
+			} else {
+				gaps = append(gaps, fmt.Sprintf("%d-%d", next, id-1))
+			}
+		}
+		next = id + 1
+	}
+	return gaps
+}
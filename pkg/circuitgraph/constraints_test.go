@@ -0,0 +1,210 @@
+package circuitgraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeChain writes the constraints and sym files of a chain of n
+// multiplications, s1 * s1 = s2, s2 * s2 = s3 and so on, to dir
+func writeChain(t *testing.T, dir string, n int) (string, string) {
+	t.Helper()
+	var constraints, sym strings.Builder
+	constraints.WriteString(`{"constraints":[`)
+	for i := 1; i <= n; i++ {
+		if i > 1 {
+			constraints.WriteString(",")
+		}
+		fmt.Fprintf(&constraints, `[{"%d":"1"},{"%d":"1"},{"%d":"1"}]`, i, i, i+1)
+	}
+	constraints.WriteString("]}")
+	for i := 1; i <= n+1; i++ {
+		fmt.Fprintf(&sym, "%d,%d,0,main.s%d\n", i, i, i)
+	}
+	constraintsFile := filepath.Join(dir, "chain_constraints.json")
+	symFile := filepath.Join(dir, "chain.sym")
+	if err := os.WriteFile(constraintsFile, []byte(constraints.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(symFile, []byte(sym.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return constraintsFile, symFile
+}
+
+func TestLoadContextCancelled(t *testing.T) {
+	constraintsFile, symFile := writeChain(t, t.TempDir(), 1000)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := LoadFromJsonContext(ctx, constraintsFile, ParseOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadFromJsonContext error = %v, want %v", err, context.Canceled)
+	}
+	if _, err := LoadSymTableContext(ctx, symFile, ParseOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadSymTableContext error = %v, want %v", err, context.Canceled)
+	}
+	constraints, err := LoadFromJson(constraintsFile, ParseOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuildGraphContext(ctx, constraints, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("BuildGraphContext error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := contextReader{ctx, strings.NewReader("constraints")}
+	buffer := make([]byte, 4)
+	if n, err := reader.Read(buffer); n != 4 || err != nil {
+		t.Fatalf("Read = %d, %v before cancellation", n, err)
+	}
+	cancel()
+	if n, err := reader.Read(buffer); n != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("Read = %d, %v after cancellation, want 0, %v", n, err, context.Canceled)
+	}
+}
+
+func TestLoadContextUncancelled(t *testing.T) {
+	constraintsFile, symFile := writeChain(t, t.TempDir(), 1000)
+	constraints, err := LoadFromJsonContext(context.Background(), constraintsFile, ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(constraints) != 1000 {
+		t.Errorf("loaded %d constraints, want 1000", len(constraints))
+	}
+	table, err := LoadSymTableContext(context.Background(), symFile, ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Signals) != 1002 {
+		t.Errorf("loaded %d signals, want 1002 with the constant", len(table.Signals))
+	}
+}
+
+func TestLoadSymTableWitnessMapping(t *testing.T) {
+	// The simplification removed main.tmp, so every later signal has a
+	// witness index below its signal index
+	table, err := LoadSymTable(filepath.Join("testdata", "diverging.sym"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSignals := map[int64]string{0: "1", 1: "main.out", 2: "main.a", 3: "main.b"}
+	if !reflect.DeepEqual(table.Signals, wantSignals) {
+		t.Errorf("signals = %v, want %v", table.Signals, wantSignals)
+	}
+	if wantRemoved := []string{"main.tmp"}; !reflect.DeepEqual(table.Removed, wantRemoved) {
+		t.Errorf("removed = %v, want %v", table.Removed, wantRemoved)
+	}
+
+	constraints, err := LoadFromJson(filepath.Join("testdata", "diverging_constraints.json"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckArtifacts(constraints, table, ParseOptions{Strict: true}); err != nil {
+		t.Fatal(err)
+	}
+	g, err := BuildGraph(constraints, table.Signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, node := range g.SortedNodes() {
+		names = append(names, node.Name)
+	}
+	if want := []string{"main.out", "main.a", "main.b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("nodes = %v, want %v", names, want)
+	}
+}
+
+func TestCheckArtifactsRemovedSignals(t *testing.T) {
+	// removed_last.sym removes main.tmp, the 4th signal, so signal index 4 has
+	// no witness, and mismatched_constraints.json references witness 4
+	table, err := LoadSymTable(filepath.Join("testdata", "removed_last.sym"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wantRemoved := []string{"main.tmp"}; !reflect.DeepEqual(table.Removed, wantRemoved) {
+		t.Errorf("removed = %v, want %v", table.Removed, wantRemoved)
+	}
+	constraints, err := LoadFromJson(filepath.Join("testdata", "mismatched_constraints.json"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CheckArtifacts(constraints, table, ParseOptions{Strict: true})
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("error = %v, want a ParseError", err)
+	}
+	if !strings.Contains(err.Error(), "signal ID(s) 4 missing from the sym file") {
+		t.Errorf("error = %v, want witness 4 reported as missing", err)
+	}
+	if strings.Contains(err.Error(), "4 (main.tmp)") {
+		t.Errorf("error = %v, names witness 4 after the signal of index 4", err)
+	}
+
+	// Witnesses 1 to 3 all exist, whatever the signal indices of their lines
+	if err := CheckArtifacts(constraints[:1], table, ParseOptions{Strict: true}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLoadGzipped(t *testing.T) {
+	plainConstraints, err := LoadFromJson(filepath.Join("testdata", "diverging_constraints.json"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainSym, err := LoadSymTable(filepath.Join("testdata", "diverging.sym"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Compressed files are recognized by their content, whatever their name
+	renamed := filepath.Join(t.TempDir(), "renamed_constraints.json")
+	content, err := os.ReadFile(filepath.Join("testdata", "diverging_constraints.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(renamed, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{filepath.Join("testdata", "diverging_constraints.json.gz"), renamed} {
+		constraints, err := LoadFromJson(file, ParseOptions{Strict: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(constraints, plainConstraints) {
+			t.Errorf("%s: constraints = %v, want %v", file, constraints, plainConstraints)
+		}
+	}
+	sym, err := LoadSymTable(filepath.Join("testdata", "diverging.sym.gz"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sym, plainSym) {
+		t.Errorf("sym = %v, want %v", sym, plainSym)
+	}
+}
+
+func TestLoadCorruptGzip(t *testing.T) {
+	content, err := os.ReadFile(filepath.Join("testdata", "diverging_constraints.json.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(t.TempDir(), "truncated_constraints.json.gz")
+	if err := os.WriteFile(truncated, content[:len(content)/2], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var parseErr *ParseError
+	if _, err := LoadFromJson(truncated, ParseOptions{}); !errors.As(err, &parseErr) {
+		t.Errorf("error = %v, want a ParseError", err)
+	}
+}
@@ -0,0 +1,19 @@
+// Package circuitgraph builds and analyzes constraint graphs of compiled
+// Circom circuits.
+//
+// The constraints and signal names are read from the --json and --sym outputs
+// of the circom compiler with LoadFromJson and LoadFromSym. BuildGraph turns
+// them into an undirected graph of signals, in which two signals are adjacent
+// if they appear in a common constraint, and Analyze runs the checks for
+// potentially underconstrained signals on that graph. AnalyzeGraph runs every
+// check, including those needing the constraints and the signals declared by
+// the template, and returns statistics and findings without printing. None of
+// them touch the filesystem, so graphs can be built from constraints produced
+// elsewhere.
+//
+// The package follows semantic versioning, see Version. Until 1.0.0, minor
+// versions may change the API.
+package circuitgraph
+
+// Version of the public API of this package
+const Version = "0.3.0"
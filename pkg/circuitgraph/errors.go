@@ -0,0 +1,21 @@
+package circuitgraph
+
+import "fmt"
+
+// ParseError is returned for compiler output that cannot be read
+type ParseError struct {
+	File   string // Empty if the problem is not tied to a single file
+	Offset int64  // Byte offset of the problem in File, -1 if unknown
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	switch {
+	case e.File == "":
+		return e.Reason
+	case e.Offset < 0:
+		return fmt.Sprintf("%s: %s", e.File, e.Reason)
+	default:
+		return fmt.Sprintf("%s: offset %d: %s", e.File, e.Offset, e.Reason)
+	}
+}
@@ -0,0 +1,53 @@
+package circuitgraph_test
+
+import (
+	"fmt"
+
+	"github.com/Artifex1/circuit-graph-analysis/pkg/circuitgraph"
+)
+
+// The constraints of a circuit computing y = x² and z = y * w, each one
+// A * B = C with the signal IDs of its linear combinations, as LoadFromJson
+// returns them, and the signal names of the sym file
+var (
+	constraints = circuitgraph.Constraints{
+		{{1}, {1}, {2}},
+		{{2}, {3}, {4}},
+	}
+	signals = map[int64]string{0: "one", 1: "main.x", 2: "main.y", 3: "main.w", 4: "main.z"}
+)
+
+func ExampleBuildGraph() {
+	g, err := circuitgraph.BuildGraph(constraints, signals)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(g.Nodes().Len(), "signals,", g.Edges().Len(), "edges")
+	fmt.Println("main.y is adjacent to main.z:", g.HasEdgeBetween(2, 4))
+	fmt.Println("main.x is adjacent to main.z:", g.HasEdgeBetween(1, 4))
+	// Output:
+	// 4 signals, 4 edges
+	// main.y is adjacent to main.z: true
+	// main.x is adjacent to main.z: false
+}
+
+func ExampleAnalyzeGraph() {
+	g, err := circuitgraph.BuildGraph(constraints, signals)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	result, err := circuitgraph.AnalyzeGraph(g, constraints, signals, circuitgraph.AnalyzeOptions{})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(result.Stats.Constraints, "constraints,", result.Stats.Signals, "signals, connected:", result.Stats.Connected)
+	for _, finding := range result.Findings {
+		fmt.Printf("%s %s: %s\n", finding.Severity, finding.Signal, finding.Message)
+	}
+	// Output:
+	// 2 constraints, 4 signals, connected: true
+	// medium main.x: signal has one or no connections
+}
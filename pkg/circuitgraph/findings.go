@@ -0,0 +1,37 @@
+package circuitgraph
+
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Finding is a potential issue detected in a constraint graph
+type Finding struct {
+	Category string   `json:"category"`
+	Severity Severity `json:"severity"`
+	Signal   string   `json:"signal,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Rank orders severities from info (0) to critical (4), unknown severities rank below info
+func (s Severity) Rank() int {
+	switch s {
+	case SeverityInfo:
+		return 0
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	default:
+		return -1
+	}
+}
@@ -0,0 +1,280 @@
+package circuitgraph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// cancelCheckInterval is the number of constraints processed between cancellation checks
+const cancelCheckInterval = 256
+
+// NamedNode is a signal in the constraint graph, or a synthetic node standing
+// in for a constraint wider than the arity cap
+type NamedNode struct {
+	IDVal int64  // Node ID
+	Name  string // Node name or title
+}
+
+// ID satisfies the gonum Node interface
+func (n NamedNode) ID() int64 {
+	return n.IDVal
+}
+
+// Synthetic reports whether the node stands for a constraint rather than a signal
+func (n NamedNode) Synthetic() bool {
+	return n.IDVal < 0
+}
+
+// constraintNodeID is the ID of the synthetic node of constraint c. Signal IDs
+// are never negative, so the two cannot collide.
+func constraintNodeID(c int) int64 {
+	return -int64(c) - 1
+}
+
+type graphConfig struct {
+	excludeConstant bool
+	arityCap        int
+	weighted        bool
+	projection      Projection
+}
+
+// GraphOption configures BuildGraph
+type GraphOption func(*graphConfig)
+
+// WithoutConstant leaves the constant signal "1" out of the graph
+func WithoutConstant() GraphOption {
+	return func(c *graphConfig) { c.excludeConstant = true }
+}
+
+// WithArityCap connects the signals of constraints over more than n signals
+// through a synthetic star node instead of a clique. This keeps the graph
+// linear in the constraint width while preserving connectivity.
+func WithArityCap(n int) GraphOption {
+	return func(c *graphConfig) { c.arityCap = n }
+}
+
+// Projection selects how a constraint is turned into edges
+type Projection string
+
+const (
+	// ProjectionClique connects every pair of signals of a constraint, which
+	// costs O(k²) edges for a constraint over k signals
+	ProjectionClique Projection = "clique"
+	// ProjectionStar connects the signals of every constraint through a
+	// synthetic constraint node, which costs O(k) edges
+	ProjectionStar Projection = "star"
+)
+
+// WithProjection selects the projection of all constraints, regardless of the arity cap
+func WithProjection(p Projection) GraphOption {
+	return func(c *graphConfig) { c.projection = p }
+}
+
+// WithWeightedEdges weighs each edge by the number of constraints it stems from
+func WithWeightedEdges() GraphOption {
+	return func(c *graphConfig) { c.weighted = true }
+}
+
+type edgeKey [2]int64
+
+func newEdgeKey(x, y int64) edgeKey {
+	if x > y {
+		x, y = y, x
+	}
+	return edgeKey{x, y}
+}
+
+// CircuitGraph is the constraint graph of a circuit. It embeds the underlying
+// gonum graph and records which constraints induced each edge.
+type CircuitGraph struct {
+	*simple.UndirectedGraph
+	weighted   bool
+	provenance map[edgeKey][]int
+}
+
+// BuildGraph projects the constraints onto an undirected graph of signals, in
+// which two signals are adjacent if they appear in a common constraint.
+// Signals missing from the names map are named signal_<id>.
+func BuildGraph(constraints Constraints, signals map[int64]string, options ...GraphOption) (*CircuitGraph, error) {
+	return BuildGraphContext(context.Background(), constraints, signals, options...)
+}
+
+// BuildGraphContext is BuildGraph, stopping with ctx.Err() once ctx is done
+func BuildGraphContext(ctx context.Context, constraints Constraints, signals map[int64]string, options ...GraphOption) (*CircuitGraph, error) {
+	var config graphConfig
+	for _, option := range options {
+		option(&config)
+	}
+	if config.arityCap < 0 {
+		return nil, fmt.Errorf("arity cap must not be negative, got %d", config.arityCap)
+	}
+	switch config.projection {
+	case "", ProjectionClique, ProjectionStar:
+	default:
+		return nil, fmt.Errorf("unknown projection %q", config.projection)
+	}
+
+	g := &CircuitGraph{
+		UndirectedGraph: simple.NewUndirectedGraph(),
+		weighted:        config.weighted,
+		provenance:      make(map[edgeKey][]int),
+	}
+
+	for c, constraint := range constraints {
+		// Large circuits take a while, check for cancellation every so often
+		if c%cancelCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		// Collect all unique signals in this constraint
+		signalSet := make(map[int64]struct{})
+		for _, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				if signal < 0 {
+					return nil, fmt.Errorf("constraint %d references negative signal ID %d", c, signal)
+				}
+				if signal == 0 && config.excludeConstant {
+					continue
+				}
+				signalSet[signal] = struct{}{}
+			}
+		}
+
+		// Create or get nodes for all signals in this constraint
+		nodes := make([]*NamedNode, 0, len(signalSet))
+		for signal := range signalSet {
+			node, ok := g.Node(signal).(*NamedNode)
+			if !ok {
+				// Add node if it doesn't exist, signals missing from the sym file are reported by CheckArtifacts
+				name, ok := signals[signal]
+				if !ok {
+					name = fmt.Sprintf("signal_%d", signal)
+				}
+				node = &NamedNode{IDVal: signal, Name: name}
+				g.AddNode(node)
+			}
+			nodes = append(nodes, node)
+		}
+		// The set is unordered, connect the nodes the same way on every run
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID() < nodes[j].ID() })
+
+		wide := config.arityCap > 0 && len(nodes) > config.arityCap
+		if (config.projection == ProjectionStar && len(nodes) > 1) || wide {
+			// Connect all nodes through a star node for this constraint
+			star := &NamedNode{IDVal: constraintNodeID(c), Name: fmt.Sprintf("constraint_%d", c)}
+			g.AddNode(star)
+			for _, node := range nodes {
+				g.connect(star, node, c)
+			}
+			continue
+		}
+
+		// Connect all nodes with each other
+		for i := 0; i < len(nodes); i++ {
+			for j := i + 1; j < len(nodes); j++ {
+				g.connect(nodes[i], nodes[j], c)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+func (g *CircuitGraph) connect(x, y *NamedNode, constraint int) {
+	g.SetEdge(simple.Edge{F: x, T: y})
+	key := newEdgeKey(x.ID(), y.ID())
+	g.provenance[key] = append(g.provenance[key], constraint)
+}
+
+// Provenance returns the indices of the constraints that induced the edge between x and y
+func (g *CircuitGraph) Provenance(x, y int64) []int {
+	return g.provenance[newEdgeKey(x, y)]
+}
+
+// EdgeWeight returns the number of constraints behind the edge between x and
+// y if the graph was built WithWeightedEdges, 1 otherwise, and 0 if there is no edge.
+func (g *CircuitGraph) EdgeWeight(x, y int64) float64 {
+	constraints := g.Provenance(x, y)
+	if len(constraints) == 0 {
+		return 0
+	}
+	if g.weighted {
+		return float64(len(constraints))
+	}
+	return 1
+}
+
+// SignalCount returns the number of signal nodes, excluding synthetic ones
+func (g *CircuitGraph) SignalCount() int {
+	count := 0
+	nodes := g.Nodes()
+	for nodes.Next() {
+		if !nodes.Node().(*NamedNode).Synthetic() {
+			count++
+		}
+	}
+	return count
+}
+
+// SortedNodes returns the nodes of the graph ordered by ID, synthetic nodes
+// first. Exporters use it so that the same graph is always written the same way.
+func (g *CircuitGraph) SortedNodes() []*NamedNode {
+	nodes := make([]*NamedNode, 0, g.Nodes().Len())
+	for _, node := range sortedNodes(graph.NodesOf(g.Nodes())) {
+		nodes = append(nodes, node.(*NamedNode))
+	}
+	return nodes
+}
+
+// SortedEdges returns the edges of the graph as pairs of ends, the lower ID
+// first, ordered by the IDs of their ends
+func (g *CircuitGraph) SortedEdges() [][2]*NamedNode {
+	edges := make([][2]*NamedNode, 0, g.Edges().Len())
+	iterator := g.Edges()
+	for iterator.Next() {
+		from, to := iterator.Edge().From().(*NamedNode), iterator.Edge().To().(*NamedNode)
+		if from.ID() > to.ID() {
+			from, to = to, from
+		}
+		edges = append(edges, [2]*NamedNode{from, to})
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0].ID() != edges[j][0].ID() {
+			return edges[i][0].ID() < edges[j][0].ID()
+		}
+		return edges[i][1].ID() < edges[j][1].ID()
+	})
+	return edges
+}
+
+// SignalDegree returns the number of distinct signals sharing a constraint
+// with the given one, looking through synthetic star nodes
+func (g *CircuitGraph) SignalDegree(id int64) int {
+	return len(g.signalNeighbors(id))
+}
+
+// signalNeighbors returns the IDs of the signals sharing a constraint with the given one
+func (g *CircuitGraph) signalNeighbors(id int64) map[int64]struct{} {
+	neighbors := make(map[int64]struct{})
+	from := g.From(id)
+	for from.Next() {
+		neighbor := from.Node().(*NamedNode)
+		if !neighbor.Synthetic() {
+			neighbors[neighbor.ID()] = struct{}{}
+			continue
+		}
+		members := g.From(neighbor.ID())
+		for members.Next() {
+			if member := members.Node().ID(); member != id {
+				neighbors[member] = struct{}{}
+			}
+		}
+	}
+	return neighbors
+}
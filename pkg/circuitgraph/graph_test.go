@@ -0,0 +1,57 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"testing"
+)
+
+// benchmarkConstraints returns count constraints over width signals each,
+// every one sharing half of its signals with the next, so that the graph is
+// connected as in a real circuit
+func benchmarkConstraints(count, width int) (Constraints, map[int64]string) {
+	constraints := make(Constraints, count)
+	signals := make(map[int64]string)
+	for c := range constraints {
+		first := int64(c*width/2 + 1)
+		var a, b, out []int64
+		for i := int64(0); i < int64(width); i++ {
+			signal := first + i
+			signals[signal] = fmt.Sprintf("main.s[%d]", signal)
+			switch i % 3 {
+			case 0:
+				a = append(a, signal)
+			case 1:
+				b = append(b, signal)
+			default:
+				out = append(out, signal)
+			}
+		}
+		constraints[c] = [3][]int64{a, b, out}
+	}
+	return constraints, signals
+}
+
+// BenchmarkBuildGraph measures the clique projection, O(k²) edges for a
+// constraint over k signals, against the star projection, O(k) edges
+func BenchmarkBuildGraph(b *testing.B) {
+	shapes := []struct {
+		name         string
+		count, width int
+	}{
+		{"small", 2000, 3},
+		{"wide", 100, 64},
+	}
+	for _, shape := range shapes {
+		constraints, signals := benchmarkConstraints(shape.count, shape.width)
+		for _, projection := range []Projection{ProjectionClique, ProjectionStar} {
+			b.Run(fmt.Sprintf("%s/%s", shape.name, projection), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := BuildGraph(constraints, signals, WithProjection(projection)); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CategoryHub is reported for a signal connected to a large share of the graph
+const CategoryHub = "hub-signal"
+
+// Graphs with fewer signals than minHubGraphSize are not checked for hubs, as
+// any signal of a tiny template covers a large share of it
+const minHubGraphSize = 20
+
+// Hub is a signal whose neighborhood covers a large share of the graph
+type Hub struct {
+	Signal   string     `json:"signal"`
+	Role     SignalKind `json:"role"`
+	Degree   int        `json:"degree"`   // Signals sharing a constraint with the hub
+	Coverage float64    `json:"coverage"` // Share of the other signals among its neighbors, in percent
+}
+
+// FindHubs returns the signals other than the "1" signal whose neighbors make
+// up more than threshold percent of the other signals, highest coverage
+// first. Roles are looked up in kinds, the signals declared by the template.
+// Constraint nodes are looked through and never reported.
+func FindHubs(g *CircuitGraph, kinds map[string]SignalKind, threshold float64) []Hub {
+	signals := g.SignalCount()
+	if g.Node(0) != nil {
+		signals--
+	}
+	if signals < minHubGraphSize {
+		return nil
+	}
+
+	var hubs []Hub
+	nodes := g.Nodes()
+	for nodes.Next() {
+		node := nodes.Node().(*NamedNode)
+		if node.Synthetic() || node.ID() == 0 {
+			continue
+		}
+		neighbors := g.signalNeighbors(node.ID())
+		delete(neighbors, 0)
+		coverage := 100 * float64(len(neighbors)) / float64(signals-1)
+		if coverage > threshold {
+			hubs = append(hubs, Hub{
+				Signal:   node.Name,
+				Role:     SignalRole(node.Name, kinds),
+				Degree:   len(neighbors),
+				Coverage: coverage,
+			})
+		}
+	}
+	sort.Slice(hubs, func(i, j int) bool {
+		if hubs[i].Coverage != hubs[j].Coverage {
+			return hubs[i].Coverage > hubs[j].Coverage
+		}
+		return hubs[i].Signal < hubs[j].Signal
+	})
+	return hubs
+}
+
+// CheckHubs reports every hub. Accumulators make intermediate hubs common,
+// an input signal acting as a hub is unusual and gets a higher severity.
+func CheckHubs(hubs []Hub) []Finding {
+	var findings []Finding
+	for _, hub := range hubs {
+		severity := SeverityInfo
+		if hub.Role == KindInput {
+			severity = SeverityLow
+		}
+		findings = append(findings, Finding{
+			Category: CategoryHub,
+			Severity: severity,
+			Signal:   hub.Signal,
+			Message:  fmt.Sprintf("%s signal shares constraints with %d signals, %.1f%% of the graph", hub.Role, hub.Degree, hub.Coverage),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,121 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SignalKind is the way a signal is declared in a circom template
+type SignalKind string
+
+const (
+	KindInput        SignalKind = "input"
+	KindOutput       SignalKind = "output"
+	KindIntermediate SignalKind = "intermediate"
+	KindSubcomponent SignalKind = "subcomponent" // Declared by a component the template instantiates
+)
+
+// SignalRole returns the kind of a signal of the compiled main component,
+// e.g. main.in[2], given the kinds of the template's signals by name
+func SignalRole(signal string, kinds map[string]SignalKind) SignalKind {
+	name := strings.TrimPrefix(signal, "main.")
+	if strings.Contains(name, ".") {
+		return KindSubcomponent
+	}
+	if bracket := strings.Index(name, "["); bracket >= 0 {
+		name = name[:bracket]
+	}
+	if kind, ok := kinds[name]; ok {
+		return kind
+	}
+	return KindIntermediate
+}
+
+// CheckOutputs reports a template that declares no output signals, given the
+// kinds of its signals by name. Such a template computes nothing visible to
+// its users, which is fine for assertion-only templates and a mistake otherwise.
+func CheckOutputs(kinds map[string]SignalKind) []Finding {
+	for _, kind := range kinds {
+		if kind == KindOutput {
+			return nil
+		}
+	}
+	return []Finding{{
+		Category: CategoryNoOutputs,
+		Severity: SeverityInfo,
+		Message:  "template declares no output signals",
+	}}
+}
+
+// Finding categories reported by CheckInputs
+const (
+	CategoryFloatingInput = "floating-input"
+	CategoryIsolatedInput = "isolated-input"
+	CategoryInputIsland   = "input-island"
+)
+
+// CheckInputs reports the input signals that appear in no constraint at all,
+// being missing from the graph, as floating, those that constrain nothing once
+// the "1" signal is removed, and those sharing constraints only with other
+// inputs, apart from a circuit made of inputs alone. A floating input is
+// usually declared and then forgotten, while an isolated one is at least
+// compared to constants. Roles are looked up in kinds, and the declaring line
+// of each input in lines, when the source scan found one.
+func CheckInputs(g *CircuitGraph, signals map[int64]string, kinds map[string]SignalKind, lines map[string]int) []Finding {
+	components := signalComponents(g)
+	var findings []Finding
+	for id, name := range signals {
+		if g.Node(id) == nil && SignalRole(name, kinds) == KindInput {
+			findings = append(findings, Finding{
+				Category: CategoryFloatingInput,
+				Severity: SeverityHigh,
+				Signal:   name,
+				Message:  "input signal appears in no constraint, the prover can set it freely without any effect, it may have been forgotten" + declaredOn(name, lines),
+			})
+		}
+	}
+	for _, component := range components {
+		var inputs []string
+		for _, node := range component {
+			if SignalRole(node.Name, kinds) == KindInput {
+				inputs = append(inputs, node.Name)
+			}
+		}
+		if len(inputs) != len(component) {
+			continue
+		}
+		sort.Strings(inputs)
+
+		category, message := CategoryIsolatedInput, "input signal constrains nothing, the circuit accepts any value for it"
+		if len(inputs) > 1 {
+			if len(components) == 1 {
+				continue
+			}
+			category = CategoryInputIsland
+			message = fmt.Sprintf("input signal only shares constraints with the inputs %s, never with the rest of the circuit", strings.Join(inputs, ", "))
+		}
+		for _, input := range inputs {
+			findings = append(findings, Finding{
+				Category: category,
+				Severity: SeverityHigh,
+				Signal:   input,
+				Message:  message + declaredOn(input, lines),
+			})
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Signal < findings[j].Signal })
+	return findings
+}
+
+// declaredOn returns where a signal of the main component is declared, if known
+func declaredOn(signal string, lines map[string]int) string {
+	name := strings.TrimPrefix(signal, "main.")
+	if bracket := strings.Index(name, "["); bracket >= 0 {
+		name = name[:bracket]
+	}
+	if line, ok := lines[name]; ok {
+		return fmt.Sprintf(" (declared on line %d)", line)
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CategoryLinearOutput is reported for an output tied to the inputs by linear constraints alone
+const CategoryLinearOutput = "linear-only-output"
+
+// maxLinearInputs limits the inputs named in a finding
+const maxLinearInputs = 3
+
+// quadratic reports whether a constraint multiplies two signals, that is
+// whether both its A and B terms mention a signal other than "1"
+func quadratic(constraint [3][]int64) bool {
+	for _, term := range constraint[:2] {
+		nonConstant := false
+		for _, signal := range term {
+			if signal != 0 {
+				nonConstant = true
+				break
+			}
+		}
+		if !nonConstant {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckLinearOutputs reports the outputs of the main component that appear in
+// no quadratic A·B term, and whose region of signals linked by linear
+// constraints reaches an input without touching any quadratic constraint.
+// Every path from such an output to the inputs runs through linear
+// constraints only, so the prover may be free to compute it independently of
+// the witness. Roles are looked up in kinds, the signals declared by the template.
+func CheckLinearOutputs(constraints Constraints, signals map[int64]string, kinds map[string]SignalKind) []Finding {
+	inProduct := make(map[int64]bool)   // Signals of the A or B term of a quadratic constraint
+	inQuadratic := make(map[int64]bool) // Signals of any term of a quadratic constraint
+	parent := make(map[int64]int64)
+	var find func(int64) int64
+	find = func(id int64) int64 {
+		if p, ok := parent[id]; ok && p != id {
+			parent[id] = find(p)
+			return parent[id]
+		}
+		return id
+	}
+
+	for _, constraint := range constraints {
+		if quadratic(constraint) {
+			for i, term := range constraint {
+				for _, signal := range term {
+					inQuadratic[signal] = true
+					if i < 2 {
+						inProduct[signal] = true
+					}
+				}
+			}
+			continue
+		}
+		// Linear constraints join their signals into one region
+		first := int64(-1)
+		for _, term := range constraint {
+			for _, signal := range term {
+				if signal == 0 {
+					continue
+				}
+				if first < 0 {
+					first = find(signal)
+					continue
+				}
+				if root := find(signal); root != first {
+					parent[root] = first
+				}
+			}
+		}
+	}
+
+	regionQuadratic := make(map[int64]bool)
+	regionInputs := make(map[int64][]string)
+	for id, name := range signals {
+		root := find(id)
+		if inQuadratic[id] {
+			regionQuadratic[root] = true
+		}
+		if strings.HasPrefix(name, "main.") && SignalRole(name, kinds) == KindInput {
+			regionInputs[root] = append(regionInputs[root], name)
+		}
+	}
+
+	var findings []Finding
+	for id, name := range signals {
+		if id == 0 || !strings.HasPrefix(name, "main.") || SignalRole(name, kinds) != KindOutput || inProduct[id] {
+			continue
+		}
+		root := find(id)
+		inputs := regionInputs[root]
+		if regionQuadratic[root] || len(inputs) == 0 {
+			continue
+		}
+		sort.Strings(inputs)
+		named := inputs
+		if len(named) > maxLinearInputs {
+			named = append(named[:maxLinearInputs:maxLinearInputs], "...")
+		}
+		findings = append(findings, Finding{
+			Category: CategoryLinearOutput,
+			Severity: SeverityLow,
+			Signal:   name,
+			Message:  fmt.Sprintf("output signal appears in no quadratic term, only linear constraints connect it to the input(s) %s", strings.Join(named, ", ")),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Signal < findings[j].Signal })
+	return findings
+}
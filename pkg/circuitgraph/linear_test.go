@@ -0,0 +1,85 @@
+package circuitgraph
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// linearLeakKinds are the signals testdata/linear_leak.circom declares
+var linearLeakKinds = map[string]SignalKind{
+	"a": KindInput, "b": KindInput, "c": KindInput, "d": KindInput,
+	"prod": KindOutput, "leak": KindOutput,
+}
+
+func TestCheckLinearOutputs(t *testing.T) {
+	constraints, err := LoadFromJson(filepath.Join("testdata", "linear_leak_constraints.json"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signals, err := LoadFromSym(filepath.Join("testdata", "linear_leak.sym"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		kinds    map[string]SignalKind
+		findings []string // Signals flagged
+	}{
+		{"broken fixture", linearLeakKinds, []string{"main.leak"}},
+		{"leak declared an intermediate signal", map[string]SignalKind{"a": KindInput, "b": KindInput, "c": KindInput, "d": KindInput, "prod": KindOutput, "leak": KindIntermediate}, nil},
+		{"no input behind the leak", map[string]SignalKind{"a": KindInput, "b": KindInput, "c": KindIntermediate, "d": KindIntermediate, "prod": KindOutput, "leak": KindOutput}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			findings := CheckLinearOutputs(constraints, signals, test.kinds)
+			if len(findings) != len(test.findings) {
+				t.Fatalf("findings = %+v, want %v", findings, test.findings)
+			}
+			for i, finding := range findings {
+				if finding.Category != CategoryLinearOutput || finding.Signal != test.findings[i] {
+					t.Errorf("finding %d = %+v, want %s on %s", i, finding, CategoryLinearOutput, test.findings[i])
+				}
+				if !strings.Contains(finding.Message, "main.c, main.d") {
+					t.Errorf("message %q does not name the inputs", finding.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeGraphLinearOutputs(t *testing.T) {
+	constraints, err := LoadFromJson(filepath.Join("testdata", "linear_leak_constraints.json"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signals, err := LoadFromSym(filepath.Join("testdata", "linear_leak.sym"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := BuildGraph(constraints, signals)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, list := range []string{"linear-outputs", "stats"} {
+		checks, err := ParseChecks(list)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := AnalyzeGraph(g, constraints, signals, AnalyzeOptions{Kinds: linearLeakKinds, Checks: checks})
+		if err != nil {
+			t.Fatal(err)
+		}
+		flagged := 0
+		for _, finding := range result.Findings {
+			if finding.Category == CategoryLinearOutput {
+				flagged++
+			}
+		}
+		if want := map[string]int{"linear-outputs": 1, "stats": 0}[list]; flagged != want {
+			t.Errorf("checks %s: %d %s findings, want %d", list, flagged, CategoryLinearOutput, want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package circuitgraph
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var arrayIndexRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+
+// SignalFamily replaces the array indices of a signal name with [*], so
+// main.x[3] and main.x[7] are one family
+func SignalFamily(signal string) string {
+	return arrayIndexRegexp.ReplaceAllString(signal, "[*]")
+}
+
+// Pattern is a family of signals, such as those written by one loop body,
+// split by the local structure around each member
+type Pattern struct {
+	Family     string       `json:"family"`
+	Signals    int          `json:"signals"`    // Members of the family
+	Structures []Repetition `json:"structures"` // Most repeated structure first
+}
+
+// Repetition is a local structure shared by several members of a family
+type Repetition struct {
+	Count   int    `json:"count"`
+	Example string `json:"example"` // Member with the lowest ID, usually the first loop iteration
+}
+
+// RepeatedPatterns groups the signals of array families by the structure
+// around them: their degree and the families of their neighbors. Loop bodies
+// produce one structure repeated for every iteration, and a second one for
+// the iterations at either end. More structures, or counts off by one, point
+// at an unexpected loop expansion. Families with fewer than minMembers
+// members are skipped.
+func RepeatedPatterns(g *CircuitGraph, minMembers int) []Pattern {
+	members := make(map[string][]*NamedNode)
+	nodes := g.Nodes()
+	for nodes.Next() {
+		node := nodes.Node().(*NamedNode)
+		if node.Synthetic() || node.ID() == 0 {
+			continue
+		}
+		if family := SignalFamily(node.Name); family != node.Name {
+			members[family] = append(members[family], node)
+		}
+	}
+
+	var patterns []Pattern
+	for family, nodes := range members {
+		if len(nodes) < minMembers {
+			continue
+		}
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID() < nodes[j].ID() })
+
+		index := make(map[string]int)
+		var structures []Repetition
+		for _, node := range nodes {
+			signature := localStructure(g, node)
+			i, ok := index[signature]
+			if !ok {
+				i = len(structures)
+				index[signature] = i
+				structures = append(structures, Repetition{Example: node.Name})
+			}
+			structures[i].Count++
+		}
+		sort.SliceStable(structures, func(i, j int) bool { return structures[i].Count > structures[j].Count })
+		patterns = append(patterns, Pattern{Family: family, Signals: len(nodes), Structures: structures})
+	}
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].Family < patterns[j].Family })
+	return patterns
+}
+
+// localStructure describes the neighborhood of a signal without its indices
+func localStructure(g *CircuitGraph, node *NamedNode) string {
+	var neighbors []string
+	for id := range g.signalNeighbors(node.ID()) {
+		neighbors = append(neighbors, SignalFamily(g.Node(id).(*NamedNode).Name))
+	}
+	sort.Strings(neighbors)
+	return strings.Join(neighbors, "\x00")
+}
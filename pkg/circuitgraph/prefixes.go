@@ -0,0 +1,185 @@
+package circuitgraph
+
+import (
+	"sort"
+	"strings"
+)
+
+// PrefixStats aggregates the signals whose name starts with a prefix, a path
+// of dot-separated components and array indices with indices merged into
+// [*], e.g. main.hasher.sbox[*]
+type PrefixStats struct {
+	Prefix          string         `json:"prefix"`
+	Signals         int            `json:"signals"`
+	AverageDegree   float64        `json:"average_degree"`
+	Flagged         int            `json:"flagged"`          // Signals named by a finding
+	ConstraintShare float64        `json:"constraint_share"` // Share of all constraints mentioning one of its signals, in percent
+	Children        []*PrefixStats `json:"children,omitempty"`
+	Indices         []*PrefixStats `json:"indices,omitempty"` // Statistics per concrete index of a [*] prefix, if requested
+
+	degrees     int
+	constraints int
+	children    map[string]*PrefixStats
+	indices     map[string]*PrefixStats
+}
+
+// nameSegments splits a signal name at dots and before array indices, e.g.
+// main.sbox[2].out into main, sbox, [2] and out
+func nameSegments(name string) []string {
+	var segments []string
+	start := 0
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '.':
+			if i > start {
+				segments = append(segments, name[start:i])
+			}
+			start = i + 1
+		case '[':
+			if i > start {
+				segments = append(segments, name[start:i])
+			}
+			start = i
+		case ']':
+			segments = append(segments, name[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(name) {
+		segments = append(segments, name[start:])
+	}
+	return segments
+}
+
+// PrefixStatistics builds a trie over the names of the signals other than
+// the "1" signal and aggregates their count, average degree, the number of
+// them in flagged and the share of constraints mentioning them per prefix.
+// With indices, every [*] prefix also lists the statistics of each of its
+// concrete indices, one level deep.
+func PrefixStatistics(g *CircuitGraph, constraints Constraints, signals map[int64]string, flagged map[string]bool, indices bool) *PrefixStats {
+	root := newPrefixStats("")
+	paths := make(map[int64][]*PrefixStats, len(signals))
+	for id, name := range signals {
+		if id == 0 {
+			continue
+		}
+		degree := 0
+		if g.Node(id) != nil {
+			degree = g.SignalDegree(id)
+		}
+		var path []*PrefixStats
+		node := root
+		for _, segment := range nameSegments(name) {
+			var concrete *PrefixStats
+			if strings.HasPrefix(segment, "[") {
+				if indices {
+					concrete = node.child(segment, true)
+				}
+				segment = "[*]"
+			}
+			node = node.child(segment, false)
+			path = append(path, node)
+			if concrete != nil {
+				path = append(path, concrete)
+			}
+		}
+		for _, prefix := range path {
+			prefix.Signals++
+			prefix.degrees += degree
+			if flagged[name] {
+				prefix.Flagged++
+			}
+		}
+		paths[id] = path
+	}
+
+	for _, constraint := range constraints {
+		touched := make(map[*PrefixStats]struct{})
+		for _, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				for _, prefix := range paths[signal] {
+					touched[prefix] = struct{}{}
+				}
+			}
+		}
+		for prefix := range touched {
+			prefix.constraints++
+		}
+	}
+
+	root.finish("", len(constraints))
+	if len(root.Children) == 1 {
+		return root.Children[0] // Usually main
+	}
+	return root
+}
+
+func newPrefixStats(prefix string) *PrefixStats {
+	return &PrefixStats{Prefix: prefix, children: make(map[string]*PrefixStats), indices: make(map[string]*PrefixStats)}
+}
+
+// child returns the child prefix for a segment, or the concrete index of
+// the [*] child, creating it on first use
+func (p *PrefixStats) child(segment string, concrete bool) *PrefixStats {
+	if concrete {
+		parent := p.child("[*]", false)
+		if child, ok := parent.indices[segment]; ok {
+			return child
+		}
+		child := newPrefixStats(segment)
+		parent.indices[segment] = child
+		return child
+	}
+	if child, ok := p.children[segment]; ok {
+		return child
+	}
+	child := newPrefixStats(segment)
+	p.children[segment] = child
+	return child
+}
+
+// finish turns the segments into full prefixes, computes the averages and
+// shares and orders the children by size, largest first
+func (p *PrefixStats) finish(parent string, totalConstraints int) {
+	p.Prefix = joinSegment(parent, p.Prefix)
+	if p.Signals > 0 {
+		p.AverageDegree = float64(p.degrees) / float64(p.Signals)
+	}
+	if totalConstraints > 0 {
+		p.ConstraintShare = 100 * float64(p.constraints) / float64(totalConstraints)
+	}
+	for _, child := range p.children {
+		child.finish(p.Prefix, totalConstraints)
+		p.Children = append(p.Children, child)
+	}
+	for _, index := range p.indices {
+		index.finish(parent, totalConstraints)
+		p.Indices = append(p.Indices, index)
+	}
+	sortPrefixes(p.Children)
+	sort.Slice(p.Indices, func(i, j int) bool { return indexLess(p.Indices[i].Prefix, p.Indices[j].Prefix) })
+}
+
+func joinSegment(parent, segment string) string {
+	if parent == "" || strings.HasPrefix(segment, "[") {
+		return parent + segment
+	}
+	return parent + "." + segment
+}
+
+func sortPrefixes(prefixes []*PrefixStats) {
+	sort.Slice(prefixes, func(i, j int) bool {
+		if prefixes[i].Signals != prefixes[j].Signals {
+			return prefixes[i].Signals > prefixes[j].Signals
+		}
+		return prefixes[i].Prefix < prefixes[j].Prefix
+	})
+}
+
+// indexLess orders concrete indices numerically, e.g. sbox[2] before sbox[10]
+func indexLess(a, b string) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
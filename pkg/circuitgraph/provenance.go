@@ -0,0 +1,124 @@
+package circuitgraph
+
+import (
+	"sort"
+	"strings"
+)
+
+// ComponentConstraints is a node of the component tree with the constraints
+// attributed to it, those whose signals all lie below it
+type ComponentConstraints struct {
+	Name       string                  `json:"name"`                 // Last path segment, e.g. hasher or hashers[2]
+	Own        int                     `json:"own"`                  // Constraints spanning several of its children or over its own signals
+	Total      int                     `json:"total"`                // Own constraints and those of all its descendants
+	Children   []*ComponentConstraints `json:"children,omitempty"`   // Largest total first
+	Suspicious bool                    `json:"suspicious,omitempty"` // Contributes less than half the median of its sibling instances
+	children   map[string]*ComponentConstraints
+}
+
+// ConstraintsByComponent attributes every constraint to the deepest component
+// path shared by all its signals, e.g. main.hasher for a constraint only over
+// main.hasher.* signals, and returns the tree of components rooted at main.
+// The "1" signal does not take part. With groupArrays, the instances of a
+// component array are merged into one node, e.g. main.hashers[*].
+func ConstraintsByComponent(constraints Constraints, signals map[int64]string, groupArrays bool) *ComponentConstraints {
+	root := newComponentConstraints("main")
+	for _, constraint := range constraints {
+		var path []string
+		first := true
+		for _, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				if signal == 0 {
+					continue
+				}
+				signalPath := componentPath(signals[signal], groupArrays)
+				if first {
+					path, first = signalPath, false
+					continue
+				}
+				path = commonPath(path, signalPath)
+			}
+		}
+
+		node := root
+		node.Total++
+		for _, segment := range path {
+			child, ok := node.children[segment]
+			if !ok {
+				child = newComponentConstraints(segment)
+				node.children[segment] = child
+			}
+			node = child
+			node.Total++
+		}
+		node.Own++
+	}
+	root.finish()
+	return root
+}
+
+func newComponentConstraints(name string) *ComponentConstraints {
+	return &ComponentConstraints{Name: name, children: make(map[string]*ComponentConstraints)}
+}
+
+// componentPath returns the components below main a signal belongs to, e.g.
+// [hasher sbox[2]] for main.hasher.sbox[2].out
+func componentPath(signal string, groupArrays bool) []string {
+	segments := strings.Split(signal, ".")
+	if len(segments) < 2 {
+		return nil
+	}
+	path := segments[1 : len(segments)-1]
+	if groupArrays {
+		grouped := make([]string, len(path))
+		for i, segment := range path {
+			grouped[i] = SignalFamily(segment)
+		}
+		path = grouped
+	}
+	return path
+}
+
+func commonPath(a, b []string) []string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+// finish orders the children and flags array instances contributing less
+// than half the median of their siblings
+func (c *ComponentConstraints) finish() {
+	instances := make(map[string][]*ComponentConstraints)
+	for _, child := range c.children {
+		child.finish()
+		c.Children = append(c.Children, child)
+		if base := SignalFamily(child.Name); base != child.Name {
+			instances[base] = append(instances[base], child)
+		}
+	}
+	sort.Slice(c.Children, func(i, j int) bool {
+		if c.Children[i].Total != c.Children[j].Total {
+			return c.Children[i].Total > c.Children[j].Total
+		}
+		return c.Children[i].Name < c.Children[j].Name
+	})
+
+	for _, siblings := range instances {
+		if len(siblings) < 3 {
+			continue
+		}
+		totals := make([]int, len(siblings))
+		for i, sibling := range siblings {
+			totals[i] = sibling.Total
+		}
+		sort.Ints(totals)
+		median := totals[len(totals)/2]
+		for _, sibling := range siblings {
+			if 2*sibling.Total < median {
+				sibling.Suspicious = true
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package circuitgraph
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// r1csMagic starts every circom --r1cs output file
+var r1csMagic = []byte("r1cs")
+
+// r1csHeaderSection is the type of the section of an r1cs file holding the header
+const r1csHeaderSection = 1
+
+// Visibility tells whether the prover has to reveal an input of the main
+// component to the verifier
+type Visibility string
+
+const (
+	VisibilityPublic  Visibility = "public"
+	VisibilityPrivate Visibility = "private"
+)
+
+// R1CSHeader is the header of a circom --r1cs output file. circom orders the
+// witness after the "1" signal as the outputs of the main component, which
+// are all public, its public inputs, its private inputs and then the other
+// signals, so the counts tell the role of the first witness indices.
+type R1CSHeader struct {
+	Wires         uint32
+	PublicOutputs uint32
+	PublicInputs  uint32
+	PrivateInputs uint32
+	Constraints   uint32
+}
+
+// LoadR1CSHeader reads the header section of a circom --r1cs output file,
+// skipping the constraints
+func LoadR1CSHeader(path string) (R1CSHeader, error) {
+	file, err := openArtifact(path)
+	if err != nil {
+		return R1CSHeader{}, err
+	}
+	defer file.Close()
+
+	var header R1CSHeader
+	fail := func(offset int64, reason string) error {
+		return &ParseError{File: path, Offset: offset, Reason: reason}
+	}
+	magic := make([]byte, len(r1csMagic))
+	if _, err := io.ReadFull(file, magic); err != nil || string(magic) != string(r1csMagic) {
+		return header, fail(0, "not an r1cs file")
+	}
+	var preamble struct{ Version, Sections uint32 }
+	if err := binary.Read(file, binary.LittleEndian, &preamble); err != nil {
+		return header, fail(4, "truncated r1cs preamble")
+	}
+
+	offset := int64(12)
+	for i := uint32(0); i < preamble.Sections; i++ {
+		var section struct {
+			Type uint32
+			Size uint64
+		}
+		if err := binary.Read(file, binary.LittleEndian, &section); err != nil {
+			return header, fail(offset, "truncated r1cs section")
+		}
+		offset += 12
+		if section.Type != r1csHeaderSection {
+			if _, err := io.CopyN(io.Discard, file, int64(section.Size)); err != nil {
+				return header, fail(offset, "truncated r1cs section")
+			}
+			offset += int64(section.Size)
+			continue
+		}
+
+		// The field size in bytes and the prime, then the counts
+		var fieldSize uint32
+		if err := binary.Read(file, binary.LittleEndian, &fieldSize); err != nil {
+			return header, fail(offset, "truncated r1cs header")
+		}
+		if _, err := io.CopyN(io.Discard, file, int64(fieldSize)); err != nil {
+			return header, fail(offset, "truncated r1cs header")
+		}
+		var counts struct {
+			Wires, PublicOutputs, PublicInputs, PrivateInputs uint32
+			Labels                                            uint64
+			Constraints                                       uint32
+		}
+		if err := binary.Read(file, binary.LittleEndian, &counts); err != nil {
+			return header, fail(offset, "truncated r1cs header")
+		}
+		header = R1CSHeader{
+			Wires:         counts.Wires,
+			PublicOutputs: counts.PublicOutputs,
+			PublicInputs:  counts.PublicInputs,
+			PrivateInputs: counts.PrivateInputs,
+			Constraints:   counts.Constraints,
+		}
+		if uint64(header.PublicOutputs)+uint64(header.PublicInputs)+uint64(header.PrivateInputs) >= uint64(header.Wires) {
+			return header, fail(offset, fmt.Sprintf("r1cs header declares %d wires, too few for its inputs and outputs", header.Wires))
+		}
+		return header, nil
+	}
+	return header, fail(offset, "r1cs file has no header section")
+}
+
+// InputVisibility returns the visibility of the input of the main component
+// at a witness index, empty if the index is not one of an input
+func (h R1CSHeader) InputVisibility(witness int64) Visibility {
+	public := int64(h.PublicOutputs) + 1
+	private := public + int64(h.PublicInputs)
+	switch {
+	case witness >= public && witness < private:
+		return VisibilityPublic
+	case witness >= private && witness < private+int64(h.PrivateInputs):
+		return VisibilityPrivate
+	default:
+		return ""
+	}
+}
+
+// Kinds returns the kinds of the main component signals the header knows the
+// role of, its inputs and outputs, by name as SignalRole looks them up, for
+// analyzing a circuit whose source is not available
+func (h R1CSHeader) Kinds(signals map[int64]string) map[string]SignalKind {
+	kinds := make(map[string]SignalKind)
+	for id, name := range signals {
+		kind := KindInput
+		if id >= 1 && id <= int64(h.PublicOutputs) {
+			kind = KindOutput
+		} else if h.InputVisibility(id) == "" {
+			continue
+		}
+		name = strings.TrimPrefix(name, "main.")
+		if bracket := strings.Index(name, "["); bracket >= 0 {
+			name = name[:bracket]
+		}
+		kinds[name] = kind
+	}
+	return kinds
+}
+
+// InputSignal is an input of the main component with its visibility
+type InputSignal struct {
+	Signal     string     `json:"signal"`
+	Visibility Visibility `json:"visibility"`
+}
+
+// InputVisibilities returns the inputs of the main component among signals,
+// in the order circom declares them
+func InputVisibilities(signals map[int64]string, header R1CSHeader) []InputSignal {
+	var ids []int64
+	for id := range signals {
+		if header.InputVisibility(id) != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	inputs := make([]InputSignal, len(ids))
+	for i, id := range ids {
+		inputs[i] = InputSignal{Signal: signals[id], Visibility: header.InputVisibility(id)}
+	}
+	return inputs
+}
+
+// inputFindingCategories are the categories of the findings on an input that
+// let a prover pick its value
+var inputFindingCategories = map[string]bool{
+	CategoryUnderconstrained: true,
+	CategoryFloatingInput:    true,
+	CategoryIsolatedInput:    true,
+	CategoryInputIsland:      true,
+}
+
+// MarkInputVisibility notes the visibility of the input in the message of
+// every finding on an input left unconstrained, and raises the severity of
+// those on a public input by one level: the verifier accepts a proof for any
+// value of such an input, while a free private input may only be harmless
+// slack of the witness.
+func MarkInputVisibility(findings []Finding, inputs []InputSignal) {
+	visibilities := make(map[string]Visibility, len(inputs))
+	for _, input := range inputs {
+		visibilities[input.Signal] = input.Visibility
+	}
+	for i, finding := range findings {
+		visibility, ok := visibilities[finding.Signal]
+		if !ok || !inputFindingCategories[finding.Category] {
+			continue
+		}
+		findings[i].Message += fmt.Sprintf(" (%s input)", visibility)
+		if visibility == VisibilityPublic {
+			findings[i].Severity = finding.Severity.raised()
+		}
+	}
+}
+
+// raised returns the severity one level above, critical and unknown
+// severities staying as they are
+func (s Severity) raised() Severity {
+	switch s {
+	case SeverityInfo:
+		return SeverityLow
+	case SeverityLow:
+		return SeverityMedium
+	case SeverityMedium:
+		return SeverityHigh
+	case SeverityHigh:
+		return SeverityCritical
+	default:
+		return s
+	}
+}
@@ -0,0 +1,133 @@
+package circuitgraph
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// r1csFile returns an r1cs file with a constraints section ahead of the
+// header section, as circom writes them
+func r1csFile(header R1CSHeader) []byte {
+	var b bytes.Buffer
+	write := func(values ...any) {
+		for _, value := range values {
+			binary.Write(&b, binary.LittleEndian, value)
+		}
+	}
+	b.WriteString("r1cs")
+	write(uint32(1), uint32(2))
+
+	constraints := bytes.Repeat([]byte{0xaa}, 40)
+	write(uint32(2), uint64(len(constraints)))
+	b.Write(constraints)
+
+	prime := bytes.Repeat([]byte{0xff}, 32)
+	write(uint32(1), uint64(4+len(prime)+4*4+8+4), uint32(len(prime)))
+	b.Write(prime)
+	write(header.Wires, header.PublicOutputs, header.PublicInputs, header.PrivateInputs, uint64(header.Wires), header.Constraints)
+	return b.Bytes()
+}
+
+// writeR1CS writes content to a file in a temporary directory and returns its path
+func writeR1CS(t *testing.T, content []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "circuit.r1cs")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// sampleHeader has one output (witness 1), two public inputs (2 and 3), two
+// private inputs (4 and 5) and two internal signals (6 and 7)
+var sampleHeader = R1CSHeader{Wires: 8, PublicOutputs: 1, PublicInputs: 2, PrivateInputs: 2, Constraints: 5}
+
+func TestLoadR1CSHeader(t *testing.T) {
+	header, err := LoadR1CSHeader(writeR1CS(t, r1csFile(sampleHeader)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header != sampleHeader {
+		t.Errorf("header = %+v, want %+v", header, sampleHeader)
+	}
+}
+
+func TestLoadR1CSHeaderErrors(t *testing.T) {
+	valid := r1csFile(sampleHeader)
+	noHeader := append([]byte(nil), valid[:12]...)
+	binary.LittleEndian.PutUint32(noHeader[8:], 0)
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"wrong magic", append([]byte("wasm"), valid[4:]...)},
+		{"empty", nil},
+		{"truncated preamble", valid[:6]},
+		{"truncated section", valid[:20]},
+		{"truncated skipped section", valid[:40]},
+		{"truncated header", valid[:len(valid)-6]},
+		{"no header section", noHeader},
+		{"too few wires", r1csFile(R1CSHeader{Wires: 3, PublicOutputs: 1, PublicInputs: 1, PrivateInputs: 1})},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := LoadR1CSHeader(writeR1CS(t, test.content))
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Errorf("error = %v, want a ParseError", err)
+			}
+		})
+	}
+}
+
+func TestInputVisibility(t *testing.T) {
+	want := []Visibility{"", "", VisibilityPublic, VisibilityPublic, VisibilityPrivate, VisibilityPrivate, "", ""}
+	for witness, visibility := range want {
+		if got := sampleHeader.InputVisibility(int64(witness)); got != visibility {
+			t.Errorf("InputVisibility(%d) = %q, want %q", witness, got, visibility)
+		}
+	}
+
+	signals := map[int64]string{0: "1", 1: "main.out", 2: "main.root", 3: "main.nullifier", 4: "main.secret[0]", 5: "main.secret[1]", 6: "main.tmp"}
+	inputs := InputVisibilities(signals, sampleHeader)
+	wantInputs := []InputSignal{
+		{"main.root", VisibilityPublic},
+		{"main.nullifier", VisibilityPublic},
+		{"main.secret[0]", VisibilityPrivate},
+		{"main.secret[1]", VisibilityPrivate},
+	}
+	if !reflect.DeepEqual(inputs, wantInputs) {
+		t.Errorf("inputs = %+v, want %+v", inputs, wantInputs)
+	}
+	wantKinds := map[string]SignalKind{"out": KindOutput, "root": KindInput, "nullifier": KindInput, "secret": KindInput}
+	if kinds := sampleHeader.Kinds(signals); !reflect.DeepEqual(kinds, wantKinds) {
+		t.Errorf("kinds = %v, want %v", kinds, wantKinds)
+	}
+}
+
+func TestMarkInputVisibility(t *testing.T) {
+	inputs := []InputSignal{{"main.root", VisibilityPublic}, {"main.secret", VisibilityPrivate}}
+	findings := []Finding{
+		{Category: CategoryUnderconstrained, Severity: SeverityMedium, Signal: "main.root", Message: "unconstrained"},
+		{Category: CategoryUnderconstrained, Severity: SeverityMedium, Signal: "main.secret", Message: "unconstrained"},
+		{Category: CategoryFloatingInput, Severity: SeverityCritical, Signal: "main.root", Message: "floating"},
+		{Category: CategoryBottleneck, Severity: SeverityLow, Signal: "main.root", Message: "bottleneck"},
+		{Category: CategoryUnderconstrained, Severity: SeverityMedium, Signal: "main.tmp", Message: "unconstrained"},
+	}
+	MarkInputVisibility(findings, inputs)
+	want := []Finding{
+		{Category: CategoryUnderconstrained, Severity: SeverityHigh, Signal: "main.root", Message: "unconstrained (public input)"},
+		{Category: CategoryUnderconstrained, Severity: SeverityMedium, Signal: "main.secret", Message: "unconstrained (private input)"},
+		{Category: CategoryFloatingInput, Severity: SeverityCritical, Signal: "main.root", Message: "floating (public input)"},
+		{Category: CategoryBottleneck, Severity: SeverityLow, Signal: "main.root", Message: "bottleneck"},
+		{Category: CategoryUnderconstrained, Severity: SeverityMedium, Signal: "main.tmp", Message: "unconstrained"},
+	}
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("findings = %+v, want %+v", findings, want)
+	}
+}
@@ -0,0 +1,54 @@
+package circuitgraph
+
+import (
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/topo"
+)
+
+// Removal compares the components of a graph without the "1" signal before
+// and after also removing some signals, answering whether the circuit would
+// fall apart without them
+type Removal struct {
+	Removed []string `json:"removed"`
+	Before  []int    `json:"before"` // Signals per component without the "1" signal, largest first
+	After   []int    `json:"after"`  // The same once the removed signals are gone as well
+}
+
+// RemoveSignals computes the components of the graph without the "1" signal
+// and without the signals for which remove returns true. The graph itself is
+// left untouched.
+func RemoveSignals(g *CircuitGraph, remove func(name string) bool) Removal {
+	gc := withoutConstant(g)
+	removal := Removal{Before: componentSizes(gc)}
+	for _, node := range graph.NodesOf(gc.Nodes()) {
+		named := node.(*NamedNode)
+		if !named.Synthetic() && remove(named.Name) {
+			removal.Removed = append(removal.Removed, named.Name)
+			gc.RemoveNode(named.ID())
+		}
+	}
+	sort.Strings(removal.Removed)
+	removal.After = componentSizes(gc)
+	return removal
+}
+
+// componentSizes returns the number of signals in every connected component
+// holding at least one, largest first
+func componentSizes(g graph.Undirected) []int {
+	var sizes []int
+	for _, component := range topo.ConnectedComponents(g) {
+		signals := 0
+		for _, node := range component {
+			if !node.(*NamedNode).Synthetic() {
+				signals++
+			}
+		}
+		if signals > 0 {
+			sizes = append(sizes, signals)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	return sizes
+}
@@ -0,0 +1,124 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Finding categories reported by CheckSlotUsage and CheckPinnedSignals
+const (
+	CategoryNarrowSlots  = "narrow-slot-usage"
+	CategoryPinnedSignal = "constant-pinned-signal"
+)
+
+// minNarrowSlotUses is the number of constraints a signal must appear in
+// before using a single slot, or mostly the constant, is considered a pattern
+// rather than incidental
+const minNarrowSlotUses = 3
+
+// Slots is the set of terms of A·B = C constraints a signal appears in
+type Slots uint8
+
+const (
+	SlotA Slots = 1 << iota
+	SlotB
+	SlotC
+)
+
+// String lists the slots, e.g. "AC", or "-" for none
+func (s Slots) String() string {
+	name := ""
+	for i, slot := range []Slots{SlotA, SlotB, SlotC} {
+		if s&slot != 0 {
+			name += string("ABC"[i])
+		}
+	}
+	if name == "" {
+		return "-"
+	}
+	return name
+}
+
+// single reports whether the set holds exactly one slot
+func (s Slots) single() bool {
+	return s != 0 && s&(s-1) == 0
+}
+
+// SlotUse is how a signal takes part in the constraints
+type SlotUse struct {
+	Slots        Slots
+	Constraints  int // Constraints mentioning the signal in any slot
+	WithConstant int // Constraints also mentioning the "1" signal
+}
+
+// SignalSlots returns the slots every signal appears in, keyed by signal ID
+func SignalSlots(constraints Constraints) map[int64]SlotUse {
+	usage := make(map[int64]SlotUse)
+	for _, constraint := range constraints {
+		slots := make(map[int64]Slots)
+		for i, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				slots[signal] |= Slots(1) << i
+			}
+		}
+		_, constant := slots[0]
+		for signal, s := range slots {
+			use := usage[signal]
+			use.Slots |= s
+			use.Constraints++
+			if constant {
+				use.WithConstant++
+			}
+			usage[signal] = use
+		}
+	}
+	return usage
+}
+
+// CheckSlotUsage reports the signals other than "1" and the inputs that
+// appear in at least minNarrowSlotUses constraints, always in the same slot.
+// Such a signal has a restricted role, e.g. it is only ever defined and never
+// reused. Roles are looked up in kinds, the signals declared by the template.
+func CheckSlotUsage(usage map[int64]SlotUse, signals map[int64]string, kinds map[string]SignalKind) []Finding {
+	var findings []Finding
+	for id, use := range usage {
+		name, ok := signals[id]
+		if id == 0 || !ok || !use.Slots.single() || use.Constraints < minNarrowSlotUses || SignalRole(name, kinds) == KindInput {
+			continue
+		}
+		findings = append(findings, Finding{
+			Category: CategoryNarrowSlots,
+			Severity: SeverityInfo,
+			Signal:   name,
+			Message:  fmt.Sprintf("signal appears in %d constraints, always in the %s term", use.Constraints, use.Slots),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Signal < findings[j].Signal })
+	return findings
+}
+
+// CheckPinnedSignals reports the signals other than "1" appearing in at least
+// minNarrowSlotUses constraints, at least threshold of which (between 0 and 1)
+// also mention the "1" signal. Such signals are mostly pinned to constants
+// and may be parameters declared as signals.
+func CheckPinnedSignals(usage map[int64]SlotUse, signals map[int64]string, threshold float64) []Finding {
+	var findings []Finding
+	for id, use := range usage {
+		name, ok := signals[id]
+		if id == 0 || !ok || use.Constraints < minNarrowSlotUses {
+			continue
+		}
+		share := float64(use.WithConstant) / float64(use.Constraints)
+		if share < threshold {
+			continue
+		}
+		findings = append(findings, Finding{
+			Category: CategoryPinnedSignal,
+			Severity: SeverityLow,
+			Signal:   name,
+			Message:  fmt.Sprintf("%d of the %d constraints over the signal also mention the \"1\" signal (%.0f%%), it is mostly pinned to constants and might be a misnamed parameter", use.WithConstant, use.Constraints, 100*share),
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Signal < findings[j].Signal })
+	return findings
+}
@@ -0,0 +1,282 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/topo"
+	"gonum.org/v1/gonum/mat"
+)
+
+// CategoryBottleneck is reported when the largest component barely holds together
+const CategoryBottleneck = "connectivity-bottleneck"
+
+// BottleneckThreshold is the algebraic connectivity below which the largest
+// component is considered to hinge on a bottleneck
+const BottleneckThreshold = 1e-3
+
+// maxLanczosSteps bounds the Lanczos iterations between restarts
+const maxLanczosSteps = 300
+
+// The Lanczos iteration restarts until the Fiedler value changes by less than
+// lanczosTolerance relative to its value, at most maxLanczosRestarts times
+const (
+	lanczosTolerance   = 1e-3
+	maxLanczosRestarts = 5
+)
+
+// Connectivity describes how close the largest component is to falling apart
+type Connectivity struct {
+	// Second smallest eigenvalue of the graph Laplacian (Fiedler value),
+	// approximated. Zero for components of fewer than two nodes.
+	Fiedler       float64  `json:"fiedler"`
+	ComponentSize int      `json:"component_size"`  // Signals in the largest component after removing the "1" signal
+	Cut           []string `json:"cut,omitempty"`   // Smaller side of the partition given by the signs of the Fiedler vector
+	Split         *Split   `json:"split,omitempty"` // One or two edges all that connect two large regions, if any
+}
+
+// AlgebraicConnectivity computes the Fiedler value of the largest component
+// of the graph without the constant signal. It runs a Lanczos iteration on
+// the sparse Laplacian, so large circuits get an approximation. Edge weights
+// count when the graph was built WithWeightedEdges.
+func AlgebraicConnectivity(g *CircuitGraph) Connectivity {
+	gc := withoutConstant(g)
+
+	// The largest component by number of signals, constraint nodes included as members
+	var largest []graph.Node
+	largestSignals := 0
+	for _, component := range topo.ConnectedComponents(gc) {
+		signals := 0
+		for _, node := range component {
+			if !node.(*NamedNode).Synthetic() {
+				signals++
+			}
+		}
+		if signals > largestSignals {
+			largest, largestSignals = component, signals
+		}
+	}
+	connectivity := Connectivity{ComponentSize: largestSignals}
+	if len(largest) < 2 {
+		return connectivity
+	}
+
+	nodes := sortedNodes(largest)
+	index := make(map[int64]int, len(nodes))
+	for i, node := range nodes {
+		index[node.ID()] = i
+	}
+	laplacian := newSparseLaplacian(g, gc, nodes, index)
+
+	value, vector := laplacian.fiedler()
+	connectivity.Fiedler = value
+	connectivity.Split = sweepSplit(g, nodes, vector, laplacian)
+
+	// The signs of the Fiedler vector split the component along its bottleneck
+	var sides [2][]string
+	for i, node := range nodes {
+		named := node.(*NamedNode)
+		if named.Synthetic() {
+			continue
+		}
+		side := 0
+		if vector[i] < 0 {
+			side = 1
+		}
+		sides[side] = append(sides[side], named.Name)
+	}
+	cut := sides[0]
+	if len(sides[1]) < len(cut) {
+		cut = sides[1]
+	}
+	sort.Strings(cut)
+	connectivity.Cut = cut
+	return connectivity
+}
+
+// checkBottleneck reports a connected component that hinges on a bottleneck
+func checkBottleneck(connectivity Connectivity) []Finding {
+	if connectivity.ComponentSize < 3 || connectivity.Fiedler >= BottleneckThreshold || len(connectivity.Cut) == 0 {
+		return nil
+	}
+	message := fmt.Sprintf("algebraic connectivity %.3g of the largest component suggests a bottleneck separating %d of its %d signals",
+		connectivity.Fiedler, len(connectivity.Cut), connectivity.ComponentSize)
+	if prefixes := CommonPrefixes(connectivity.Cut, maxBlockPrefixes); len(prefixes) > 0 {
+		message += " (" + strings.Join(prefixes, ", ") + ")"
+	}
+	return []Finding{{
+		Category: CategoryBottleneck,
+		Severity: SeverityInfo,
+		Message:  message,
+	}}
+}
+
+// sparseLaplacian is the Laplacian of a component as adjacency lists
+type sparseLaplacian struct {
+	degree    []float64
+	neighbors [][]int
+	weights   [][]float64
+}
+
+func newSparseLaplacian(g *CircuitGraph, component graph.Undirected, nodes []graph.Node, index map[int64]int) *sparseLaplacian {
+	l := &sparseLaplacian{
+		degree:    make([]float64, len(nodes)),
+		neighbors: make([][]int, len(nodes)),
+		weights:   make([][]float64, len(nodes)),
+	}
+	for i, node := range nodes {
+		from := component.From(node.ID())
+		for from.Next() {
+			neighbor := from.Node().ID()
+			weight := g.EdgeWeight(node.ID(), neighbor)
+			l.neighbors[i] = append(l.neighbors[i], index[neighbor])
+			l.weights[i] = append(l.weights[i], weight)
+			l.degree[i] += weight
+		}
+	}
+	return l
+}
+
+// apply computes dst = L x
+func (l *sparseLaplacian) apply(dst, x []float64) {
+	for i := range x {
+		sum := l.degree[i] * x[i]
+		for k, j := range l.neighbors[i] {
+			sum -= l.weights[i][k] * x[j]
+		}
+		dst[i] = sum
+	}
+}
+
+// fiedler returns the smallest eigenvalue of L on the complement of the
+// constant vector, which spans its null space in a connected graph, along
+// with its eigenvector. The Lanczos iteration restarts from its best estimate
+// until the value settles, which keeps the memory bounded for large circuits.
+func (l *sparseLaplacian) fiedler() (float64, []float64) {
+	n := len(l.degree)
+
+	// A fixed seed keeps results reproducible between runs
+	random := rand.New(rand.NewSource(1))
+	start := make([]float64, n)
+	for i := range start {
+		start[i] = random.Float64() - 0.5
+	}
+
+	value := math.Inf(1)
+	for restart := 0; restart < maxLanczosRestarts; restart++ {
+		next, vector := l.lanczos(start, min(n-1, maxLanczosSteps))
+		converged := value-next <= lanczosTolerance*next
+		value, start = next, vector
+		if converged {
+			break
+		}
+	}
+	return math.Max(value, 0), start
+}
+
+// lanczos runs steps iterations from start and returns the smallest Ritz
+// value and vector. It only keeps the last two Lanczos vectors and replays the
+// iteration to assemble the Ritz vector, so memory stays linear in the
+// component size. Without reorthogonalization, converged values repeat as
+// ghosts, which leaves the smallest one intact.
+func (l *sparseLaplacian) lanczos(start []float64, steps int) (float64, []float64) {
+	alpha, beta := l.iterate(start, steps, nil)
+
+	// Eigenpairs of the tridiagonal projection, in ascending order
+	m := len(alpha)
+	t := mat.NewSymDense(m, nil)
+	for i := 0; i < m; i++ {
+		t.SetSym(i, i, alpha[i])
+		if i+1 < m {
+			t.SetSym(i, i+1, beta[i])
+		}
+	}
+	var eigen mat.EigenSym
+	if !eigen.Factorize(t, true) {
+		return 0, start
+	}
+	var vectors mat.Dense
+	eigen.VectorsTo(&vectors)
+
+	vector := make([]float64, len(start))
+	l.iterate(start, m, func(j int, q []float64) {
+		axpy(vector, vectors.At(j, 0), q)
+	})
+	return eigen.Values(nil)[0], vector
+}
+
+// iterate runs the Lanczos recurrence on the complement of the constant
+// vector, calling visit with every Lanczos vector, and returns the diagonal
+// and off-diagonal of the tridiagonal projection
+func (l *sparseLaplacian) iterate(start []float64, steps int, visit func(j int, q []float64)) (alpha, beta []float64) {
+	n := len(start)
+	q := make([]float64, n)
+	copy(q, start)
+	removeMean(q)
+	scale(q, 1/norm(q))
+	previous := make([]float64, n)
+	w := make([]float64, n)
+
+	for j := 0; j < steps; j++ {
+		if visit != nil {
+			visit(j, q)
+		}
+		l.apply(w, q)
+		a := dot(w, q)
+		alpha = append(alpha, a)
+		axpy(w, -a, q)
+		if j > 0 {
+			axpy(w, -beta[j-1], previous)
+		}
+		// Rounding lets the constant vector creep back in
+		removeMean(w)
+		b := norm(w)
+		if j == steps-1 || b < 1e-10 {
+			break
+		}
+		beta = append(beta, b)
+		previous, q, w = q, w, previous
+		scale(q, 1/b)
+	}
+	return alpha, beta
+}
+
+func dot(x, y []float64) float64 {
+	sum := 0.0
+	for i := range x {
+		sum += x[i] * y[i]
+	}
+	return sum
+}
+
+func norm(x []float64) float64 {
+	return math.Sqrt(dot(x, x))
+}
+
+func scale(x []float64, factor float64) {
+	for i := range x {
+		x[i] *= factor
+	}
+}
+
+// axpy computes y += a x
+func axpy(y []float64, a float64, x []float64) {
+	for i := range y {
+		y[i] += a * x[i]
+	}
+}
+
+func removeMean(x []float64) {
+	mean := 0.0
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float64(len(x))
+	for i := range x {
+		x[i] -= mean
+	}
+}
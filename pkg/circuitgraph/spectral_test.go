@@ -0,0 +1,37 @@
+package circuitgraph
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAlgebraicConnectivity(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints Constraints
+		fiedler     float64
+		size        int
+	}{
+		{"empty graph", nil, 0, 0},
+		{"single signal", Constraints{{{0}, {1}, {1}}}, 0, 1},
+		{"two signals", Constraints{{{1}, {1}, {2}}}, 2, 2},
+		{"path of three", Constraints{{{1}, {1}, {2}}, {{2}, {2}, {3}}}, 1, 3},
+		{"triangle", Constraints{{{1}, {2}, {3}}}, 3, 3},
+		{"two islands", twoIslands, 2, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := BuildGraph(test.constraints, twoIslandsSignals)
+			if err != nil {
+				t.Fatal(err)
+			}
+			connectivity := AlgebraicConnectivity(g)
+			if math.Abs(connectivity.Fiedler-test.fiedler) > 1e-6 {
+				t.Errorf("Fiedler value = %g, want %g", connectivity.Fiedler, test.fiedler)
+			}
+			if connectivity.ComponentSize != test.size {
+				t.Errorf("component size = %d, want %d", connectivity.ComponentSize, test.size)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// CategoryNearDisconnected is reported when one or two edges hold the largest component together
+const CategoryNearDisconnected = "near-disconnection"
+
+// A split is reported if at most maxSplitEdges edges connect two regions of
+// at least minSplitRegion signals and a tenth of the component each
+const (
+	maxSplitEdges  = 2
+	minSplitRegion = 5
+)
+
+// Split is the place where the largest component would fall apart, found by
+// sweeping its nodes in the order of the Fiedler vector
+type Split struct {
+	Edges   []SplitEdge `json:"edges"`   // Edges connecting the two regions
+	Regions [2]Region   `json:"regions"` // Larger region first
+}
+
+// SplitEdge is an edge of a Split with the constraints that induced it
+type SplitEdge struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Constraints []int  `json:"constraints"`
+}
+
+// Region is one side of a Split
+type Region struct {
+	Signals  int      `json:"signals"`
+	Prefixes []string `json:"prefixes,omitempty"` // Most common component paths of its signals
+}
+
+// sweepSplit orders the nodes by their entry of the Fiedler vector and finds
+// the prefix of that order crossed by the fewest edges, among those leaving
+// large regions on both sides. It returns nil unless at most maxSplitEdges
+// edges cross it.
+func sweepSplit(g *CircuitGraph, nodes []graph.Node, vector []float64, l *sparseLaplacian) *Split {
+	order := make([]int, len(nodes))
+	signals := 0
+	for i, node := range nodes {
+		order[i] = i
+		if !node.(*NamedNode).Synthetic() {
+			signals++
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return vector[order[i]] < vector[order[j]] })
+	minRegion := max(minSplitRegion, signals/10)
+
+	// Moving a node to the first region turns its edges into the other region
+	// into crossing ones, and its crossing edges into inner ones
+	inFirst := make([]bool, len(nodes))
+	crossing, firstSignals := 0, 0
+	best, bestCrossing, bestBalance := -1, maxSplitEdges+1, 0
+	for k, i := range order[:len(order)-1] {
+		inFirst[i] = true
+		for _, j := range l.neighbors[i] {
+			if inFirst[j] {
+				crossing--
+			} else {
+				crossing++
+			}
+		}
+		if !nodes[i].(*NamedNode).Synthetic() {
+			firstSignals++
+		}
+		balance := min(firstSignals, signals-firstSignals)
+		if balance < minRegion {
+			continue
+		}
+		if crossing < bestCrossing || crossing == bestCrossing && balance > bestBalance {
+			best, bestCrossing, bestBalance = k, crossing, balance
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+
+	first := make(map[int]bool, best+1)
+	for _, i := range order[:best+1] {
+		first[i] = true
+	}
+	var split Split
+	var names [2][]string
+	for i, node := range nodes {
+		side := 1
+		if first[i] {
+			side = 0
+		}
+		named := node.(*NamedNode)
+		if !named.Synthetic() {
+			names[side] = append(names[side], named.Name)
+		}
+		for _, j := range l.neighbors[i] {
+			if first[i] && !first[j] {
+				split.Edges = append(split.Edges, SplitEdge{
+					From:        named.Name,
+					To:          nodes[j].(*NamedNode).Name,
+					Constraints: g.Provenance(named.ID(), nodes[j].ID()),
+				})
+			}
+		}
+	}
+	if len(names[1]) > len(names[0]) {
+		names[0], names[1] = names[1], names[0]
+	}
+	for side, regionNames := range names {
+		split.Regions[side] = Region{Signals: len(regionNames), Prefixes: CommonPrefixes(regionNames, maxBlockPrefixes)}
+	}
+	return &split
+}
+
+// checkSplit reports the edges holding the largest component together
+func checkSplit(split *Split) []Finding {
+	if split == nil {
+		return nil
+	}
+	seen := make(map[int]bool)
+	var constraints []string
+	for _, edge := range split.Edges {
+		for _, constraint := range edge.Constraints {
+			if !seen[constraint] {
+				seen[constraint] = true
+				constraints = append(constraints, "#"+strconv.Itoa(constraint))
+			}
+		}
+	}
+	return []Finding{{
+		Category: CategoryNearDisconnected,
+		Severity: SeverityLow,
+		Message: fmt.Sprintf("only constraint(s) %s connect region A (%s) to region B (%s), check whether the circuit is meant to split there",
+			strings.Join(constraints, ", "), split.Regions[0], split.Regions[1]),
+	}}
+}
+
+// String describes a region by its size and prefixes, e.g. "12 signals, main.foo"
+func (r Region) String() string {
+	description := fmt.Sprintf("%d signals", r.Signals)
+	if len(r.Prefixes) > 0 {
+		description += ", " + strings.Join(r.Prefixes, ", ")
+	}
+	return description
+}
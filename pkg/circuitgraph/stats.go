@@ -0,0 +1,301 @@
+package circuitgraph
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"gonum.org/v1/gonum/graph"
+)
+
+// Stats summarizes the size of a compiled template
+type Stats struct {
+	Constraints      int     `json:"constraints"`
+	Signals          int     `json:"signals"`           // Unique signals, the nodes of the graph
+	Edges            int     `json:"edges"`             // Edges of the graph, including those of constraint nodes
+	SignalReferences int     `json:"signal_references"` // Signal occurrences summed over all constraints
+	ReuseRatio       float64 `json:"reuse_ratio"`       // Signal references per unique signal
+
+	// Inputs of the main component by visibility, as declared to circom,
+	// both 0 if the r1cs header was not available
+	PublicInputs  int `json:"public_inputs"`
+	PrivateInputs int `json:"private_inputs"`
+
+	// The constraints as a constraint×signal incidence matrix, with a nonzero
+	// entry wherever a constraint mentions a signal in any of its terms. Its
+	// columns are the signals the constraints mention, "1" included, so the
+	// figures do not depend on the graph options.
+	Nonzeros              int     `json:"nonzeros"`
+	Density               float64 `json:"density"` // Share of nonzero entries, 1 minus the sparsity
+	NonzerosPerConstraint float64 `json:"nonzeros_per_constraint"`
+	NonzerosPerSignal     float64 `json:"nonzeros_per_signal"`
+
+	// Connected components once the constant signal is removed. Connected
+	// holds if there is at most one, i.e. no independent subgraphs.
+	Connected              bool    `json:"connected"`
+	Components             int     `json:"components"`
+	LargestComponent       int     `json:"largest_component"`
+	SecondLargestComponent int     `json:"second_largest_component"`
+	OutsideLargestFraction float64 `json:"outside_largest_fraction"` // Share of signals not in the largest component
+
+	// Pearson correlation of the degrees at either end of an edge once the
+	// constant signal is removed. Positive when hubs connect to hubs, negative
+	// when they connect to leaves, 0 if all degrees are equal.
+	Assortativity float64 `json:"assortativity"`
+
+	// Signals sharing a constraint with the "1" signal, and their share of all
+	// other signals. Both are 0 if the graph was built WithoutConstant.
+	ConstantDegree   int     `json:"constant_degree"`
+	ConstantCoverage float64 `json:"constant_coverage"`
+
+	// Constraints over the "1" signal and at most one other signal, which pin
+	// that signal to constants, and the share of all edges touching the "1"
+	// signal. The share is 0 if the graph was built WithoutConstant.
+	ConstantAssertions int     `json:"constant_assertions"`
+	ConstantEdgeShare  float64 `json:"constant_edge_share"`
+
+	// Structure once the constant signal is removed. Pure linear systems
+	// often project onto bipartite or triangle-free graphs.
+	Triangles int        `json:"triangles"`
+	Bipartite bool       `json:"bipartite"`
+	Partition [][]string `json:"partition,omitempty"` // Signals on either side if the graph is bipartite
+
+	// Hash of the sorted signal degrees and the counts above, equal for
+	// isomorphic graphs whatever their signal names. Different graphs can
+	// share it, as the degree sequence does not determine the structure.
+	Fingerprint string `json:"fingerprint"`
+}
+
+// LowConstantCoverage is the share of signals below which the "1" signal of a
+// template with at least minHubGraphSize signals is considered suspiciously
+// isolated. Most circuits use the constant throughout.
+const LowConstantCoverage = 0.05
+
+// LowConstantDegree reports whether the "1" signal touches suspiciously few
+// signals, which can point to signal keys lost or collapsed while parsing the
+// constraints, or to an unusual circuit
+func (s Stats) LowConstantDegree() bool {
+	return s.Signals >= minHubGraphSize && s.ConstantCoverage < LowConstantCoverage
+}
+
+// ComputeStats summarizes a template from its constraints and the graph built from them
+func ComputeStats(constraints Constraints, g *CircuitGraph) Stats {
+	stats := Stats{
+		Constraints: len(constraints),
+		Signals:     g.SignalCount(),
+		Edges:       g.Edges().Len(),
+	}
+	columns := make(map[int64]struct{})
+	for _, constraint := range constraints {
+		constant := false
+		others := make(map[int64]struct{})
+		for _, linearExpression := range constraint {
+			stats.SignalReferences += len(linearExpression)
+			for _, signal := range linearExpression {
+				columns[signal] = struct{}{}
+				if signal == 0 {
+					constant = true
+				} else {
+					others[signal] = struct{}{}
+				}
+			}
+		}
+		if constant && len(others) <= 1 {
+			stats.ConstantAssertions++
+		}
+		stats.Nonzeros += len(others)
+		if constant {
+			stats.Nonzeros++
+		}
+	}
+	if stats.Signals > 0 {
+		stats.ReuseRatio = float64(stats.SignalReferences) / float64(stats.Signals)
+	}
+	if len(constraints) > 0 && len(columns) > 0 {
+		stats.Density = float64(stats.Nonzeros) / (float64(len(constraints)) * float64(len(columns)))
+		stats.NonzerosPerConstraint = float64(stats.Nonzeros) / float64(len(constraints))
+		stats.NonzerosPerSignal = float64(stats.Nonzeros) / float64(len(columns))
+	}
+
+	stats.countComponents(g)
+	if g.Node(0) != nil {
+		stats.ConstantDegree = g.SignalDegree(0)
+		if stats.Signals > 1 {
+			stats.ConstantCoverage = float64(stats.ConstantDegree) / float64(stats.Signals-1)
+		}
+		if stats.Edges > 0 {
+			stats.ConstantEdgeShare = float64(g.From(0).Len()) / float64(stats.Edges)
+		}
+	}
+	gc := withoutConstant(g)
+	stats.Assortativity = degreeAssortativity(gc)
+	stats.Triangles = countTriangles(gc)
+	if partition, ok := bipartition(gc); ok {
+		stats.Bipartite = true
+		stats.Partition = partition[:]
+	}
+	stats.Fingerprint = fingerprint(g, stats)
+	return stats
+}
+
+// degreeAssortativity returns the Pearson correlation of the degrees at both
+// ends of every edge, counting each edge in both directions. Constraint nodes
+// count as nodes, since their edges stand in for the cliques of wide
+// constraints. It is 0 if there are no edges or all ends have the same degree.
+func degreeAssortativity(g graph.Undirected) float64 {
+	var count, sum, sumSquares, sumProducts float64
+	// Summed in a fixed order, floating-point addition is not associative
+	for _, node := range sortedNodes(graph.NodesOf(g.Nodes())) {
+		neighbors := sortedNodes(graph.NodesOf(g.From(node.ID())))
+		j := float64(len(neighbors))
+		for _, neighbor := range neighbors {
+			k := float64(g.From(neighbor.ID()).Len())
+			count++
+			sum += j
+			sumSquares += j * j
+			sumProducts += j * k
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	mean := sum / count
+	variance := sumSquares/count - mean*mean
+	// Rounding leaves a tiny variance when all degrees are equal
+	if variance <= 1e-12*sumSquares/count {
+		return 0
+	}
+	return (sumProducts/count - mean*mean) / variance
+}
+
+// countTriangles counts every triangle once, from its node with the lowest ID
+func countTriangles(g graph.Undirected) int {
+	triangles := 0
+	nodes := g.Nodes()
+	for nodes.Next() {
+		u := nodes.Node().ID()
+		var higher []int64
+		neighbors := g.From(u)
+		for neighbors.Next() {
+			if v := neighbors.Node().ID(); v > u {
+				higher = append(higher, v)
+			}
+		}
+		for i, v := range higher {
+			for _, w := range higher[i+1:] {
+				if g.HasEdgeBetween(v, w) {
+					triangles++
+				}
+			}
+		}
+	}
+	return triangles
+}
+
+// bipartition two-colors the graph and returns the signals of each color,
+// sorted by name. Constraint nodes are colored but not listed. It reports
+// false if an odd cycle prevents the coloring.
+func bipartition(g graph.Undirected) ([2][]string, bool) {
+	partition := [2][]string{{}, {}}
+	color := make(map[int64]int)
+	for _, start := range sortedNodes(graph.NodesOf(g.Nodes())) {
+		if _, ok := color[start.ID()]; ok {
+			continue
+		}
+		color[start.ID()] = 0
+		queue := []graph.Node{start}
+		for len(queue) > 0 {
+			node := queue[0]
+			queue = queue[1:]
+			if named := node.(*NamedNode); !named.Synthetic() {
+				partition[color[node.ID()]] = append(partition[color[node.ID()]], named.Name)
+			}
+			neighbors := g.From(node.ID())
+			for neighbors.Next() {
+				neighbor := neighbors.Node()
+				c, ok := color[neighbor.ID()]
+				if !ok {
+					color[neighbor.ID()] = 1 - color[node.ID()]
+					queue = append(queue, neighbor)
+				} else if c == color[node.ID()] {
+					return [2][]string{}, false
+				}
+			}
+		}
+	}
+	sort.Strings(partition[0])
+	sort.Strings(partition[1])
+	return partition, true
+}
+
+// fingerprint hashes the invariants of the graph without its names
+func fingerprint(g *CircuitGraph, stats Stats) string {
+	var degrees []int
+	nodes := g.Nodes()
+	for nodes.Next() {
+		if node := nodes.Node().(*NamedNode); !node.Synthetic() && node.ID() != 0 {
+			degrees = append(degrees, g.SignalDegree(node.ID()))
+		}
+	}
+	sort.Ints(degrees)
+
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%d %d %d %d %d %d|", stats.Constraints, stats.Signals, stats.Edges, stats.Components, stats.Triangles, stats.ConstantDegree)
+	for _, degree := range degrees {
+		fmt.Fprintf(hash, "%d,", degree)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// TopologyHash hashes the graph as its sorted signal names and the sorted
+// pairs of names of its edges, independent of the witness IDs the compiler
+// assigned. Unlike the fingerprint of the stats, renaming a signal changes it,
+// so it tells whether the structure of a circuit changed at all.
+func TopologyHash(g *CircuitGraph) string {
+	var names, edges []string
+	for _, node := range g.SortedNodes() {
+		names = append(names, node.Name)
+	}
+	for _, edge := range g.SortedEdges() {
+		from, to := edge[0].Name, edge[1].Name
+		if from > to {
+			from, to = to, from
+		}
+		edges = append(edges, fmt.Sprintf("%q %q", from, to))
+	}
+	sort.Strings(names)
+	sort.Strings(edges)
+
+	hash := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(hash, "%q\n", name)
+	}
+	fmt.Fprintln(hash, "|")
+	for _, edge := range edges {
+		fmt.Fprintln(hash, edge)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// countComponents fills in the number and sizes of the connected components
+// of the signals, which every analysis reports whichever checks it runs
+func (s *Stats) countComponents(g *CircuitGraph) {
+	components := signalComponents(g)
+	s.Components = len(components)
+	s.Connected = s.Components <= 1
+	total := 0
+	for _, component := range components {
+		size := len(component)
+		total += size
+		if size > s.LargestComponent {
+			s.SecondLargestComponent = s.LargestComponent
+			s.LargestComponent = size
+		} else if size > s.SecondLargestComponent {
+			s.SecondLargestComponent = size
+		}
+	}
+	if total > 0 {
+		s.OutsideLargestFraction = float64(total-s.LargestComponent) / float64(total)
+	}
+}
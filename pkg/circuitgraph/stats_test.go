@@ -0,0 +1,48 @@
+package circuitgraph
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/graph/simple"
+)
+
+// undirected returns a graph of the given edges between node IDs
+func undirected(edges [][2]int64) *simple.UndirectedGraph {
+	g := simple.NewUndirectedGraph()
+	for _, edge := range edges {
+		g.SetEdge(simple.Edge{F: simple.Node(edge[0]), T: simple.Node(edge[1])})
+	}
+	return g
+}
+
+func TestDegreeAssortativity(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges [][2]int64
+		want  float64
+	}{
+		{"empty graph", nil, 0},
+		{"single edge", [][2]int64{{1, 2}}, 0},
+		{"cycle", [][2]int64{{1, 2}, {2, 3}, {3, 4}, {4, 1}}, 0},
+		{"complete graph", [][2]int64{{1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}, 0},
+		{"star", [][2]int64{{1, 2}, {1, 3}, {1, 4}, {1, 5}}, -1},
+		{"path of four", [][2]int64{{1, 2}, {2, 3}, {3, 4}}, -0.5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := degreeAssortativity(undirected(test.edges))
+			if math.IsNaN(got) || math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("assortativity = %g, want %g", got, test.want)
+			}
+		})
+	}
+
+	t.Run("isolated node", func(t *testing.T) {
+		g := simple.NewUndirectedGraph()
+		g.AddNode(simple.Node(1))
+		if got := degreeAssortativity(g); got != 0 {
+			t.Errorf("assortativity = %g, want 0", got)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+pragma circom 2.0.0;
+
+// Deliberately broken: leak is a linear combination of the inputs c and d,
+// which the prover can compute without knowing any other signal, while prod
+// is properly constrained by a multiplication
+template LinearLeak() {
+    signal input a;
+    signal input b;
+    signal input c;
+    signal input d;
+    signal output prod;
+    signal output leak;
+    prod <== a * b;
+    leak <== c + d;
+}
+
+component main = LinearLeak();
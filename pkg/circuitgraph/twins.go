@@ -0,0 +1,92 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CategoryTwinSignals is reported for signals appearing in exactly the same constraints
+const CategoryTwinSignals = "twin-signals"
+
+// minTwinConstraints is the number of constraints twins must share. Signals
+// sharing a single constraint are common, e.g. the factors of a product, and
+// already reported as underconstrained.
+const minTwinConstraints = 2
+
+// TwinGroup is a set of signals appearing in exactly the same constraints.
+// Nothing but their coefficients tells them apart, so they are either
+// redundant or missing a constraint distinguishing them.
+type TwinGroup struct {
+	ID          int      `json:"id"` // Numbered from 1 in the order of the groups
+	Signals     []string `json:"signals"`
+	Constraints int      `json:"constraints"` // Constraints every signal of the group appears in
+}
+
+// TwinGroups returns the groups of signals other than "1" sharing the same
+// set of at least minTwinConstraints constraints, ordered by their first signal
+func TwinGroups(constraints Constraints, signals map[int64]string) []TwinGroup {
+	appearances := make(map[int64][]int)
+	for c, constraint := range constraints {
+		seen := make(map[int64]bool)
+		for _, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				if signal != 0 && !seen[signal] {
+					seen[signal] = true
+					appearances[signal] = append(appearances[signal], c)
+				}
+			}
+		}
+	}
+
+	bySet := make(map[string][]int64)
+	for signal, list := range appearances {
+		if len(list) < minTwinConstraints {
+			continue
+		}
+		parts := make([]string, len(list))
+		for i, c := range list {
+			parts[i] = strconv.Itoa(c)
+		}
+		key := strings.Join(parts, ",")
+		bySet[key] = append(bySet[key], signal)
+	}
+
+	var groups []TwinGroup
+	for _, ids := range bySet {
+		if len(ids) < 2 {
+			continue
+		}
+		group := TwinGroup{Constraints: len(appearances[ids[0]])}
+		for _, id := range ids {
+			name, ok := signals[id]
+			if !ok {
+				name = fmt.Sprintf("signal_%d", id)
+			}
+			group.Signals = append(group.Signals, name)
+		}
+		sort.Strings(group.Signals)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Signals[0] < groups[j].Signals[0] })
+	for i := range groups {
+		groups[i].ID = i + 1
+	}
+	return groups
+}
+
+// CheckTwins reports every twin group once, on its first signal
+func CheckTwins(groups []TwinGroup) []Finding {
+	var findings []Finding
+	for _, group := range groups {
+		findings = append(findings, Finding{
+			Category: CategoryTwinSignals,
+			Severity: SeverityLow,
+			Signal:   group.Signals[0],
+			Message: fmt.Sprintf("signal appears in exactly the same %d constraints as %s, the twins are redundant or lack a constraint telling them apart",
+				group.Constraints, strings.Join(group.Signals[1:], ", ")),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,42 @@
+package circuitgraph
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTwinGroups(t *testing.T) {
+	// a and b share constraints 0 and 1 with the constant, d and e share
+	// constraints 2 and 3, and f and g share constraint 4 alone
+	constraints, err := LoadFromJson(filepath.Join("testdata", "twins_constraints.json"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signals, err := LoadFromSym(filepath.Join("testdata", "twins.sym"), ParseOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signals[0] = "1"
+
+	want := []TwinGroup{
+		{ID: 1, Signals: []string{"main.a", "main.b"}, Constraints: 2},
+		{ID: 2, Signals: []string{"main.d", "main.e"}, Constraints: 2},
+	}
+	// The groups come out of maps, every run must number them alike
+	for run := 0; run < 20; run++ {
+		if groups := TwinGroups(constraints, signals); !reflect.DeepEqual(groups, want) {
+			t.Fatalf("run %d: groups = %+v, want %+v", run, groups, want)
+		}
+	}
+
+	findings := CheckTwins(want)
+	if len(findings) != 2 || findings[0].Signal != "main.a" || findings[1].Signal != "main.d" {
+		t.Fatalf("findings = %+v, want one on main.a and one on main.d", findings)
+	}
+	for _, finding := range findings {
+		if finding.Category != CategoryTwinSignals || finding.Severity != SeverityLow {
+			t.Errorf("finding = %+v, want a low %s finding", finding, CategoryTwinSignals)
+		}
+	}
+}
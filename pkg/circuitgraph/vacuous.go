@@ -0,0 +1,153 @@
+package circuitgraph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CategoryVacuousConstraint is reported for constraints that structurally look
+// like they constrain nothing
+const CategoryVacuousConstraint = "possibly-vacuous-constraint"
+
+// maxVacuousExamples limits the constraints named in a finding
+const maxVacuousExamples = 5
+
+// CoveredConstraint is a constraint whose signals all appear in another one
+type CoveredConstraint struct {
+	Index    int `json:"index"`    // Index of the constraint adding no signal of its own
+	Covering int `json:"covering"` // Index of a constraint mentioning all of its signals
+}
+
+// VacuousConstraints returns the indices of the constraints that are
+// possibly vacuous, judged by the signals they mention rather than by their
+// coefficients:
+//
+//   - degenerate constraints mention no signal but "1", or multiply the only
+//     term holding signals by an empty term with an empty C term, 0 = 0 for
+//     any witness. Linear constraints, with signals in the C term alone, are
+//     not degenerate.
+//   - covered constraints mention at least two signals other than "1", all of
+//     them also in another constraint. Of constraints over the same signals,
+//     all but the first are covered. Single-signal constraints, such as
+//     booleanity checks, are left out as they always look covered.
+//
+// Either may be padding inflating the apparent constrainedness of a circuit,
+// although the coefficients can still make a covered constraint independent.
+func VacuousConstraints(constraints Constraints) (degenerate []int, covered []CoveredConstraint) {
+	sets := make([][]int64, len(constraints))
+	bySignal := make(map[int64][]int)
+	for c, constraint := range constraints {
+		if degenerateConstraint(constraint) {
+			degenerate = append(degenerate, c)
+			continue
+		}
+		seen := make(map[int64]bool)
+		for _, linearExpression := range constraint {
+			for _, signal := range linearExpression {
+				if signal != 0 && !seen[signal] {
+					seen[signal] = true
+					sets[c] = append(sets[c], signal)
+					bySignal[signal] = append(bySignal[signal], c)
+				}
+			}
+		}
+		sort.Slice(sets[c], func(i, j int) bool { return sets[c][i] < sets[c][j] })
+	}
+
+	for c, set := range sets {
+		if len(set) < 2 {
+			continue
+		}
+		// Any covering constraint mentions the rarest signal of the set
+		rarest := set[0]
+		for _, signal := range set[1:] {
+			if len(bySignal[signal]) < len(bySignal[rarest]) {
+				rarest = signal
+			}
+		}
+		for _, other := range bySignal[rarest] {
+			if other == c || len(sets[other]) < len(set) || (len(sets[other]) == len(set) && other > c) {
+				continue
+			}
+			if subset(set, sets[other]) {
+				covered = append(covered, CoveredConstraint{Index: c, Covering: other})
+				break
+			}
+		}
+	}
+	return degenerate, covered
+}
+
+// degenerateConstraint reports whether a constraint reduces to 0 = 0 or to a
+// relation between constants, whatever its coefficients
+func degenerateConstraint(constraint [3][]int64) bool {
+	nonConstant := func(term []int64) bool {
+		for _, signal := range term {
+			if signal != 0 {
+				return true
+			}
+		}
+		return false
+	}
+	if !nonConstant(constraint[0]) && !nonConstant(constraint[1]) && !nonConstant(constraint[2]) {
+		return true
+	}
+	return len(constraint[2]) == 0 && (len(constraint[0]) == 0 || len(constraint[1]) == 0)
+}
+
+// subset reports whether the sorted set a is included in the sorted set b
+func subset(a, b []int64) bool {
+	j := 0
+	for _, signal := range a {
+		for j < len(b) && b[j] < signal {
+			j++
+		}
+		if j == len(b) || b[j] != signal {
+			return false
+		}
+		j++
+	}
+	return true
+}
+
+// CheckVacuousConstraints reports the degenerate and the covered constraints
+// of VacuousConstraints as one informational finding each, naming a few of them
+func CheckVacuousConstraints(constraints Constraints) []Finding {
+	degenerate, covered := VacuousConstraints(constraints)
+	var findings []Finding
+	if len(degenerate) > 0 {
+		examples := make([]string, 0, maxVacuousExamples)
+		for _, c := range degenerate[:min(len(degenerate), maxVacuousExamples)] {
+			examples = append(examples, strconv.Itoa(c))
+		}
+		findings = append(findings, Finding{
+			Category: CategoryVacuousConstraint,
+			Severity: SeverityInfo,
+			Message: fmt.Sprintf("%d constraint(s) mention no signal but \"1\" or multiply by an empty term, they hold for any witness (constraints %s%s)",
+				len(degenerate), strings.Join(examples, ", "), ellipsis(len(degenerate))),
+		})
+	}
+	if len(covered) > 0 {
+		examples := make([]string, 0, maxVacuousExamples)
+		for _, c := range covered[:min(len(covered), maxVacuousExamples)] {
+			examples = append(examples, fmt.Sprintf("%d within %d", c.Index, c.Covering))
+		}
+		findings = append(findings, Finding{
+			Category: CategoryVacuousConstraint,
+			Severity: SeverityInfo,
+			Message: fmt.Sprintf("%d constraint(s) only mention signals of another constraint, they may be padding rather than new relations (constraints %s%s)",
+				len(covered), strings.Join(examples, ", "), ellipsis(len(covered))),
+		})
+	}
+	return findings
+}
+
+// ellipsis marks a list of examples cut at maxVacuousExamples
+func ellipsis(count int) string {
+	if count > maxVacuousExamples {
+		return ", ..."
+	}
+	return ""
+}
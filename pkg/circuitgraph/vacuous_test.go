@@ -0,0 +1,64 @@
+package circuitgraph
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVacuousConstraints(t *testing.T) {
+	tests := []struct {
+		name        string
+		constraints Constraints
+		degenerate  []int
+		covered     []CoveredConstraint
+	}{
+		{
+			name:        "all constant",
+			constraints: Constraints{{{0}, {0}, {0}}, {{}, {}, {0}}},
+			degenerate:  []int{0, 1},
+		},
+		{
+			name:        "product with an empty term",
+			constraints: Constraints{{{1, 2}, {}, {}}, {{}, {0, 3}, {}}},
+			degenerate:  []int{0, 1},
+		},
+		{
+			name:        "linear, C term only",
+			constraints: Constraints{{{}, {}, {1, 2}}, {{}, {}, {0, 3}}},
+		},
+		{
+			name:        "same signals, the later one covered",
+			constraints: Constraints{{{1}, {2}, {3}}, {{3}, {1}, {2}}},
+			covered:     []CoveredConstraint{{Index: 1, Covering: 0}},
+		},
+		{
+			name:        "strict subset",
+			constraints: Constraints{{{}, {}, {1, 2}}, {{1}, {2}, {3}}},
+			covered:     []CoveredConstraint{{Index: 0, Covering: 1}},
+		},
+		{
+			name:        "strict subset, the constant aside",
+			constraints: Constraints{{{1}, {2}, {3}}, {{0, 1}, {0, 2}, {}}},
+			covered:     []CoveredConstraint{{Index: 1, Covering: 0}},
+		},
+		{
+			name:        "booleanity",
+			constraints: Constraints{{{1}, {0, 1}, {}}, {{1}, {2}, {3}}, {{2}, {0, 2}, {}}},
+		},
+		{
+			name:        "overlapping, neither covered",
+			constraints: Constraints{{{1}, {2}, {}}, {{2}, {3}, {}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			degenerate, covered := VacuousConstraints(test.constraints)
+			if !reflect.DeepEqual(degenerate, test.degenerate) {
+				t.Errorf("degenerate = %v, want %v", degenerate, test.degenerate)
+			}
+			if !reflect.DeepEqual(covered, test.covered) {
+				t.Errorf("covered = %+v, want %+v", covered, test.covered)
+			}
+		})
+	}
+}